@@ -8,15 +8,51 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"atqos/internal/app"
+	"atqos/internal/config"
+	"atqos/internal/store"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gc":
+			runGC(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "db":
+			runDB(os.Args[2:])
+			return
+		case "bundle":
+			runBundle(os.Args[2:])
+			return
+		case "search":
+			runSearch(os.Args[2:])
+			return
+		case "events":
+			runEvents(os.Args[2:])
+			return
+		case "replay-events":
+			runReplayEvents(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		}
+	}
+	runMain()
+}
+
+func runMain() {
 	root := flag.String("repo", ".", "Path to repository root")
 	artifacts := flag.String("artifacts", "artifacts", "Artifact output directory")
-	dbPath := flag.String("db", "artifacts/atqos.db", "SQLite database path")
+	dbPath := flag.String("db", "artifacts/atqos.db", "SQLite database path, postgres:// DSN, or :memory: for an ephemeral run")
 	configPath := flag.String("config", "", "Optional config file path")
 	flag.Parse()
 
@@ -30,10 +66,7 @@ func main() {
 		log.Fatalf("failed to resolve artifact path: %v", err)
 	}
 
-	dbFile, err := filepath.Abs(*dbPath)
-	if err != nil {
-		log.Fatalf("failed to resolve db path: %v", err)
-	}
+	dbFile := resolveDB(*dbPath)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -55,3 +88,398 @@ func main() {
 		fmt.Println(result.Summary)
 	}
 }
+
+func runGC(args []string) {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	root := flags.String("repo", ".", "Path to repository root")
+	artifacts := flags.String("artifacts", "artifacts", "Artifact output directory")
+	dbPath := flags.String("db", "artifacts/atqos.db", "SQLite database path or postgres:// DSN")
+	configPath := flags.String("config", "", "Optional config file path (retention policy, store.dsn)")
+	keepLast := flags.Int("keep-last", -1, "Keep the last N runs per repository (overrides config)")
+	maxAge := flags.Int("max-age-days", -1, "Remove runs older than N days (overrides config)")
+	maxBytes := flags.Int64("max-bytes", -1, "Cap total artifact bytes across runs (overrides config)")
+	dryRun := flags.Bool("dry-run", false, "List what would be removed without removing anything")
+	_ = flags.Parse(args)
+
+	repoPath, err := filepath.Abs(*root)
+	if err != nil {
+		log.Fatalf("failed to resolve repo path: %v", err)
+	}
+	artifactRoot, err := filepath.Abs(*artifacts)
+	if err != nil {
+		log.Fatalf("failed to resolve artifact path: %v", err)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	casDir := cfg.Artifacts.CASDir
+	if casDir == "" {
+		casDir = filepath.Join(artifactRoot, "cas")
+	} else if casDir, err = filepath.Abs(casDir); err != nil {
+		log.Fatalf("failed to resolve artifact store path: %v", err)
+	}
+	dsn := resolveDB(*dbPath)
+	if cfg.Store.DSN != "" {
+		dsn = cfg.Store.DSN
+	}
+	retention := cfg.Retention
+	if *keepLast >= 0 {
+		retention.KeepLast = *keepLast
+	}
+	if *maxAge >= 0 {
+		retention.MaxAgeDays = *maxAge
+	}
+	if *maxBytes >= 0 {
+		retention.MaxTotalBytes = *maxBytes
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd := app.GCCommand{
+		RepoPath:    repoPath,
+		DBPath:      dsn,
+		ArtifactDir: artifactRoot,
+		CASDir:      casDir,
+		Retention:   retention,
+		DryRun:      *dryRun,
+	}
+	result, err := cmd.Run(ctx)
+	if err != nil {
+		log.Fatalf("gc failed: %v", err)
+	}
+
+	verb := "removed"
+	if result.DryRun {
+		verb = "would remove"
+	}
+	for _, path := range result.Worktrees {
+		fmt.Printf("%s worktree %s\n", verb, path)
+	}
+	for _, branch := range result.Branches {
+		fmt.Printf("%s branch %s\n", verb, branch)
+	}
+	for _, run := range result.Runs {
+		fmt.Printf("%s run %s (%s, started %s, %d bytes): %s\n", verb, run.RunID, run.Status, run.StartedAt.Format(time.RFC3339), run.Bytes, run.Reason)
+	}
+	for _, sum := range result.Blobs {
+		fmt.Printf("%s blob %s\n", verb, sum)
+	}
+	fmt.Printf("gc %s %d worktrees, %d branches, %d runs and %d blobs (%d bytes); kept %d runs\n",
+		verb, len(result.Worktrees), len(result.Branches), len(result.Runs), len(result.Blobs), result.FreedBytes, result.Kept)
+}
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN")
+	root := flags.String("repo", "", "Path to repository root (defaults to the run's repository)")
+	format := flags.String("format", app.ExportPatches, "Export format: patches, bundle or branch")
+	out := flags.String("out", "", "Output directory (defaults to exports/<run>)")
+	branch := flags.String("branch", "", "Branch to create (defaults to the configured prefix plus the run id)")
+	remote := flags.String("remote", "", "Remote to push the branch to")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatalf("usage: atqos export [flags] <run-id>")
+	}
+
+	dbFile := dbDSN()
+	repoPath := *root
+	if repoPath != "" {
+		var err error
+		if repoPath, err = filepath.Abs(repoPath); err != nil {
+			log.Fatalf("failed to resolve repo path: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd := app.ExportCommand{
+		DBPath:   dbFile,
+		RepoPath: repoPath,
+		RunID:    flags.Arg(0),
+		Format:   *format,
+		OutDir:   *out,
+		Branch:   *branch,
+		Remote:   *remote,
+	}
+	result, err := cmd.Run(ctx)
+	if err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+	for _, path := range result.Files {
+		fmt.Println(path)
+	}
+	fmt.Printf("exported %d changes to branch %s (%s)\n", result.Applied, result.Branch, result.Head)
+	if result.Conflicts > 0 {
+		fmt.Printf("%d changes did not apply cleanly and were left out\n", result.Conflicts)
+	}
+	fmt.Printf("PR description written to %s\n", result.Description)
+}
+
+func runDB(args []string) {
+	if len(args) == 0 || (args[0] != "migrate" && args[0] != "version") {
+		log.Fatalf("usage: atqos db migrate|version [-db path]")
+	}
+	flags := flag.NewFlagSet("db "+args[0], flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN")
+	_ = flags.Parse(args[1:])
+
+	dbFile := dbDSN()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "migrate":
+		result, err := app.DBMigrateCommand{DBPath: dbFile}.Run(ctx)
+		for _, name := range result.Applied {
+			fmt.Printf("applied %s\n", name)
+		}
+		if err != nil {
+			log.Fatalf("migrate failed: %v", err)
+		}
+		fmt.Printf("schema at version %d (was %d)\n", result.To, result.From)
+	case "version":
+		version, err := app.DBVersionCommand{DBPath: dbFile}.Run(ctx)
+		if err != nil {
+			log.Fatalf("version failed: %v", err)
+		}
+		fmt.Printf("schema version %d (latest %d)\n", version.Current, version.Latest)
+		if version.Current > version.Latest {
+			fmt.Println("database is newer than this binary; upgrade atqos")
+		}
+		for _, migration := range version.Pending {
+			fmt.Printf("pending %04d_%s\n", migration.Version, migration.Name)
+		}
+	}
+}
+
+func runBundle(args []string) {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		log.Fatalf("usage: atqos bundle export|import [flags] <run-id|bundle>")
+	}
+	flags := flag.NewFlagSet("bundle "+args[0], flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN")
+	artifacts := flags.String("artifacts", "artifacts", "Artifact directory to restore files into (import)")
+	out := flags.String("out", "", "Bundle file to write (export; defaults to exports/<run>.bundle.tar.gz)")
+	_ = flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		log.Fatalf("usage: atqos bundle export [-db path] [-out file] <run-id>\n       atqos bundle import [-db path] [-artifacts dir] <bundle>")
+	}
+
+	dbFile := dbDSN()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "export":
+		result, err := app.BundleExportCommand{DBPath: dbFile, RunID: flags.Arg(0), Out: *out}.Run(ctx)
+		if err != nil {
+			log.Fatalf("bundle export failed: %v", err)
+		}
+		for _, path := range result.Missing {
+			fmt.Printf("missing artifact %s\n", path)
+		}
+		fmt.Printf("wrote %s (%d files)\n", result.Path, result.Files)
+	case "import":
+		artifactRoot, err := filepath.Abs(*artifacts)
+		if err != nil {
+			log.Fatalf("failed to resolve artifact path: %v", err)
+		}
+		result, err := app.BundleImportCommand{DBPath: dbFile, ArtifactDir: artifactRoot, Path: flags.Arg(0)}.Run(ctx)
+		if err != nil {
+			log.Fatalf("bundle import failed: %v", err)
+		}
+		fmt.Printf("imported run %s: %d findings, %d tasks, %d attempts, %d artifacts\n",
+			result.RunID, result.Findings, result.Tasks, result.Attempts, result.Artifacts)
+		fmt.Printf("restored %d files to %s\n", result.Files, result.Dir)
+	}
+}
+
+func runSearch(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN")
+	runID := flags.String("run", "", "Only search this run")
+	tool := flags.String("tool", "", "Only search findings, tasks and output from this tool")
+	limit := flags.Int("limit", 20, "Maximum number of hits")
+	_ = flags.Parse(args)
+	// Flags may follow the query, so keep parsing after each positional argument.
+	var terms []string
+	for flags.NArg() > 0 {
+		terms = append(terms, flags.Arg(0))
+		_ = flags.Parse(flags.Args()[1:])
+	}
+	if len(terms) == 0 {
+		log.Fatalf("usage: atqos search \"<query>\" [-run id] [-tool name] [-limit n] [-db path]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hits, err := app.SearchCommand{
+		DBPath: dbDSN(),
+		Query:  strings.Join(terms, " "),
+		RunID:  *runID,
+		Tool:   *tool,
+		Limit:  *limit,
+	}.Run(ctx)
+	if err != nil {
+		log.Fatalf("search failed: %v", err)
+	}
+	if len(hits) == 0 {
+		fmt.Println("no matches")
+		return
+	}
+	for i, hit := range hits {
+		ref := ""
+		if hit.RefID > 0 {
+			ref = fmt.Sprintf(" #%d", hit.RefID)
+		}
+		fmt.Printf("%d. %s %s%s [%s] %s\n", i+1, hit.RunID, hit.Kind, ref, hit.Tool, hit.Title)
+		if hit.Path != "" {
+			fmt.Printf("   %s\n", hit.Path)
+		}
+		if snippet := strings.Join(strings.Fields(hit.Snippet), " "); snippet != "" {
+			fmt.Printf("   %s\n", snippet)
+		}
+	}
+}
+
+// dbFlags registers -db and -config on flags and returns a function giving
+// the store to open: the config's store.dsn when set, as for runs, else -db.
+func dbFlags(flags *flag.FlagSet, usage string) func() string {
+	dbPath := flags.String("db", "artifacts/atqos.db", usage)
+	configPath := flags.String("config", "", "Optional config file path (store.dsn overrides -db)")
+	return func() string {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+		if cfg.Store.DSN != "" {
+			return cfg.Store.DSN
+		}
+		return resolveDB(*dbPath)
+	}
+}
+
+func resolveDB(dsn string) string {
+	if !store.IsPath(dsn) {
+		return dsn
+	}
+	path, err := filepath.Abs(dsn)
+	if err != nil {
+		log.Fatalf("failed to resolve db path: %v", err)
+	}
+	return path
+}
+
+func runEvents(args []string) {
+	const usage = "usage: atqos events verify [-artifacts dir] [-require-chain] <events.jsonl|run-dir|run-id>\n       atqos events check [-db path] [-artifacts dir] <events.jsonl|run-dir|run-id>"
+	if len(args) == 0 || (args[0] != "verify" && args[0] != "check") {
+		log.Fatal(usage)
+	}
+	flags := flag.NewFlagSet("events "+args[0], flag.ExitOnError)
+	artifacts := flags.String("artifacts", "artifacts", "Artifact directory used to resolve a run ID")
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN (check)")
+	requireChain := flags.Bool("require-chain", false, "Fail if the log is not hash chained (verify)")
+	_ = flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		log.Fatal(usage)
+	}
+
+	if args[0] == "check" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		result, err := app.EventsCheckCommand{
+			DBPath:      dbDSN(),
+			ArtifactDir: *artifacts,
+			Target:      flags.Arg(0),
+		}.Run(ctx)
+		if err != nil {
+			log.Fatalf("events check failed: %v", err)
+		}
+		fmt.Printf("%s: run %s\n", result.Path, result.RunID)
+		for _, diff := range result.Diffs {
+			fmt.Println("  " + diff)
+		}
+		if len(result.Diffs) > 0 {
+			fmt.Printf("DRIFT: %d differences between events and database\n", len(result.Diffs))
+			os.Exit(1)
+		}
+		fmt.Println("OK")
+		return
+	}
+
+	result, err := app.EventsVerifyCommand{
+		ArtifactDir:  *artifacts,
+		Target:       flags.Arg(0),
+		RequireChain: *requireChain,
+	}.Run()
+	if err != nil {
+		log.Fatalf("events verify failed: %v", err)
+	}
+	chain := "not chained"
+	if result.Chained {
+		chain = "hash chained, head " + result.LastHash
+	}
+	fmt.Printf("%s: run %s, %d events, seq %d..%d, %s\n", result.Path, result.RunID, result.Events, result.FirstSeq, result.LastSeq, chain)
+	for _, problem := range result.Problems {
+		fmt.Println("  " + problem)
+	}
+	if !result.OK() {
+		fmt.Printf("FAILED: %d problems\n", len(result.Problems))
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
+
+func runReplayEvents(args []string) {
+	flags := flag.NewFlagSet("replay-events", flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN to rebuild the run into")
+	artifacts := flags.String("artifacts", "artifacts", "Artifact directory used to resolve a run ID")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatalf("usage: atqos replay-events [-db path] [-artifacts dir] <events.jsonl|run-dir|run-id>")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := app.ReplayEventsCommand{
+		DBPath:      dbDSN(),
+		ArtifactDir: *artifacts,
+		Target:      flags.Arg(0),
+	}.Run(ctx)
+	if err != nil {
+		log.Fatalf("replay-events failed: %v", err)
+	}
+	fmt.Printf("rebuilt run %s: %d findings, %d tasks, %d attempts, %d artifacts\n",
+		result.RunID, result.Findings, result.Tasks, result.Attempts, result.Artifacts)
+}
+
+func runWatch(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	dbDSN := dbFlags(flags, "SQLite database path or postgres:// DSN")
+	artifacts := flags.String("artifacts", "artifacts", "Artifact directory used to resolve a run ID")
+	interval := flags.Duration("interval", time.Second, "Refresh interval")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatalf("usage: atqos watch [-db path] [-artifacts dir] [-interval 1s] <run-id|run-dir|events.jsonl>")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd := app.WatchCommand{
+		DBPath:      dbDSN(),
+		ArtifactDir: *artifacts,
+		Target:      flags.Arg(0),
+		Interval:    *interval,
+	}
+	if err := cmd.Run(ctx); err != nil {
+		log.Fatalf("watch failed: %v", err)
+	}
+}
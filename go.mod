@@ -2,7 +2,12 @@ module atqos
 
 go 1.22
 
-require modernc.org/sqlite v1.29.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/sys v0.16.0
+	modernc.org/sqlite v1.29.0
+)
 
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
@@ -11,7 +16,6 @@ require (
 	github.com/mattn/go-isatty v0.0.16 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
-	golang.org/x/sys v0.16.0 // indirect
 	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
 	modernc.org/libc v1.41.0 // indirect
 	modernc.org/mathutil v1.6.0 // indirect
@@ -20,10 +20,49 @@ type Request struct {
 	Targets       map[string]string `json:"targets,omitempty"`
 	Instructions  string            `json:"instructions"`
 	Validation    Validation        `json:"validation"`
+
+	PreviousAttempts []PreviousAttempt `json:"previous_attempts,omitempty"`
+}
+
+type PreviousAttempt struct {
+	Turn               int      `json:"turn"`
+	Status             string   `json:"status"`
+	Summary            string   `json:"summary"`
+	ValidationExitCode int      `json:"validation_exit_code"`
+	Violations         []string `json:"violations,omitempty"`
+	Output             string   `json:"output,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	WallTimeMs   int64   `json:"wall_time_ms"`
+}
+
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+	u.WallTimeMs += other.WallTimeMs
+}
+
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+type SessionAgent interface {
+	Agent
+	EndSession(req Request)
 }
 
 type Validation struct {
-	Commands []string `json:"commands"`
+	Commands []string   `json:"commands"`
+	Argv     [][]string `json:"argv,omitempty"`
+
+	// Run runs the commands the way attempt validation does and returns
+	// their output; it backs the run_validation tool of in-process agents.
+	Run func(ctx context.Context) (string, error) `json:"-"`
 }
 
 type Result struct {
@@ -33,4 +72,11 @@ type Result struct {
 	Status        string   `json:"status"`
 	Summary       string   `json:"summary"`
 	FilesChanged  []string `json:"files_changed"`
+	Actions       *Actions `json:"actions,omitempty"`
+	Notes         *Notes   `json:"notes,omitempty"`
+	Usage         *Usage   `json:"usage,omitempty"`
+
+	Stdout   []byte `json:"-"`
+	Stderr   []byte `json:"-"`
+	ExitCode int    `json:"-"`
 }
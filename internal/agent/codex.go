@@ -1,23 +1,39 @@
 package agent
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 )
 
 type CodexCLIAdapter struct {
-	command []string
+	command  []string
+	env      map[string]string
+	versions []int
 }
 
 func NewCodexCLI(command []string) *CodexCLIAdapter {
 	return &CodexCLIAdapter{command: command}
 }
 
+func (a *CodexCLIAdapter) WithEnv(env map[string]string) *CodexCLIAdapter {
+	a.env = env
+	return a
+}
+
+func (a *CodexCLIAdapter) WithVersions(versions []int) *CodexCLIAdapter {
+	a.versions = versions
+	return a
+}
+
+func (a *CodexCLIAdapter) ProtocolVersions() []int {
+	if len(a.versions) == 0 {
+		return []int{ProtocolV1}
+	}
+	return a.versions
+}
+
 func (a *CodexCLIAdapter) Name() string {
 	return "codex"
 }
@@ -31,34 +47,7 @@ func (a *CodexCLIAdapter) Invoke(ctx context.Context, req Request) (Result, erro
 		return Result{}, fmt.Errorf("codex command not configured")
 	}
 
-	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
-	stdin := &bytes.Buffer{}
-	if err := json.NewEncoder(stdin).Encode(req); err != nil {
-		return Result{}, err
-	}
-	cmd.Stdin = stdin
-
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return Result{}, fmt.Errorf("codex command failed: %w", err)
-	}
-
-	var result Result
-	if err := json.NewDecoder(&stdout).Decode(&result); err != nil {
-		return Result{}, fmt.Errorf("decode codex result: %w", err)
-	}
-	if result.SchemaVersion != req.SchemaVersion {
-		return Result{}, fmt.Errorf("codex result schema mismatch: %d", result.SchemaVersion)
-	}
-	if result.RunID != req.RunID || result.TaskID != req.TaskID {
-		return Result{}, fmt.Errorf("codex result does not match request")
-	}
-
-	return result, nil
+	return runCommand(ctx, "codex", command, a.env, req)
 }
 
 func defaultCodexCommand() []string {
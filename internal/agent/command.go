@@ -5,18 +5,38 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 )
 
 type CommandAdapter struct {
-	name    string
-	command []string
+	name     string
+	command  []string
+	env      map[string]string
+	versions []int
 }
 
 func NewCommandAdapter(name string, command []string) *CommandAdapter {
 	return &CommandAdapter{name: name, command: command}
 }
 
+func (a *CommandAdapter) WithEnv(env map[string]string) *CommandAdapter {
+	a.env = env
+	return a
+}
+
+func (a *CommandAdapter) WithVersions(versions []int) *CommandAdapter {
+	a.versions = versions
+	return a
+}
+
+func (a *CommandAdapter) ProtocolVersions() []int {
+	if len(a.versions) == 0 {
+		return []int{ProtocolV1}
+	}
+	return a.versions
+}
+
 func (a *CommandAdapter) Name() string {
 	return a.name
 }
@@ -25,8 +45,20 @@ func (a *CommandAdapter) Invoke(ctx context.Context, req Request) (Result, error
 	if len(a.command) == 0 {
 		return Result{}, fmt.Errorf("agent command not configured")
 	}
+	return runCommand(ctx, "agent", a.command, a.env, req)
+}
 
-	cmd := exec.CommandContext(ctx, a.command[0], a.command[1:]...)
+func runCommand(ctx context.Context, label string, command []string, env map[string]string, req Request) (Result, error) {
+	if err := ValidateRequest(req); err != nil {
+		return Result{}, err
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	if len(env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
 	stdin := &bytes.Buffer{}
 	if err := json.NewEncoder(stdin).Encode(req); err != nil {
 		return Result{}, err
@@ -38,14 +70,33 @@ func (a *CommandAdapter) Invoke(ctx context.Context, req Request) (Result, error
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		return Result{}, fmt.Errorf("agent command failed: %w", err)
+	runErr := cmd.Run()
+	output := Result{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: exitCode(runErr),
+	}
+	if runErr != nil {
+		return output, fmt.Errorf("%s command failed: %w", label, runErr)
 	}
 
-	var result Result
-	if err := json.NewDecoder(&stdout).Decode(&result); err != nil {
-		return Result{}, fmt.Errorf("decode agent result: %w", err)
+	result, err := DecodeResult(req, output.Stdout)
+	result.Stdout = output.Stdout
+	result.Stderr = output.Stderr
+	result.ExitCode = output.ExitCode
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", label, err)
 	}
 
 	return result, nil
 }
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
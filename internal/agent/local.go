@@ -15,6 +15,10 @@ func (a *LocalAdapter) Name() string {
 	return "local"
 }
 
+func (a *LocalAdapter) ProtocolVersions() []int {
+	return SupportedVersions
+}
+
 func (a *LocalAdapter) Invoke(ctx context.Context, req Request) (Result, error) {
 	if req.TaskID == 0 {
 		return Result{}, fmt.Errorf("task id required")
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OpenAIConfig struct {
+	BaseURL           string
+	APIKey            string
+	Model             string
+	MaxTurns          int
+	InputCostPerMTok  float64
+	OutputCostPerMTok float64
+	Client            *http.Client
+}
+
+type OpenAIAdapter struct {
+	config OpenAIConfig
+
+	mu       sync.Mutex
+	sessions map[string][]chatMessage
+}
+
+func NewOpenAI(config OpenAIConfig) *OpenAIAdapter {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com"
+	}
+	config.BaseURL = strings.TrimSuffix(strings.TrimSuffix(config.BaseURL, "/"), "/v1")
+	if config.MaxTurns <= 0 {
+		config.MaxTurns = 20
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return &OpenAIAdapter{config: config, sessions: make(map[string][]chatMessage)}
+}
+
+func OpenAIConfigFromEnv() (OpenAIConfig, bool) {
+	baseURL := os.Getenv("ATQOS_OPENAI_BASE_URL")
+	model := os.Getenv("ATQOS_OPENAI_MODEL")
+	if baseURL == "" && model == "" {
+		return OpenAIConfig{}, false
+	}
+	apiKey := os.Getenv("ATQOS_OPENAI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	maxTurns, _ := strconv.Atoi(os.Getenv("ATQOS_OPENAI_MAX_TURNS"))
+	return OpenAIConfig{
+		BaseURL:  baseURL,
+		APIKey:   apiKey,
+		Model:    model,
+		MaxTurns: maxTurns,
+	}, true
+}
+
+func (a *OpenAIAdapter) Name() string {
+	return "openai"
+}
+
+func (a *OpenAIAdapter) ProtocolVersions() []int {
+	return SupportedVersions
+}
+
+func (a *OpenAIAdapter) Invoke(ctx context.Context, req Request) (Result, error) {
+	if a.config.Model == "" {
+		return Result{}, fmt.Errorf("openai model not configured")
+	}
+
+	tools := newWorkspaceTools(req)
+	messages := a.session(req)
+	defer func() {
+		a.mu.Lock()
+		a.sessions[sessionKey(req)] = messages
+		a.mu.Unlock()
+	}()
+
+	result := Result{
+		SchemaVersion: req.SchemaVersion,
+		RunID:         req.RunID,
+		TaskID:        req.TaskID,
+		Usage:         &Usage{},
+	}
+
+	for turn := 0; turn < a.config.MaxTurns; turn++ {
+		reply, usage, err := a.complete(ctx, messages, req.SchemaVersion)
+		result.Usage.Add(usage)
+		if err != nil {
+			result.Stdout = transcript(messages)
+			result.Stderr = []byte(err.Error())
+			result.ExitCode = 1
+			return result, err
+		}
+		messages = append(messages, reply)
+
+		// Only the complete tool reports an outcome; a model that stops
+		// talking has not claimed success.
+		if len(reply.ToolCalls) == 0 {
+			result.Status = "failure"
+			result.Summary = strings.TrimSpace("stopped without calling complete: " + reply.Content)
+			result.ExitCode = 1
+			break
+		}
+
+		done := false
+		for _, call := range reply.ToolCalls {
+			output, finish := tools.call(ctx, call.Function.Name, call.Function.Arguments)
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    output,
+			})
+			if finish != nil {
+				result.Status = finish.Status
+				result.Summary = finish.Summary
+				if req.SchemaVersion >= ProtocolV2 && (finish.SystematicIssue || len(finish.SuggestedFollowups) > 0) {
+					result.Notes = &Notes{SystematicIssue: finish.SystematicIssue, SuggestedFollowups: finish.SuggestedFollowups}
+				}
+				done = true
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	result.FilesChanged = tools.filesChanged()
+	if req.SchemaVersion >= ProtocolV2 && len(tools.commands) > 0 {
+		result.Actions = &Actions{CommandsRan: tools.commands}
+	}
+	result.Stdout = transcript(messages)
+	if result.Status == "" {
+		result.Status = "blocked"
+		result.Summary = fmt.Sprintf("turn budget of %d exhausted", a.config.MaxTurns)
+		result.ExitCode = 1
+	}
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("openai agent: %s", result.Summary)
+	}
+	return result, nil
+}
+
+func (a *OpenAIAdapter) EndSession(req Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, sessionKey(req))
+}
+
+func (a *OpenAIAdapter) session(req Request) []chatMessage {
+	a.mu.Lock()
+	previous := a.sessions[sessionKey(req)]
+	a.mu.Unlock()
+
+	if len(previous) == 0 || len(req.PreviousAttempts) == 0 {
+		return []chatMessage{
+			{Role: "system", Content: systemPrompt(req)},
+			{Role: "user", Content: req.Instructions},
+		}
+	}
+	feedback := req.PreviousAttempts[len(req.PreviousAttempts)-1]
+	return append(previous, chatMessage{Role: "user", Content: feedbackPrompt(feedback)})
+}
+
+func sessionKey(req Request) string {
+	return fmt.Sprintf("%s/%d/%s", req.RunID, req.TaskID, req.WorkspacePath)
+}
+
+func (a *OpenAIAdapter) complete(ctx context.Context, messages []chatMessage, version int) (chatMessage, Usage, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:      a.config.Model,
+		Messages:   messages,
+		Tools:      toolDefinitions(version),
+		ToolChoice: "auto",
+	})
+	if err != nil {
+		return chatMessage{}, Usage{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.BaseURL+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return chatMessage{}, Usage{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.config.APIKey)
+	}
+
+	resp, err := a.config.Client.Do(httpReq)
+	if err != nil {
+		return chatMessage{}, Usage{}, fmt.Errorf("chat completion request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return chatMessage{}, Usage{}, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return chatMessage{}, Usage{}, fmt.Errorf("chat completion status %d: %s", resp.StatusCode, truncate(string(data), 512))
+	}
+
+	var parsed chatResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return chatMessage{}, Usage{}, fmt.Errorf("decode chat completion: %w", err)
+	}
+	usage := Usage{
+		InputTokens:  parsed.Usage.PromptTokens,
+		OutputTokens: parsed.Usage.CompletionTokens,
+	}
+	usage.CostUSD = float64(usage.InputTokens)*a.config.InputCostPerMTok/1e6 + float64(usage.OutputTokens)*a.config.OutputCostPerMTok/1e6
+	if len(parsed.Choices) == 0 {
+		return chatMessage{}, usage, fmt.Errorf("chat completion returned no choices")
+	}
+	message := parsed.Choices[0].Message
+	if message.Role == "" {
+		message.Role = "assistant"
+	}
+	return message, usage, nil
+}
+
+func systemPrompt(req Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are an automated software engineer working on %s task %d from the %s tool.\n", req.TaskType, req.TaskID, req.Tool)
+	b.WriteString("Use the provided tools to inspect and edit files in the workspace. Paths are relative to the workspace root.\n")
+	if len(req.AllowedPaths) > 0 {
+		fmt.Fprintf(&b, "You may only read and write files under: %s.\n", strings.Join(req.AllowedPaths, ", "))
+	}
+	if len(req.Validation.Commands) > 0 {
+		fmt.Fprintf(&b, "Your change is validated with: %s. Use run_validation to check your work.\n", strings.Join(req.Validation.Commands, "; "))
+	}
+	b.WriteString("When finished, call complete with a status of success, failure or blocked and a short summary.")
+	return b.String()
+}
+
+func feedbackPrompt(attempt PreviousAttempt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Validation failed after turn %d (exit code %d).\n", attempt.Turn, attempt.ValidationExitCode)
+	for _, violation := range attempt.Violations {
+		fmt.Fprintf(&b, "- %s\n", violation)
+	}
+	if attempt.Output != "" {
+		fmt.Fprintf(&b, "Output:\n%s\n", attempt.Output)
+	}
+	b.WriteString("Fix the remaining problems, then call complete again.")
+	return b.String()
+}
+
+func transcript(messages []chatMessage) []byte {
+	data, _ := json.MarshalIndent(messages, "", "  ")
+	return data
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	return text[:limit] + "...[truncated]"
+}
+
+type chatRequest struct {
+	Model      string           `json:"model"`
+	Messages   []chatMessage    `json:"messages"`
+	Tools      []toolDefinition `json:"tools,omitempty"`
+	ToolChoice string           `json:"tool_choice,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type toolDefinition struct {
+	Type     string       `json:"type"`
+	Function toolFunction `json:"function"`
+}
+
+type toolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
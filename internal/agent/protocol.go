@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = 2
+)
+
+var SupportedVersions = []int{ProtocolV1, ProtocolV2}
+
+type VersionedAgent interface {
+	Agent
+	ProtocolVersions() []int
+}
+
+type Actions struct {
+	CommandsRan []string `json:"commands_ran,omitempty"`
+	TestsRan    []string `json:"tests_ran,omitempty"`
+}
+
+type Notes struct {
+	SystematicIssue    bool     `json:"systematic_issue,omitempty"`
+	SuggestedFollowups []string `json:"suggested_followups,omitempty"`
+}
+
+func Negotiate(a Agent) int {
+	versioned, ok := a.(VersionedAgent)
+	if !ok {
+		return ProtocolV1
+	}
+	best := 0
+	for _, version := range versioned.ProtocolVersions() {
+		if supported(version) && version > best {
+			best = version
+		}
+	}
+	if best == 0 {
+		return ProtocolV1
+	}
+	return best
+}
+
+func CheckVersions(versions []int) error {
+	for _, version := range versions {
+		if supported(version) {
+			return nil
+		}
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	return fmt.Errorf("no supported protocol version in %v (supported: %v)", versions, SupportedVersions)
+}
+
+func ValidateRequest(req Request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return validateDocument("request", req.SchemaVersion, data)
+}
+
+func DecodeResult(req Request, data []byte) (Result, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&raw); err != nil {
+		return Result{}, &ProtocolError{Kind: "result", Version: req.SchemaVersion, Issues: []string{"no JSON object on stdout: " + err.Error()}}
+	}
+	if err := validateDocument("result", req.SchemaVersion, raw); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return Result{}, fmt.Errorf("decode agent result: %w", err)
+	}
+	var issues []string
+	if result.RunID != req.RunID {
+		issues = append(issues, fmt.Sprintf("$.run_id %q does not match request %q", result.RunID, req.RunID))
+	}
+	if result.TaskID != req.TaskID {
+		issues = append(issues, fmt.Sprintf("$.task_id %d does not match request %d", result.TaskID, req.TaskID))
+	}
+	if len(issues) > 0 {
+		return result, &ProtocolError{Kind: "result", Version: req.SchemaVersion, Issues: issues}
+	}
+	return result, nil
+}
+
+func supported(version int) bool {
+	for _, candidate := range SupportedVersions {
+		if candidate == version {
+			return true
+		}
+	}
+	return false
+}
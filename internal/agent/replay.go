@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"atqos/internal/git"
+)
+
+const (
+	ReplayModeRecord = "record"
+	ReplayModeReplay = "replay"
+)
+
+type ReplayAdapter struct {
+	mode  string
+	dir   string
+	inner Agent
+
+	mu    sync.Mutex
+	plays map[string]int
+}
+
+type cassette struct {
+	Fingerprint string          `json:"fingerprint"`
+	Request     json.RawMessage `json:"request"`
+	Entries     []cassetteEntry `json:"entries"`
+}
+
+type cassetteEntry struct {
+	Result   Result `json:"result"`
+	Patch    string `json:"patch"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewReplay(mode string, dir string, inner Agent) (*ReplayAdapter, error) {
+	switch mode {
+	case ReplayModeRecord:
+		if inner == nil {
+			return nil, fmt.Errorf("record mode requires an agent to record")
+		}
+	case ReplayModeReplay:
+	default:
+		return nil, fmt.Errorf("unknown replay mode %q", mode)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("cassette directory required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &ReplayAdapter{mode: mode, dir: dir, inner: inner, plays: make(map[string]int)}, nil
+}
+
+func (a *ReplayAdapter) Name() string {
+	if a.inner != nil {
+		return a.inner.Name()
+	}
+	return "replay"
+}
+
+func (a *ReplayAdapter) Invoke(ctx context.Context, req Request) (Result, error) {
+	fingerprint, normalized, err := Fingerprint(req)
+	if err != nil {
+		return Result{}, err
+	}
+	if a.mode == ReplayModeRecord {
+		return a.record(ctx, req, fingerprint, normalized)
+	}
+	return a.replay(ctx, req, fingerprint)
+}
+
+func (a *ReplayAdapter) ProtocolVersions() []int {
+	if a.inner == nil {
+		return SupportedVersions
+	}
+	return protocolVersions(a.inner)
+}
+
+func (a *ReplayAdapter) EndSession(req Request) {
+	if session, ok := a.inner.(SessionAgent); ok {
+		session.EndSession(req)
+	}
+}
+
+func (a *ReplayAdapter) record(ctx context.Context, req Request, fingerprint string, normalized []byte) (Result, error) {
+	before, err := git.SnapshotTree(ctx, req.WorkspacePath)
+	if err != nil {
+		return Result{}, err
+	}
+	result, invokeErr := a.inner.Invoke(ctx, req)
+	after, err := git.SnapshotTree(ctx, req.WorkspacePath)
+	if err != nil {
+		return result, err
+	}
+	patch, err := git.DiffTrees(ctx, req.WorkspacePath, before, after)
+	if err != nil {
+		return result, err
+	}
+
+	entry := cassetteEntry{
+		Result:   result,
+		Patch:    string(patch.Data),
+		Stdout:   string(result.Stdout),
+		Stderr:   string(result.Stderr),
+		ExitCode: result.ExitCode,
+	}
+	if invokeErr != nil {
+		entry.Error = invokeErr.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tape, err := a.load(fingerprint)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return result, err
+	}
+	tape.Fingerprint = fingerprint
+	tape.Request = normalized
+	tape.Entries = append(tape.Entries, entry)
+	if err := a.save(tape); err != nil {
+		return result, err
+	}
+	return result, invokeErr
+}
+
+func (a *ReplayAdapter) replay(ctx context.Context, req Request, fingerprint string) (Result, error) {
+	a.mu.Lock()
+	tape, err := a.load(fingerprint)
+	play := a.plays[fingerprint]
+	a.plays[fingerprint] = play + 1
+	a.mu.Unlock()
+	if err != nil {
+		return Result{}, fmt.Errorf("no recording for request fingerprint %s: %w", fingerprint, err)
+	}
+	if len(tape.Entries) == 0 {
+		return Result{}, fmt.Errorf("recording %s has no entries", fingerprint)
+	}
+	if play >= len(tape.Entries) {
+		play = len(tape.Entries) - 1
+	}
+	entry := tape.Entries[play]
+
+	if err := git.Apply(ctx, req.WorkspacePath, []byte(entry.Patch)); err != nil {
+		return Result{}, fmt.Errorf("replay %s: %w", fingerprint, err)
+	}
+
+	result := entry.Result
+	result.SchemaVersion = req.SchemaVersion
+	result.RunID = req.RunID
+	result.TaskID = req.TaskID
+	result.Stdout = []byte(entry.Stdout)
+	result.Stderr = []byte(entry.Stderr)
+	result.ExitCode = entry.ExitCode
+	if entry.Error != "" {
+		return result, errors.New(entry.Error)
+	}
+	return result, nil
+}
+
+func (a *ReplayAdapter) path(fingerprint string) string {
+	return filepath.Join(a.dir, fingerprint+".json")
+}
+
+func (a *ReplayAdapter) load(fingerprint string) (cassette, error) {
+	data, err := os.ReadFile(a.path(fingerprint))
+	if err != nil {
+		return cassette{}, err
+	}
+	var tape cassette
+	if err := json.Unmarshal(data, &tape); err != nil {
+		return cassette{}, fmt.Errorf("decode cassette %s: %w", fingerprint, err)
+	}
+	return tape, nil
+}
+
+func (a *ReplayAdapter) save(tape cassette) error {
+	data, err := json.MarshalIndent(tape, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.path(tape.Fingerprint), data, 0o644)
+}
+
+func Fingerprint(req Request) (string, []byte, error) {
+	// Workspaces usually live under the repository, so replace the longest
+	// path first or the run-specific workspace suffix would remain.
+	paths := [][2]string{{req.RepoPath, "$REPO"}, {req.WorkspacePath, "$WORKSPACE"}}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i][0]) > len(paths[j][0]) })
+	var substitutions []string
+	for _, pair := range paths {
+		if pair[0] != "" {
+			substitutions = append(substitutions, pair[0], pair[1])
+		}
+	}
+	normalize := strings.NewReplacer(substitutions...).Replace
+
+	commands := make([]string, 0, len(req.Validation.Commands))
+	for _, command := range req.Validation.Commands {
+		commands = append(commands, normalize(command))
+	}
+	data, err := json.Marshal(struct {
+		SchemaVersion int               `json:"schema_version"`
+		TaskType      string            `json:"task_type"`
+		Tool          string            `json:"tool"`
+		AllowedPaths  []string          `json:"allowed_paths"`
+		Targets       map[string]string `json:"targets,omitempty"`
+		Instructions  string            `json:"instructions"`
+		Validation    []string          `json:"validation"`
+		Turn          int               `json:"turn"`
+	}{
+		SchemaVersion: req.SchemaVersion,
+		TaskType:      req.TaskType,
+		Tool:          req.Tool,
+		AllowedPaths:  req.AllowedPaths,
+		Targets:       req.Targets,
+		Instructions:  normalize(req.Instructions),
+		Validation:    commands,
+		Turn:          len(req.PreviousAttempts) + 1,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), data, nil
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+type editAgent struct{}
+
+func (editAgent) Name() string { return "edit" }
+
+func (editAgent) Invoke(ctx context.Context, req Request) (Result, error) {
+	if err := os.WriteFile(filepath.Join(req.WorkspacePath, "fixed.txt"), []byte("fixed\n"), 0o644); err != nil {
+		return Result{}, err
+	}
+	return Result{Status: "success", Summary: "edited", FilesChanged: []string{"fixed.txt"}}, nil
+}
+
+func TestReplayFromDifferentWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	tapes := t.TempDir()
+	request := func(runID string) Request {
+		workspace := filepath.Join(repo, "artifacts", runID, "worktrees", "pool-1")
+		initWorkspace(t, workspace)
+		return Request{
+			SchemaVersion: ProtocolV2,
+			RunID:         runID,
+			TaskID:        1,
+			TaskType:      "fix_test",
+			Tool:          "pytest",
+			RepoPath:      repo,
+			WorkspacePath: workspace,
+			Instructions:  "Fix the failing test in " + workspace + "/tests/test_a.py",
+			Validation:    Validation{Commands: []string{"cd " + workspace + " && pytest"}},
+		}
+	}
+
+	recorder, err := NewReplay(ReplayModeRecord, tapes, editAgent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := request("run-1")
+	if _, err := recorder.Invoke(ctx, recorded); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := NewReplay(ReplayModeReplay, tapes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := request("run-2")
+	result, err := player.Invoke(ctx, replayed)
+	if err != nil {
+		t.Fatalf("replay from another workspace missed: %v", err)
+	}
+	if result.Status != "success" || result.RunID != "run-2" {
+		t.Fatalf("replayed result = %+v", result)
+	}
+	if data, err := os.ReadFile(filepath.Join(replayed.WorkspacePath, "fixed.txt")); err != nil || string(data) != "fixed\n" {
+		t.Fatalf("replayed patch not applied: %q, %v", data, err)
+	}
+}
+
+func initWorkspace(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("workspace\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "--quiet", "-m", "baseline"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+}
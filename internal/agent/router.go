@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Route struct {
+	TaskType      string
+	Tool          string
+	Severity      string
+	Agent         string
+	Fallback      string
+	FallbackAfter int
+}
+
+type Router struct {
+	agents       map[string]Agent
+	routes       []Route
+	defaultAgent string
+}
+
+func NewRouter(agents map[string]Agent, routes []Route, defaultAgent string) (*Router, error) {
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents configured")
+	}
+	if defaultAgent == "" && len(agents) == 1 {
+		for name := range agents {
+			defaultAgent = name
+		}
+	}
+	if _, ok := agents[defaultAgent]; !ok {
+		return nil, fmt.Errorf("default agent %q not configured", defaultAgent)
+	}
+	for _, route := range routes {
+		if _, ok := agents[route.Agent]; !ok {
+			return nil, fmt.Errorf("route references unknown agent %q", route.Agent)
+		}
+		if route.Fallback == "" {
+			continue
+		}
+		if _, ok := agents[route.Fallback]; !ok {
+			return nil, fmt.Errorf("route references unknown fallback agent %q", route.Fallback)
+		}
+	}
+	return &Router{agents: agents, routes: routes, defaultAgent: defaultAgent}, nil
+}
+
+func Single(agent Agent) *Router {
+	return &Router{
+		agents:       map[string]Agent{agent.Name(): agent},
+		defaultAgent: agent.Name(),
+	}
+}
+
+func (r *Router) Select(taskType string, tool string, severity string, failures int) Agent {
+	for _, route := range r.routes {
+		if !matches(route.TaskType, taskType) || !matches(route.Tool, tool) || !matches(route.Severity, severity) {
+			continue
+		}
+		if route.Fallback != "" && failures >= fallbackAfter(route) {
+			return r.agents[route.Fallback]
+		}
+		return r.agents[route.Agent]
+	}
+	return r.agents[r.defaultAgent]
+}
+
+func (r *Router) Get(name string) (Agent, bool) {
+	agent, ok := r.agents[name]
+	return agent, ok
+}
+
+func protocolVersions(inner Agent) []int {
+	if versioned, ok := inner.(VersionedAgent); ok {
+		return versioned.ProtocolVersions()
+	}
+	return []int{ProtocolV1}
+}
+
+func matches(want string, got string) bool {
+	return want == "" || want == "*" || want == got
+}
+
+func fallbackAfter(route Route) int {
+	if route.FallbackAfter <= 0 {
+		return 1
+	}
+	return route.FallbackAfter
+}
+
+type ManagedAgent struct {
+	name    string
+	inner   Agent
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+func NewManaged(name string, inner Agent, concurrency int, timeout time.Duration) *ManagedAgent {
+	managed := &ManagedAgent{name: name, inner: inner, timeout: timeout}
+	if concurrency > 0 {
+		managed.slots = make(chan struct{}, concurrency)
+	}
+	return managed
+}
+
+func (a *ManagedAgent) Name() string {
+	return a.name
+}
+
+func (a *ManagedAgent) Invoke(ctx context.Context, req Request) (Result, error) {
+	if a.slots != nil {
+		select {
+		case a.slots <- struct{}{}:
+			defer func() { <-a.slots }()
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	return a.inner.Invoke(ctx, req)
+}
+
+func (a *ManagedAgent) ProtocolVersions() []int {
+	return protocolVersions(a.inner)
+}
+
+func (a *ManagedAgent) EndSession(req Request) {
+	if session, ok := a.inner.(SessionAgent); ok {
+		session.EndSession(req)
+	}
+}
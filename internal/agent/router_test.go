@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+type namedAgent string
+
+func (a namedAgent) Name() string { return string(a) }
+
+func (a namedAgent) Invoke(ctx context.Context, req Request) (Result, error) {
+	return Result{Status: "success"}, nil
+}
+
+func TestRouterSelect(t *testing.T) {
+	agents := map[string]Agent{"cheap": namedAgent("cheap"), "strong": namedAgent("strong"), "backup": namedAgent("backup")}
+	router, err := NewRouter(agents, []Route{
+		{TaskType: "add_test", Agent: "cheap", Fallback: "strong", FallbackAfter: 2},
+		{Tool: "pytest", Severity: "blocker", Agent: "strong"},
+		{TaskType: "*", Tool: "ruff", Agent: "cheap", Fallback: "backup"},
+	}, "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		taskType string
+		tool     string
+		severity string
+		failures int
+		want     string
+	}{
+		{"add_test", "pytest", "blocker", 0, "cheap"},
+		{"add_test", "pytest", "blocker", 1, "cheap"},
+		{"add_test", "pytest", "blocker", 2, "strong"},
+		{"fix_test", "pytest", "blocker", 5, "strong"},
+		{"fix_test", "pytest", "minor", 0, "backup"},
+		{"lint", "ruff", "", 0, "cheap"},
+		{"lint", "ruff", "", 1, "backup"},
+		{"lint", "mypy", "", 0, "backup"},
+	} {
+		if got := router.Select(tc.taskType, tc.tool, tc.severity, tc.failures).Name(); got != tc.want {
+			t.Errorf("Select(%s, %s, %s, %d failures) = %s, want %s", tc.taskType, tc.tool, tc.severity, tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestNewRouterRejectsUnknownAgents(t *testing.T) {
+	agents := map[string]Agent{"a": namedAgent("a"), "b": namedAgent("b")}
+	for _, tc := range []struct {
+		name         string
+		agents       map[string]Agent
+		routes       []Route
+		defaultAgent string
+		ok           bool
+	}{
+		{name: "no agents", defaultAgent: "a"},
+		{name: "unknown default", agents: agents, defaultAgent: "c"},
+		{name: "ambiguous default", agents: agents},
+		{name: "sole agent is the default", agents: map[string]Agent{"a": namedAgent("a")}, ok: true},
+		{name: "unknown route agent", agents: agents, routes: []Route{{Agent: "c"}}, defaultAgent: "a"},
+		{name: "unknown fallback", agents: agents, routes: []Route{{Agent: "a", Fallback: "c"}}, defaultAgent: "a"},
+		{name: "valid", agents: agents, routes: []Route{{Agent: "a", Fallback: "b"}}, defaultAgent: "b", ok: true},
+	} {
+		if _, err := NewRouter(tc.agents, tc.routes, tc.defaultAgent); (err == nil) != tc.ok {
+			t.Errorf("%s: NewRouter error = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
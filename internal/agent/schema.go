@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+type schema struct {
+	Type                 interface{}        `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Minimum              *float64           `json:"minimum"`
+	MinLength            *int               `json:"minLength"`
+}
+
+type ProtocolError struct {
+	Kind    string
+	Version int
+	Issues  []string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("agent %s violates protocol v%d: %s", e.Kind, e.Version, strings.Join(e.Issues, "; "))
+}
+
+func loadSchema(kind string, version int) (*schema, error) {
+	data, err := schemaFiles.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", kind, version))
+	if err != nil {
+		return nil, fmt.Errorf("no %s schema for protocol v%d", kind, version)
+	}
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decode %s schema v%d: %w", kind, version, err)
+	}
+	return &s, nil
+}
+
+func validateDocument(kind string, version int, data []byte) error {
+	s, err := loadSchema(kind, version)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return &ProtocolError{Kind: kind, Version: version, Issues: []string{"invalid JSON: " + err.Error()}}
+	}
+	var issues []string
+	s.validate("$", doc, &issues)
+	if len(issues) > 0 {
+		return &ProtocolError{Kind: kind, Version: version, Issues: issues}
+	}
+	return nil
+}
+
+func (s *schema) validate(path string, value interface{}, issues *[]string) {
+	if s == nil {
+		return
+	}
+	if s.Const != nil && !sameValue(s.Const, value) {
+		*issues = append(*issues, fmt.Sprintf("%s must be %v", path, s.Const))
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, option := range s.Enum {
+			if sameValue(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*issues = append(*issues, fmt.Sprintf("%s must be one of %v", path, s.Enum))
+			return
+		}
+	}
+	if types := s.types(); len(types) > 0 && !matchesType(types, value) {
+		*issues = append(*issues, fmt.Sprintf("%s must be %s, got %s", path, strings.Join(types, " or "), typeOf(value)))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				*issues = append(*issues, fmt.Sprintf("%s.%s is required", path, key))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*issues = append(*issues, fmt.Sprintf("%s.%s is not allowed", path, key))
+				}
+				continue
+			}
+			property.validate(path+"."+key, v[key], issues)
+		}
+	case []interface{}:
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, issues)
+		}
+	case json.Number:
+		if s.Minimum != nil {
+			if number, err := v.Float64(); err == nil && number < *s.Minimum {
+				*issues = append(*issues, fmt.Sprintf("%s must be >= %v", path, *s.Minimum))
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			*issues = append(*issues, fmt.Sprintf("%s must be at least %d characters", path, *s.MinLength))
+		}
+	}
+}
+
+func (s *schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func matchesType(types []string, value interface{}) bool {
+	actual := typeOf(value)
+	for _, want := range types {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if number, err := v.Float64(); err == nil && number == math.Trunc(number) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+func sameValue(want interface{}, got interface{}) bool {
+	if number, ok := got.(json.Number); ok {
+		value, err := number.Float64()
+		if err != nil {
+			return false
+		}
+		wantNumber, ok := want.(float64)
+		return ok && wantNumber == value
+	}
+	return want == got
+}
@@ -0,0 +1,350 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxToolOutput  = 16000
+	maxReadBytes   = 256 * 1024
+	maxSearchLines = 100
+)
+
+type workspaceTools struct {
+	req      Request
+	root     string
+	changed  map[string]bool
+	commands []string
+}
+
+type toolFinish struct {
+	Status             string   `json:"status"`
+	Summary            string   `json:"summary"`
+	SystematicIssue    bool     `json:"systematic_issue"`
+	SuggestedFollowups []string `json:"suggested_followups"`
+}
+
+func newWorkspaceTools(req Request) *workspaceTools {
+	return &workspaceTools{
+		req:     req,
+		root:    filepath.Clean(req.WorkspacePath),
+		changed: make(map[string]bool),
+	}
+}
+
+func toolDefinitions(version int) []toolDefinition {
+	completeParams := map[string]interface{}{
+		"status":  map[string]interface{}{"type": "string", "enum": []string{"success", "failure", "blocked"}},
+		"summary": map[string]interface{}{"type": "string"},
+	}
+	if version >= ProtocolV2 {
+		completeParams["systematic_issue"] = map[string]interface{}{"type": "boolean", "description": "Set when the failure comes from a problem outside this task, such as broken tooling or a shared dependency"}
+		completeParams["suggested_followups"] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Separate pieces of work noticed while doing this task"}
+	}
+	pathParam := map[string]interface{}{"type": "string", "description": "Path relative to the workspace root"}
+	return []toolDefinition{
+		tool("read_file", "Read a file from the workspace.", map[string]interface{}{
+			"path": pathParam,
+		}, "path"),
+		tool("write_file", "Create or overwrite a file in the workspace.", map[string]interface{}{
+			"path":    pathParam,
+			"content": map[string]interface{}{"type": "string", "description": "Full file content"},
+		}, "path", "content"),
+		tool("search_files", "Search workspace files for a regular expression.", map[string]interface{}{
+			"pattern": map[string]interface{}{"type": "string", "description": "Go regular expression"},
+			"path":    map[string]interface{}{"type": "string", "description": "Optional directory to search"},
+		}, "pattern"),
+		tool("run_validation", "Run the task validation commands and return their output.", map[string]interface{}{}),
+		tool("complete", "Declare the task finished.", completeParams, "status", "summary"),
+	}
+}
+
+func tool(name string, description string, properties map[string]interface{}, required ...string) toolDefinition {
+	parameters := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		parameters["required"] = required
+	}
+	return toolDefinition{
+		Type: "function",
+		Function: toolFunction{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+func (t *workspaceTools) call(ctx context.Context, name string, arguments string) (string, *toolFinish) {
+	var args struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+		Pattern string `json:"pattern"`
+		toolFinish
+	}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return fmt.Sprintf("error: invalid arguments: %v", err), nil
+		}
+	}
+
+	var (
+		output string
+		err    error
+	)
+	switch name {
+	case "read_file":
+		output, err = t.readFile(args.Path)
+	case "write_file":
+		output, err = t.writeFile(args.Path, args.Content)
+	case "search_files":
+		output, err = t.search(args.Pattern, args.Path)
+	case "run_validation":
+		output, err = t.runValidation(ctx)
+	case "complete":
+		switch args.Status {
+		case "success", "failure", "blocked":
+		default:
+			return fmt.Sprintf("error: unknown status %q", args.Status), nil
+		}
+		finish := args.toolFinish
+		return "ok", &finish
+	default:
+		err = fmt.Errorf("unknown tool %q", name)
+	}
+	if err != nil {
+		return "error: " + err.Error(), nil
+	}
+	return truncate(output, maxToolOutput), nil
+}
+
+func (t *workspaceTools) resolve(path string) (string, string, error) {
+	if path == "" {
+		return "", "", fmt.Errorf("path required")
+	}
+	abs := filepath.Clean(filepath.Join(t.root, path))
+	rel, err := filepath.Rel(t.root, abs)
+	if err != nil || escapes(rel) {
+		return "", "", fmt.Errorf("path %s escapes the workspace", path)
+	}
+	rel = filepath.ToSlash(rel)
+	if err := t.check(path, rel); err != nil {
+		return "", "", err
+	}
+
+	// Symlinks inside the workspace may point anywhere, so check where the
+	// path really lands too. For paths that do not exist yet (writes), the
+	// deepest existing parent decides.
+	real, err := realPath(abs)
+	if err != nil {
+		return "", "", err
+	}
+	realRoot, err := realPath(t.root)
+	if err != nil {
+		return "", "", err
+	}
+	realRel, err := filepath.Rel(realRoot, real)
+	if err != nil || escapes(realRel) {
+		return "", "", fmt.Errorf("path %s escapes the workspace through a symlink", path)
+	}
+	if err := t.check(path, filepath.ToSlash(realRel)); err != nil {
+		return "", "", err
+	}
+	return real, rel, nil
+}
+
+// realPath resolves symlinks in path. Missing trailing components are kept
+// as written after resolving the deepest existing parent.
+func realPath(path string) (string, error) {
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				real = filepath.Join(real, missing[i])
+			}
+			return real, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(path))
+		path = parent
+	}
+}
+
+func (t *workspaceTools) check(path string, rel string) error {
+	for _, readOnly := range t.req.ReadOnlyPaths {
+		if filepath.IsAbs(readOnly) {
+			relReadOnly, err := filepath.Rel(t.root, readOnly)
+			if err != nil || escapes(relReadOnly) {
+				continue
+			}
+			readOnly = relReadOnly
+		}
+		if withinPath(rel, readOnly) {
+			return fmt.Errorf("path %s is read-only", path)
+		}
+	}
+	if !t.allowed(rel) {
+		return fmt.Errorf("path %s is outside allowed paths %v", path, t.req.AllowedPaths)
+	}
+	return nil
+}
+
+func (t *workspaceTools) allowed(rel string) bool {
+	if len(t.req.AllowedPaths) == 0 || rel == "." {
+		return true
+	}
+	for _, allowed := range t.req.AllowedPaths {
+		if withinPath(rel, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func escapes(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func withinPath(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(filepath.ToSlash(prefix), "/")
+	if prefix == "" {
+		return false
+	}
+	path = filepath.ToSlash(path)
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func (t *workspaceTools) readFile(path string) (string, error) {
+	abs, _, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxReadBytes))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (t *workspaceTools) writeFile(path string, content string) (string, error) {
+	abs, rel, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	t.changed[rel] = true
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), rel), nil
+}
+
+func (t *workspaceTools) search(pattern string, path string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		path = "."
+	}
+	start, _, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	matches := 0
+	err = filepath.WalkDir(start, func(abs string, entry fs.DirEntry, err error) error {
+		if err != nil || matches >= maxSearchLines {
+			return nil
+		}
+		rel, _ := filepath.Rel(t.root, abs)
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if entry.Name() == ".git" || (rel != "." && !t.allowed(rel) && !t.parentOfAllowed(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		real, _, err := t.resolve(rel)
+		if err != nil {
+			return nil
+		}
+		data, err := os.ReadFile(real)
+		if err != nil || len(data) > maxReadBytes || bytes.IndexByte(data, 0) >= 0 {
+			return nil
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if matches >= maxSearchLines {
+				break
+			}
+			if re.MatchString(line) {
+				fmt.Fprintf(&out, "%s:%d: %s\n", rel, i+1, line)
+				matches++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if matches == 0 {
+		return "no matches", nil
+	}
+	return out.String(), nil
+}
+
+func (t *workspaceTools) parentOfAllowed(rel string) bool {
+	for _, allowed := range t.req.AllowedPaths {
+		if withinPath(allowed, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *workspaceTools) runValidation(ctx context.Context) (string, error) {
+	if len(t.req.Validation.Commands) == 0 {
+		return "no validation commands configured", nil
+	}
+	if t.req.Validation.Run == nil {
+		return "", fmt.Errorf("validation is not available to this agent")
+	}
+	t.commands = append(t.commands, t.req.Validation.Commands...)
+	return t.req.Validation.Run(ctx)
+}
+
+func (t *workspaceTools) filesChanged() []string {
+	files := make([]string, 0, len(t.changed))
+	for file := range t.changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
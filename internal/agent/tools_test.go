@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveRejectsSymlinkEscapes(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "src", "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"src/out":  outside,
+		"src/docs": filepath.Join(root, "docs"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	tools := newWorkspaceTools(Request{WorkspacePath: root, AllowedPaths: []string{"src"}})
+	for _, path := range []string{"src/out/secret.txt", "src/out/new.txt", "src/docs/readme.md", "src/out/deep/new.txt"} {
+		if _, _, err := tools.resolve(path); err == nil {
+			t.Errorf("resolve(%q) succeeded, want it rejected", path)
+		}
+	}
+	if _, err := tools.writeFile("src/out/new.txt", "x"); err == nil {
+		t.Error("write through symlink succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Error("file written outside the workspace")
+	}
+	if _, _, err := tools.resolve("src/pkg/new.py"); err != nil {
+		t.Errorf("resolve of a new allowed file: %v", err)
+	}
+	if output, err := tools.search("secret", "src"); err != nil || output != "no matches" {
+		t.Errorf("search followed a symlink out of the workspace: %q, %v", output, err)
+	}
+}
+
+func TestOpenAIRequiresComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"message": map[string]interface{}{"role": "assistant", "content": "All fixed!"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	adapter := NewOpenAI(OpenAIConfig{BaseURL: server.URL, Model: "test"})
+	result, err := adapter.Invoke(context.Background(), Request{SchemaVersion: ProtocolV2, WorkspacePath: t.TempDir()})
+	if err == nil || result.Status == "success" || result.ExitCode == 0 {
+		t.Fatalf("reply without a complete call = %s, exit %d, %v; want an error", result.Status, result.ExitCode, err)
+	}
+	if !strings.Contains(result.Summary, "complete") {
+		t.Fatalf("summary = %q", result.Summary)
+	}
+}
+
+func TestRunValidationToolUsesRequestRunner(t *testing.T) {
+	ran := 0
+	run := func(ctx context.Context) (string, error) {
+		ran++
+		return "$ pytest (exit 0)\n1 passed\n", nil
+	}
+	for _, tc := range []struct {
+		name       string
+		validation Validation
+		want       string
+		commands   []string
+	}{
+		{"no commands", Validation{Run: run}, "no validation commands configured", nil},
+		{"no runner", Validation{Commands: []string{"pytest"}}, "error: validation is not available", nil},
+		{"runner", Validation{Commands: []string{"pytest"}, Run: run}, "1 passed", []string{"pytest"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ran = 0
+			tools := newWorkspaceTools(Request{WorkspacePath: t.TempDir(), Validation: tc.validation})
+			output, _ := tools.call(context.Background(), "run_validation", "")
+			if !strings.Contains(output, tc.want) {
+				t.Fatalf("output = %q, want %q", output, tc.want)
+			}
+			if ran != len(tc.commands) || len(tools.commands) != len(tc.commands) {
+				t.Fatalf("ran %d times, recorded %v; want %v", ran, tools.commands, tc.commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"atqos/internal/agent"
+	"atqos/internal/config"
+)
+
+func selectAgents(cfg config.Config) (*agent.Router, error) {
+	if len(cfg.Agents) == 0 {
+		adapter := selectAgentAdapter()
+		if mode := os.Getenv("ATQOS_AGENT_REPLAY"); mode != "" {
+			replay, err := agent.NewReplay(mode, os.Getenv("ATQOS_AGENT_CASSETTES"), adapter)
+			if err != nil {
+				return nil, err
+			}
+			adapter = replay
+		}
+		return agent.Single(adapter), nil
+	}
+
+	names := make([]string, 0, len(cfg.Agents))
+	for name := range cfg.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	agents := make(map[string]agent.Agent, len(cfg.Agents))
+	for _, name := range names {
+		spec := cfg.Agents[name]
+		adapter, err := buildAgent(name, spec)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Replay != nil {
+			replay, err := agent.NewReplay(spec.Replay.Mode, spec.Replay.CassetteDir, adapter)
+			if err != nil {
+				return nil, fmt.Errorf("agent %s: %w", name, err)
+			}
+			adapter = replay
+		}
+		agents[name] = agent.NewManaged(name, adapter, spec.Concurrency, time.Duration(spec.TimeoutSeconds)*time.Second)
+	}
+
+	routes := make([]agent.Route, 0, len(cfg.AgentRoutes))
+	for _, route := range cfg.AgentRoutes {
+		routes = append(routes, agent.Route{
+			TaskType:      route.TaskType,
+			Tool:          route.Tool,
+			Severity:      route.Severity,
+			Agent:         route.Agent,
+			Fallback:      route.Fallback,
+			FallbackAfter: route.FallbackAfter,
+		})
+	}
+
+	return agent.NewRouter(agents, routes, cfg.DefaultAgent)
+}
+
+func buildAgent(name string, spec config.AgentConfig) (agent.Agent, error) {
+	if err := agent.CheckVersions(spec.Protocols); err != nil {
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+	switch spec.Type {
+	case "", "command":
+		if len(spec.Command) == 0 {
+			return nil, fmt.Errorf("agent %s: command required", name)
+		}
+		return agent.NewCommandAdapter(name, spec.Command).WithEnv(spec.Env).WithVersions(spec.Protocols), nil
+	case "codex":
+		return agent.NewCodexCLI(spec.Command).WithEnv(spec.Env).WithVersions(spec.Protocols), nil
+	case "openai":
+		apiKeyEnv := spec.APIKeyEnv
+		if apiKeyEnv == "" {
+			apiKeyEnv = "OPENAI_API_KEY"
+		}
+		return agent.NewOpenAI(agent.OpenAIConfig{
+			BaseURL:           spec.BaseURL,
+			APIKey:            os.Getenv(apiKeyEnv),
+			Model:             spec.Model,
+			MaxTurns:          spec.MaxTurns,
+			InputCostPerMTok:  spec.InputCost,
+			OutputCostPerMTok: spec.OutputCost,
+		}), nil
+	case "local":
+		return agent.NewLocal(), nil
+	default:
+		return nil, fmt.Errorf("agent %s: unknown type %q", name, spec.Type)
+	}
+}
+
+func selectAgentAdapter() agent.Agent {
+	if openAIConfig, ok := agent.OpenAIConfigFromEnv(); ok {
+		return agent.NewOpenAI(openAIConfig)
+	}
+	codexCommand := strings.Fields(os.Getenv("ATQOS_CODEX_CMD"))
+	if len(codexCommand) > 0 {
+		return agent.NewCodexCLI(codexCommand)
+	}
+	command := strings.Fields(os.Getenv("ATQOS_AGENT_CMD"))
+	if len(command) == 0 {
+		return agent.NewLocal()
+	}
+	return agent.NewCommandAdapter("cli", command)
+}
@@ -0,0 +1,487 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"atqos/internal/artifacts"
+	"atqos/internal/bundle"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/store"
+)
+
+const (
+	bundleRun       = "run.json"
+	bundleFindings  = "findings.json"
+	bundleTasks     = "tasks.json"
+	bundleAttempts  = "attempts.json"
+	bundleArtifacts = "artifacts.json"
+	bundleEvents    = "events.jsonl"
+)
+
+type BundleExportCommand struct {
+	DBPath string
+	RunID  string
+	Out    string
+}
+
+type BundleExportResult struct {
+	Path    string
+	Files   int
+	Missing []string
+}
+
+type BundleImportCommand struct {
+	DBPath      string
+	ArtifactDir string
+	Path        string
+}
+
+type BundleImportResult struct {
+	RunID     string
+	Findings  int
+	Tasks     int
+	Attempts  int
+	Artifacts int
+	Files     int
+	Dir       string
+}
+
+func (c BundleExportCommand) Run(ctx context.Context) (BundleExportResult, error) {
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return BundleExportResult{}, err
+	}
+	defer storeDB.Close()
+
+	run, err := storeDB.GetRun(ctx, c.RunID)
+	if err != nil {
+		return BundleExportResult{}, fmt.Errorf("load run %s: %w", c.RunID, err)
+	}
+	findings, err := storeDB.ListFindings(ctx, c.RunID)
+	if err != nil {
+		return BundleExportResult{}, err
+	}
+	tasks, err := storeDB.ListTasks(ctx, c.RunID)
+	if err != nil {
+		return BundleExportResult{}, err
+	}
+	attempts := make([]core.AttemptRecord, 0)
+	for _, task := range tasks {
+		taskAttempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			return BundleExportResult{}, err
+		}
+		for _, attempt := range taskAttempts {
+			attempt.TaskID = task.ID
+			attempts = append(attempts, attempt)
+		}
+	}
+	records, err := storeDB.ListArtifacts(ctx, c.RunID)
+	if err != nil {
+		return BundleExportResult{}, err
+	}
+
+	cfg := config.Default()
+	_ = json.Unmarshal([]byte(run.Config), &cfg)
+	var cas *artifacts.CAS
+	if cfg.Artifacts.CAS && cfg.Artifacts.CASDir != "" {
+		if cas, err = artifacts.NewCAS(cfg.Artifacts.CASDir, cfg.Artifacts.Compression); err != nil {
+			return BundleExportResult{}, err
+		}
+	}
+
+	out := c.Out
+	if out == "" {
+		out = filepath.Join("exports", c.RunID+".bundle.tar.gz")
+	}
+	root := runArtifactRoot(records, c.RunID)
+	w, err := bundle.Create(out, c.RunID, root)
+	if err != nil {
+		return BundleExportResult{}, err
+	}
+	result := BundleExportResult{Path: out}
+	if err := writeBundle(w, cas, root, run, findings, tasks, attempts, records, &result); err != nil {
+		w.Abort()
+		return BundleExportResult{}, err
+	}
+	if err := w.Close(); err != nil {
+		return BundleExportResult{}, fmt.Errorf("write bundle: %w", err)
+	}
+	return result, nil
+}
+
+func writeBundle(w *bundle.Writer, cas *artifacts.CAS, root string, run core.RunRecord, findings []core.FindingRecord, tasks []core.TaskRecord, attempts []core.AttemptRecord, records []core.ArtifactRecord, result *BundleExportResult) error {
+	for _, table := range []struct {
+		name string
+		rows interface{}
+	}{
+		{bundleRun, run},
+		{bundleFindings, findings},
+		{bundleTasks, tasks},
+		{bundleAttempts, attempts},
+		{bundleArtifacts, records},
+	} {
+		if err := w.AddJSON(table.name, table.rows); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		name := bundleFileName(root, record)
+		if w.Has(name) {
+			continue
+		}
+		data, err := artifacts.ReadArtifact(cas, record)
+		if err != nil {
+			if errors.Is(err, artifacts.ErrCorrupt) {
+				return fmt.Errorf("artifact %s: %w", record.Path, err)
+			}
+			w.AddMissing(record.Path)
+			result.Missing = append(result.Missing, record.Path)
+			continue
+		}
+		if err := w.AddFile(name, bytes.NewReader(data)); err != nil {
+			return err
+		}
+		result.Files++
+	}
+
+	// Validation command logs are not artifact records; they are found
+	// through the attempt summaries that point at them.
+	for _, attempt := range attempts {
+		for _, log := range validationLogs(attempt.SummaryJSON) {
+			rel, err := filepath.Rel(root, log.path)
+			if root == "" || err != nil || strings.HasPrefix(rel, "..") || w.Has(filepath.ToSlash(rel)) {
+				continue
+			}
+			f, err := os.Open(log.path)
+			if err != nil {
+				w.AddMissing(log.path)
+				result.Missing = append(result.Missing, log.path)
+				continue
+			}
+			err = w.AddFile(filepath.ToSlash(rel), f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			result.Files++
+		}
+	}
+
+	if root != "" {
+		if f, err := os.Open(filepath.Join(root, bundleEvents)); err == nil {
+			defer f.Close()
+			if err := w.AddFile(bundleEvents, f); err != nil {
+				return err
+			}
+			result.Files++
+		}
+	}
+	return nil
+}
+
+func (c BundleImportCommand) Run(ctx context.Context) (BundleImportResult, error) {
+	tmp, err := os.MkdirTemp("", "atqos-bundle-")
+	if err != nil {
+		return BundleImportResult{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	b, err := bundle.Extract(c.Path, tmp)
+	if err != nil {
+		return BundleImportResult{}, err
+	}
+	var (
+		run      core.RunRecord
+		findings []core.FindingRecord
+		tasks    []core.TaskRecord
+		attempts []core.AttemptRecord
+		records  []core.ArtifactRecord
+	)
+	for name, rows := range map[string]interface{}{
+		bundleRun:       &run,
+		bundleFindings:  &findings,
+		bundleTasks:     &tasks,
+		bundleAttempts:  &attempts,
+		bundleArtifacts: &records,
+	} {
+		if err := b.ReadJSON(name, rows); err != nil {
+			return BundleImportResult{}, fmt.Errorf("%w: read %s: %v", bundle.ErrInvalid, name, err)
+		}
+	}
+	if run.RunID == "" || run.RunID != b.Manifest.RunID {
+		return BundleImportResult{}, fmt.Errorf("%w: run id does not match manifest", bundle.ErrInvalid)
+	}
+
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return BundleImportResult{}, err
+	}
+	defer storeDB.Close()
+	if err := storeDB.Init(ctx); err != nil {
+		return BundleImportResult{}, err
+	}
+	if _, err := storeDB.GetRun(ctx, run.RunID); err == nil {
+		return BundleImportResult{}, fmt.Errorf("run %s already exists", run.RunID)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return BundleImportResult{}, err
+	}
+
+	result := BundleImportResult{RunID: run.RunID, Dir: filepath.Join(c.ArtifactDir, run.RunID)}
+	if _, err := os.Stat(result.Dir); err == nil {
+		return BundleImportResult{}, fmt.Errorf("artifact directory %s already exists", result.Dir)
+	}
+	for _, file := range b.Files() {
+		if err := copyFile(b.FilePath(file.Name), filepath.Join(result.Dir, filepath.FromSlash(file.Name))); err != nil {
+			_ = os.RemoveAll(result.Dir)
+			return BundleImportResult{}, fmt.Errorf("restore %s: %w", file.Name, err)
+		}
+		result.Files++
+	}
+
+	run.Config = importedConfig(run.Config, b.Manifest.ArtifactRoot, result.Dir)
+
+	// The store has no multi-row transactions, so load a scratch copy into
+	// memory first: bad rows then fail before the target store is touched,
+	// and only store errors can interrupt the real load.
+	scratch := newBundleImport(store.NewMemory(), b.Manifest.ArtifactRoot, result.Dir)
+	if err := scratch.load(ctx, run, append([]core.FindingRecord(nil), findings...), tasks, attempts, records); err != nil {
+		_ = os.RemoveAll(result.Dir)
+		return BundleImportResult{}, err
+	}
+	imp := newBundleImport(storeDB, b.Manifest.ArtifactRoot, result.Dir)
+	if err := imp.load(ctx, run, findings, tasks, attempts, records); err != nil {
+		if cleanupErr := storeDB.DeleteRun(context.Background(), run.RunID); cleanupErr != nil {
+			err = fmt.Errorf("%w; removing the partial run %s also failed: %v", err, run.RunID, cleanupErr)
+		}
+		_ = os.RemoveAll(result.Dir)
+		return BundleImportResult{}, err
+	}
+	_, _ = indexOutputs(ctx, storeDB, run.RunID)
+	result.Findings = len(findings)
+	result.Tasks = len(tasks)
+	result.Attempts = len(attempts)
+	result.Artifacts = len(records)
+	return result, nil
+}
+
+type bundleImport struct {
+	store    store.Store
+	oldRoot  string
+	newRoot  string
+	tasks    map[int64]int64
+	attempts map[int64]int64
+}
+
+func newBundleImport(s store.Store, oldRoot string, newRoot string) *bundleImport {
+	return &bundleImport{
+		store:    s,
+		oldRoot:  oldRoot,
+		newRoot:  newRoot,
+		tasks:    make(map[int64]int64),
+		attempts: make(map[int64]int64),
+	}
+}
+
+// importedConfig points an imported run's config at its new home. Artifacts
+// are restored as plain files under newRoot, so the exporter's artifact
+// store no longer applies, and paths under the old run directory (such as
+// file event sinks) are moved to the new one.
+func importedConfig(raw string, oldRoot string, newRoot string) string {
+	var cfg map[string]interface{}
+	if raw == "" || json.Unmarshal([]byte(raw), &cfg) != nil {
+		return raw
+	}
+	if settings, ok := cfg["artifacts"].(map[string]interface{}); ok {
+		settings["cas"] = false
+		delete(settings, "cas_dir")
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return raw
+	}
+	if oldRoot == "" || newRoot == "" {
+		return string(data)
+	}
+	return replaceJSONPath(string(data), oldRoot, newRoot)
+}
+
+// replaceJSONPath replaces oldRoot with newRoot inside the strings of a JSON
+// document, matching them as they are escaped there.
+func replaceJSONPath(raw string, oldRoot string, newRoot string) string {
+	oldJSON, _ := json.Marshal(oldRoot)
+	newJSON, _ := json.Marshal(newRoot)
+	return strings.ReplaceAll(raw, strings.Trim(string(oldJSON), `"`), strings.Trim(string(newJSON), `"`))
+}
+
+// Task and attempt IDs are assigned by the target store, so references to
+// them in depends_on and extra JSON are rewritten as rows are inserted.
+func (imp *bundleImport) load(ctx context.Context, run core.RunRecord, findings []core.FindingRecord, tasks []core.TaskRecord, attempts []core.AttemptRecord, records []core.ArtifactRecord) error {
+	if err := imp.store.CreateRun(ctx, run); err != nil {
+		return fmt.Errorf("create run: %w", err)
+	}
+	for i := range findings {
+		findings[i].RawRef = imp.path(findings[i].RawRef)
+	}
+	if err := imp.store.InsertFindings(ctx, findings); err != nil {
+		return fmt.Errorf("insert findings: %w", err)
+	}
+
+	byTask := make(map[int64][]core.AttemptRecord)
+	for _, attempt := range attempts {
+		byTask[attempt.TaskID] = append(byTask[attempt.TaskID], attempt)
+	}
+	for _, task := range tasks {
+		oldID := task.ID
+		task.DependsOnJSON = remapIDList(task.DependsOnJSON, imp.tasks)
+		extraJSON, complete := remapExtra(task.ExtraJSON, imp.tasks, imp.attempts)
+		task.ExtraJSON = extraJSON
+		inserted := []core.TaskRecord{task}
+		if err := imp.store.InsertTasks(ctx, inserted); err != nil {
+			return fmt.Errorf("insert task %d: %w", oldID, err)
+		}
+		newID := inserted[0].ID
+		imp.tasks[oldID] = newID
+
+		for _, attempt := range byTask[oldID] {
+			oldAttempt := attempt.ID
+			attempt.TaskID = newID
+			attempt.ArtifactsJSON = imp.jsonPath(attempt.ArtifactsJSON)
+			attempt.SummaryJSON = imp.jsonPath(attempt.SummaryJSON)
+			id, err := imp.store.CreateAttempt(ctx, attempt)
+			if err != nil {
+				return fmt.Errorf("insert attempt %d: %w", oldAttempt, err)
+			}
+			imp.attempts[oldAttempt] = id
+			if attempt.FinishedAt.IsZero() {
+				continue
+			}
+			attempt.ID = id
+			if err := imp.store.FinishAttempt(ctx, attempt); err != nil {
+				return fmt.Errorf("finish attempt %d: %w", oldAttempt, err)
+			}
+		}
+		if !complete {
+			extraJSON, _ = remapExtra(task.ExtraJSON, nil, imp.attempts)
+			if err := imp.store.UpdateTaskStatus(ctx, newID, task.Status, extraJSON); err != nil {
+				return fmt.Errorf("update task %d: %w", oldID, err)
+			}
+		}
+	}
+
+	for _, record := range records {
+		if imp.newRoot != "" {
+			name := bundleFileName(imp.oldRoot, record)
+			if _, err := os.Stat(filepath.Join(imp.newRoot, filepath.FromSlash(name))); err == nil {
+				record.Path = filepath.Join(imp.newRoot, filepath.FromSlash(name))
+			}
+		}
+		if err := imp.store.AddArtifact(ctx, record); err != nil {
+			return fmt.Errorf("insert artifact %s: %w", record.Path, err)
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImport) path(value string) string {
+	if imp.oldRoot == "" || value == "" {
+		return value
+	}
+	return strings.ReplaceAll(value, imp.oldRoot, imp.newRoot)
+}
+
+func (imp *bundleImport) jsonPath(raw string) string {
+	if imp.oldRoot == "" || raw == "" {
+		return raw
+	}
+	return replaceJSONPath(raw, imp.oldRoot, imp.newRoot)
+}
+
+func remapIDList(raw string, ids map[int64]int64) string {
+	var list []int64
+	if raw == "" || json.Unmarshal([]byte(raw), &list) != nil {
+		return raw
+	}
+	for i, id := range list {
+		if mapped, ok := ids[id]; ok {
+			list[i] = mapped
+		}
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func remapExtra(raw string, tasks map[int64]int64, attempts map[int64]int64) (string, bool) {
+	var extra map[string]interface{}
+	if raw == "" || json.Unmarshal([]byte(raw), &extra) != nil {
+		return raw, true
+	}
+	complete := true
+	for key, ids := range map[string]map[int64]int64{"parent_task_id": tasks, "attempt_id": attempts} {
+		value, ok := extra[key].(float64)
+		if !ok || ids == nil {
+			continue
+		}
+		if mapped, ok := ids[int64(value)]; ok {
+			extra[key] = mapped
+		} else {
+			complete = false
+		}
+	}
+	data, _ := json.Marshal(extra)
+	return string(data), complete
+}
+
+func runArtifactRoot(records []core.ArtifactRecord, runID string) string {
+	marker := string(filepath.Separator) + runID + string(filepath.Separator)
+	for _, record := range records {
+		if i := strings.Index(record.Path, marker); i >= 0 {
+			return record.Path[:i+len(marker)-1]
+		}
+	}
+	return ""
+}
+
+func bundleFileName(root string, record core.ArtifactRecord) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, record.Path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	sum := record.SHA256
+	if len(sum) > 12 {
+		sum = sum[:12]
+	}
+	return path.Join("external", sum+"-"+filepath.Base(record.Path))
+}
+
+func copyFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
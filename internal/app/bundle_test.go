@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atqos/internal/store"
+)
+
+func TestRemapIDList(t *testing.T) {
+	ids := map[int64]int64{1: 11, 2: 12}
+	for raw, want := range map[string]string{
+		"[1,2,3]":  "[11,12,3]",
+		"[]":       "[]",
+		"":         "",
+		"not json": "not json",
+	} {
+		if got := remapIDList(raw, ids); got != want {
+			t.Errorf("remapIDList(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestRemapExtra(t *testing.T) {
+	tasks := map[int64]int64{3: 30}
+	attempts := map[int64]int64{7: 70}
+
+	got, complete := remapExtra(`{"parent_task_id":3,"attempt_id":7,"note":"x"}`, tasks, attempts)
+	if !complete {
+		t.Fatal("all references were mapped but remapExtra reported incomplete")
+	}
+	var extra map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &extra); err != nil {
+		t.Fatal(err)
+	}
+	if extra["parent_task_id"] != float64(30) || extra["attempt_id"] != float64(70) || extra["note"] != "x" {
+		t.Fatalf("remapExtra = %s", got)
+	}
+
+	// Attempts are inserted after their task, so an attempt reference is
+	// unresolved on the first pass and fixed by a second one.
+	got, complete = remapExtra(`{"attempt_id":8}`, tasks, map[int64]int64{})
+	if complete || got != `{"attempt_id":8}` {
+		t.Fatalf("unmapped attempt = %s, complete %v", got, complete)
+	}
+	if got, complete = remapExtra(got, nil, map[int64]int64{8: 80}); !complete || got != `{"attempt_id":80}` {
+		t.Fatalf("second pass = %s, complete %v", got, complete)
+	}
+
+	if got, complete := remapExtra("", tasks, attempts); got != "" || !complete {
+		t.Fatalf("empty extra = %q, %v", got, complete)
+	}
+}
+
+func TestImportedConfig(t *testing.T) {
+	raw := `{"artifacts":{"cas":true,"cas_dir":"/old/cas","compression":"zstd"},"events":{"files":[{"path":"/old/art/run-1/debug.jsonl"}]},"max_workers":2}`
+	got := importedConfig(raw, "/old/art/run-1", "/new/art/run-1")
+
+	var cfg struct {
+		Artifacts map[string]interface{} `json:"artifacts"`
+		Events    struct {
+			Files []struct {
+				Path string `json:"path"`
+			} `json:"files"`
+		} `json:"events"`
+		MaxWorkers int `json:"max_workers"`
+	}
+	if err := json.Unmarshal([]byte(got), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Artifacts["cas"] != false || cfg.Artifacts["cas_dir"] != nil || cfg.Artifacts["compression"] != "zstd" {
+		t.Fatalf("artifacts = %v", cfg.Artifacts)
+	}
+	if len(cfg.Events.Files) != 1 || cfg.Events.Files[0].Path != "/new/art/run-1/debug.jsonl" {
+		t.Fatalf("file sinks = %+v", cfg.Events.Files)
+	}
+	if cfg.MaxWorkers != 2 {
+		t.Fatalf("unrelated settings changed: %s", got)
+	}
+}
+
+func TestBundleRoundTripMovesSummaryPaths(t *testing.T) {
+	run := runFixture(t, newFixtureRepo(t), nil)
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "run.bundle.tar.gz")
+	if _, err := (BundleExportCommand{DBPath: run.db, RunID: run.result.RunID, Out: out}).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	db := filepath.Join(t.TempDir(), "imported.db")
+	imported, err := BundleImportCommand{DBPath: db, ArtifactDir: t.TempDir(), Path: out}.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The exporting run's files are gone, so only imported paths resolve.
+	if err := os.RemoveAll(run.artifactDir); err != nil {
+		t.Fatal(err)
+	}
+
+	storeDB, err := store.Open(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storeDB.Close()
+	tasks, err := storeDB.ListTasks(ctx, imported.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs []validationLog
+	for _, task := range tasks {
+		attempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, attempt := range attempts {
+			logs = append(logs, validationLogs(attempt.SummaryJSON)...)
+		}
+	}
+	if len(logs) == 0 {
+		t.Fatal("imported attempts have no validation logs")
+	}
+	for _, log := range logs {
+		if !strings.HasPrefix(log.path, imported.Dir+string(filepath.Separator)) {
+			t.Errorf("summary path %s is outside %s", log.path, imported.Dir)
+		}
+		if _, err := os.Stat(log.path); err != nil {
+			t.Errorf("summary path does not resolve: %v", err)
+		}
+	}
+}
@@ -9,11 +9,10 @@ import (
 	"strings"
 	"time"
 
-	"atqos/internal/agent"
+	"atqos/internal/artifacts"
 	"atqos/internal/config"
 	"atqos/internal/core"
 	"atqos/internal/engine"
-	"atqos/internal/eventlog"
 	"atqos/internal/git"
 	"atqos/internal/plugins/coverage"
 	"atqos/internal/plugins/pytest"
@@ -41,6 +40,16 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		return Result{}, err
 	}
 
+	var cas *artifacts.CAS
+	if cfg.Artifacts.CAS {
+		if cfg.Artifacts.CASDir == "" {
+			cfg.Artifacts.CASDir = filepath.Join(c.ArtifactDir, "cas")
+		}
+		if cas, err = artifacts.NewCAS(cfg.Artifacts.CASDir, cfg.Artifacts.Compression); err != nil {
+			return Result{}, err
+		}
+	}
+
 	runID, err := core.NewRunID()
 	if err != nil {
 		return Result{}, err
@@ -51,14 +60,20 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		return Result{}, fmt.Errorf("create artifact root: %w", err)
 	}
 
-	logPath := filepath.Join(artifactRoot, "events.jsonl")
-	logger, err := eventlog.New(logPath)
+	logger, err := openEventSinks(cfg.Events, artifactRoot)
 	if err != nil {
 		return Result{}, err
 	}
 	defer logger.Close()
 
-	storeDB, err := store.NewSQLite(c.DBPath)
+	dsn := c.DBPath
+	if cfg.Store.DSN != "" {
+		dsn = cfg.Store.DSN
+	}
+	if cas != nil {
+		cas.WithOwner(casOwner(dsn))
+	}
+	storeDB, err := store.Open(dsn, cfg.Conflicts)
 	if err != nil {
 		return Result{}, err
 	}
@@ -67,18 +82,32 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	if err := storeDB.Init(ctx); err != nil {
 		return Result{}, err
 	}
+	storeDB = engine.Journal(storeDB, logger, runID)
 
 	configJSON, err := json.Marshal(cfg)
 	if err != nil {
 		return Result{}, fmt.Errorf("marshal config: %w", err)
 	}
 
+	var base git.Base
+	if git.IsRepo(ctx, c.RepoPath) {
+		base, err = git.ResolveBase(ctx, c.RepoPath, cfg.IncludeDirty, repoRelative(c.RepoPath, c.ArtifactDir, c.DBPath)...)
+		if err != nil {
+			return Result{}, err
+		}
+	} else if cfg.GitStrategy == "worktree" {
+		return Result{}, fmt.Errorf("git_strategy worktree requires a git repository; %s is not one, use git_strategy copy", c.RepoPath)
+	}
+
 	run := core.RunRecord{
-		RunID:     runID,
-		RepoPath:  c.RepoPath,
-		StartedAt: time.Now(),
-		Status:    core.RunStatusRunning,
-		Config:    string(configJSON),
+		RunID:          runID,
+		RepoPath:       c.RepoPath,
+		StartedAt:      time.Now(),
+		Status:         core.RunStatusRunning,
+		Config:         string(configJSON),
+		BaseCommit:     base.Commit,
+		DirtyDiff:      string(base.DirtyDiff.Data),
+		SnapshotCommit: base.Snapshot,
 	}
 	if err := storeDB.CreateRun(ctx, run); err != nil {
 		return Result{}, err
@@ -86,13 +115,37 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	if err := logger.Emit(core.Event{
 		RunID:     runID,
 		Level:     "info",
-		EventType: "run_started",
-		Payload: map[string]string{
-			"repo_path": c.RepoPath,
+		EventType: core.EventRunStarted,
+		Payload: core.RunStartedPayload{
+			RepoPath:       c.RepoPath,
+			BaseCommit:     base.Commit,
+			SnapshotCommit: base.Snapshot,
 		},
 	}); err != nil {
 		return Result{}, err
 	}
+	if base.Dirty() {
+		diffPath := filepath.Join(artifactRoot, "base.diff")
+		if err := os.WriteFile(diffPath, base.DirtyDiff.Data, 0o644); err != nil {
+			return Result{}, err
+		}
+		if err := storeDB.AddArtifact(ctx, newArtifact(runID, "core", "diff", diffPath)); err != nil {
+			return Result{}, err
+		}
+		level := "info"
+		if base.Snapshot == "" {
+			level = "warn"
+		}
+		_ = logger.Emit(core.Event{
+			RunID:     runID,
+			Level:     level,
+			EventType: core.EventDirtyWorktree,
+			Payload: core.DirtyWorktreePayload{
+				Files:    base.DirtyDiff.Files,
+				Included: base.Snapshot != "",
+			},
+		})
+	}
 
 	runnerRegistry := runner.NewRegistry(artifactRoot)
 	adapter := repo.NewAdapter()
@@ -115,6 +168,7 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	}
 
 	summary := core.Summary{}
+	baselines := make(map[string]engine.Baseline)
 	for _, plugin := range plugins {
 		if !cfg.PluginEnabled(plugin.ID()) {
 			continue
@@ -123,7 +177,7 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		if err := logger.Emit(core.Event{
 			RunID:     runID,
 			Level:     "info",
-			EventType: "collect_started",
+			EventType: core.EventCollectStarted,
 			Tool:      plugin.ID(),
 		}); err != nil {
 			return Result{}, err
@@ -136,10 +190,10 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		if err := logger.Emit(core.Event{
 			RunID:     runID,
 			Level:     "info",
-			EventType: "collect_finished",
+			EventType: core.EventCollectFinished,
 			Tool:      plugin.ID(),
-			Payload: map[string]int{
-				"artifact_count": len(artifacts.Items),
+			Payload: core.CollectFinishedPayload{
+				ArtifactCount: len(artifacts.Items),
 			},
 		}); err != nil {
 			return Result{}, err
@@ -158,10 +212,10 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		if err := logger.Emit(core.Event{
 			RunID:     runID,
 			Level:     "info",
-			EventType: "normalize_finished",
+			EventType: core.EventNormalizeFinished,
 			Tool:      plugin.ID(),
-			Payload: map[string]int{
-				"finding_count": len(findings),
+			Payload: core.NormalizeFinishedPayload{
+				FindingCount: len(findings),
 			},
 		}); err != nil {
 			return Result{}, err
@@ -170,6 +224,7 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		if err := storeDB.InsertFindings(ctx, findings); err != nil {
 			return finalize(storeDB, logger, runID, summary, err)
 		}
+		baselines[plugin.ID()] = engine.Baseline{Artifacts: artifacts, Findings: findings}
 
 		tasks, err := plugin.Plan(ctx, runCtx, findings)
 		if err != nil {
@@ -178,10 +233,10 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		if err := logger.Emit(core.Event{
 			RunID:     runID,
 			Level:     "info",
-			EventType: "plan_finished",
+			EventType: core.EventPlanFinished,
 			Tool:      plugin.ID(),
-			Payload: map[string]int{
-				"task_count": len(tasks),
+			Payload: core.PlanFinishedPayload{
+				TaskCount: len(tasks),
 			},
 		}); err != nil {
 			return Result{}, err
@@ -206,14 +261,39 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 		summary.Add(findings, tasks)
 	}
 
-	agentAdapter := selectAgentAdapter()
-	gitStrategy := selectGitStrategy(cfg.GitStrategy, artifactRoot)
+	agents, err := selectAgents(cfg)
+	if err != nil {
+		return finalize(storeDB, logger, runID, summary, err)
+	}
+	gitStrategy, err := selectGitStrategy(cfg, runID, c.RepoPath, artifactRoot, base, repoRelative(c.RepoPath, c.ArtifactDir, c.DBPath))
+	if err != nil {
+		return finalize(storeDB, logger, runID, summary, err)
+	}
+	if pool, ok := gitStrategy.(*git.WorktreeStrategy); ok {
+		report, err := git.PruneOrphans(ctx, c.RepoPath, worktreeOwnerActive(ctx, storeDB))
+		if err != nil {
+			return finalize(storeDB, logger, runID, summary, err)
+		}
+		if len(report.Worktrees) > 0 || len(report.Branches) > 0 {
+			_ = logger.Emit(core.Event{
+				RunID:     runID,
+				Level:     "info",
+				EventType: core.EventWorktreesPruned,
+				Payload: core.WorktreesPrunedPayload{
+					Worktrees: report.Worktrees,
+					Branches:  report.Branches,
+				},
+			})
+		}
+		defer pool.Close(context.Background())
+	}
 	executor := engine.Executor{
 		Store:       storeDB,
 		RunContext:  runCtx,
-		Agent:       agentAdapter,
+		Agents:      agents,
 		GitStrategy: gitStrategy,
 		Plugins:     plugins,
+		Baselines:   baselines,
 	}
 	if err := executor.Run(ctx); err != nil {
 		return finalize(storeDB, logger, runID, summary, err)
@@ -232,6 +312,8 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	if err != nil {
 		return Result{}, err
 	}
+	usage := executor.Usage()
+	report.Usage = &usage
 	reportPath := filepath.Join(artifactRoot, "summary.json")
 	if err := writeJSON(reportPath, report); err != nil {
 		return Result{}, err
@@ -239,13 +321,17 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	if err := storeDB.AddArtifact(ctx, newArtifact(runID, "core", "summary", reportPath)); err != nil {
 		return Result{}, err
 	}
+	indexRunOutputs(ctx, storeDB, logger, runID)
+	if cas != nil {
+		ingestArtifacts(ctx, cas, storeDB, logger, runID)
+	}
 
 	if err := logger.Emit(core.Event{
 		RunID:     runID,
 		Level:     "info",
-		EventType: "run_finished",
-		Payload: map[string]string{
-			"status": core.RunStatusSucceeded,
+		EventType: core.EventRunFinished,
+		Payload: core.RunFinishedPayload{
+			Status: core.RunStatusSucceeded,
 		},
 	}); err != nil {
 		return Result{}, err
@@ -258,7 +344,7 @@ func (c Command) Run(ctx context.Context) (Result, error) {
 	}, nil
 }
 
-func finalize(storeDB *store.SQLiteStore, logger core.EventLogger, runID string, summary core.Summary, runErr error) (Result, error) {
+func finalize(storeDB store.Store, logger core.EventLogger, runID string, summary core.Summary, runErr error) (Result, error) {
 	summaryJSON, err := json.Marshal(summary)
 	if err != nil {
 		return Result{}, err
@@ -267,13 +353,14 @@ func finalize(storeDB *store.SQLiteStore, logger core.EventLogger, runID string,
 	if updateErr := storeDB.UpdateRunStatus(context.Background(), runID, core.RunStatusFailed, string(summaryJSON)); updateErr != nil {
 		return Result{}, updateErr
 	}
+	indexRunOutputs(context.Background(), storeDB, logger, runID)
 	if logger != nil {
 		_ = logger.Emit(core.Event{
 			RunID:     runID,
 			Level:     "error",
-			EventType: "run_failed",
-			Payload: map[string]string{
-				"error": runErr.Error(),
+			EventType: core.EventRunFailed,
+			Payload: core.ErrorPayload{
+				Error: runErr.Error(),
 			},
 		})
 	}
@@ -282,16 +369,17 @@ func finalize(storeDB *store.SQLiteStore, logger core.EventLogger, runID string,
 }
 
 type runReport struct {
-	RunID     string       `json:"run_id"`
-	Status    string       `json:"status"`
-	Findings  int          `json:"findings"`
-	Tasks     int          `json:"tasks"`
-	StartedAt string       `json:"started_at"`
-	Finished  string       `json:"finished_at"`
-	Summary   core.Summary `json:"summary"`
+	RunID     string              `json:"run_id"`
+	Status    string              `json:"status"`
+	Findings  int                 `json:"findings"`
+	Tasks     int                 `json:"tasks"`
+	StartedAt string              `json:"started_at"`
+	Finished  string              `json:"finished_at"`
+	Summary   core.Summary        `json:"summary"`
+	Usage     *engine.UsageReport `json:"usage,omitempty"`
 }
 
-func buildRunReport(ctx context.Context, storeDB *store.SQLiteStore, runID string, summary core.Summary) (runReport, error) {
+func buildRunReport(ctx context.Context, storeDB store.Store, runID string, summary core.Summary) (runReport, error) {
 	runSummary, err := storeDB.GetRunSummary(ctx, runID)
 	if err != nil {
 		return runReport{}, err
@@ -311,6 +399,36 @@ func buildRunReport(ctx context.Context, storeDB *store.SQLiteStore, runID strin
 	}, nil
 }
 
+func ingestArtifacts(ctx context.Context, cas *artifacts.CAS, storeDB store.Store, logger core.EventLogger, runID string) {
+	records, err := storeDB.ListArtifacts(ctx, runID)
+	if err == nil {
+		var report artifacts.IngestReport
+		if report, err = artifacts.Ingest(ctx, cas, records); err == nil {
+			_ = logger.Emit(core.Event{
+				RunID:     runID,
+				Level:     "info",
+				EventType: core.EventArtifactsIngested,
+				Payload: core.ArtifactsIngestedPayload{
+					Files:       report.Files,
+					Deduped:     report.Deduped,
+					Skipped:     report.Skipped,
+					Bytes:       report.Bytes,
+					StoredBytes: report.StoredBytes,
+				},
+			})
+			return
+		}
+	}
+	_ = logger.Emit(core.Event{
+		RunID:     runID,
+		Level:     "warn",
+		EventType: core.EventArtifactsIngestFailed,
+		Payload: core.ErrorPayload{
+			Error: err.Error(),
+		},
+	})
+}
+
 func writeJSON(path string, payload interface{}) error {
 	data, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
@@ -325,33 +443,57 @@ func newArtifact(runID string, tool string, kind string, path string) core.Artif
 	if info != nil {
 		size = info.Size()
 	}
+	sum, _ := artifacts.FileSHA256(path)
 	return core.ArtifactRecord{
 		RunID:     runID,
 		Tool:      tool,
 		Kind:      kind,
 		Path:      path,
+		SHA256:    sum,
 		SizeBytes: size,
 		CreatedAt: time.Now(),
 	}
 }
 
-func selectGitStrategy(strategy string, artifactRoot string) git.Strategy {
-	switch strategy {
-	case "worktree":
-		return git.NewWorktree(filepath.Join(artifactRoot, "worktrees"))
-	default:
-		return git.NewInPlace()
+func repoRelative(repoPath string, paths ...string) []string {
+	var out []string
+	for _, path := range paths {
+		rel, err := filepath.Rel(repoPath, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		out = append(out, filepath.ToSlash(rel))
 	}
+	return out
 }
 
-func selectAgentAdapter() agent.Agent {
-	codexCommand := strings.Fields(os.Getenv("ATQOS_CODEX_CMD"))
-	if len(codexCommand) > 0 {
-		return agent.NewCodexCLI(codexCommand)
-	}
-	command := strings.Fields(os.Getenv("ATQOS_AGENT_CMD"))
-	if len(command) == 0 {
-		return agent.NewLocal()
+func selectGitStrategy(cfg config.Config, runID string, repoPath string, artifactRoot string, base git.Base, excludes []string) (git.Strategy, error) {
+	switch cfg.GitStrategy {
+	case "copy":
+		rules := git.NewExcludes(nil)
+		if cfg.Copy.UseGitignore {
+			if err := rules.LoadFile(filepath.Join(repoPath, ".gitignore")); err != nil {
+				return nil, fmt.Errorf("load .gitignore: %w", err)
+			}
+		}
+		for _, pattern := range cfg.Copy.Excludes {
+			rules.Add(pattern)
+		}
+		for _, path := range excludes {
+			rules.Add("/" + path)
+		}
+		copier := git.NewCopy(filepath.Join(artifactRoot, "workspaces"), cfg.Copy.Mode, rules)
+		return copier, nil
+	case "worktree":
+		pool := git.NewWorktree(filepath.Join(artifactRoot, "worktrees"))
+		pool.Base = base
+		pool.RunID = runID
+		pool.PoolSize = cfg.MaxWorkers
+		if cfg.Speculative.Enabled && cfg.Speculative.Candidates > 1 {
+			pool.PoolSize *= cfg.Speculative.Candidates
+		}
+		return pool, nil
+	default:
+		return git.NewInPlace(), nil
 	}
-	return agent.NewCommandAdapter("cli", command)
 }
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"atqos/internal/store"
+)
+
+type DBMigrateCommand struct {
+	DBPath string
+}
+
+type DBMigrateResult struct {
+	From    int
+	To      int
+	Applied []string
+}
+
+type DBVersionCommand struct {
+	DBPath string
+}
+
+func (c DBMigrateCommand) Run(ctx context.Context) (DBMigrateResult, error) {
+	storeDB, err := openMigrator(c.DBPath)
+	if err != nil {
+		return DBMigrateResult{}, err
+	}
+	defer storeDB.Close()
+
+	before, err := storeDB.SchemaVersion(ctx)
+	if err != nil {
+		return DBMigrateResult{}, err
+	}
+	applied, err := storeDB.Migrate(ctx)
+	result := DBMigrateResult{From: before.Current, To: before.Current}
+	for _, migration := range applied {
+		result.Applied = append(result.Applied, fmt.Sprintf("%04d_%s", migration.Version, migration.Name))
+		result.To = migration.Version
+	}
+	return result, err
+}
+
+func (c DBVersionCommand) Run(ctx context.Context) (store.SchemaVersion, error) {
+	storeDB, err := openMigrator(c.DBPath)
+	if err != nil {
+		return store.SchemaVersion{}, err
+	}
+	defer storeDB.Close()
+	return storeDB.SchemaVersion(ctx)
+}
+
+type migratorStore interface {
+	store.Store
+	store.Migrator
+}
+
+func openMigrator(dsn string) (migratorStore, error) {
+	storeDB, err := store.Open(dsn, "")
+	if err != nil {
+		return nil, err
+	}
+	migrator, ok := storeDB.(migratorStore)
+	if !ok {
+		storeDB.Close()
+		return nil, fmt.Errorf("store %q does not support migrations", dsn)
+	}
+	return migrator, nil
+}
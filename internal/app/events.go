@@ -0,0 +1,152 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"atqos/internal/config"
+	"atqos/internal/eventlog"
+)
+
+const defaultWebhookRetries = 3
+
+// openEventSinks fans events out to the run's events.jsonl plus the sinks
+// configured under "events". Relative file paths are resolved against the
+// run's artifact directory.
+func openEventSinks(cfg config.EventsConfig, artifactRoot string) (*eventlog.Fanout, error) {
+	path := filepath.Join(artifactRoot, "events.jsonl")
+	primary, err := eventlog.New(path)
+	if err != nil {
+		return nil, err
+	}
+	fanout := eventlog.NewFanout(primary).WithHashChain(cfg.HashChain)
+	if err := fanout.Resume(path); err != nil {
+		fanout.Close()
+		return nil, err
+	}
+
+	add := func(sink eventlog.Sink, level string) error {
+		level, err := eventlog.ParseLevel(level)
+		if err != nil {
+			sink.Close()
+			return err
+		}
+		fanout.Add(eventlog.WithLevel(sink, level))
+		return nil
+	}
+
+	if cfg.Console.Enabled {
+		if err := add(eventlog.NewConsole(os.Stderr, cfg.Console.Color), cfg.Console.Level); err != nil {
+			fanout.Close()
+			return nil, err
+		}
+	}
+	for _, file := range cfg.Files {
+		if file.Path == "" {
+			fanout.Close()
+			return nil, fmt.Errorf("events: file sink needs a path")
+		}
+		filePath := file.Path
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(artifactRoot, filePath)
+		}
+		sink, err := eventlog.NewRotatingFile(filePath, file.MaxBytes, file.MaxFiles)
+		if err == nil {
+			err = add(sink, file.Level)
+		}
+		if err != nil {
+			fanout.Close()
+			return nil, err
+		}
+	}
+	for _, hook := range cfg.Webhooks {
+		if hook.URL == "" {
+			fanout.Close()
+			return nil, fmt.Errorf("events: webhook sink needs a url")
+		}
+		headers := make(http.Header)
+		for header, env := range hook.HeaderEnv {
+			if value := os.Getenv(env); value != "" {
+				headers.Set(header, value)
+			}
+		}
+		retries := hook.MaxRetries
+		if retries == 0 {
+			retries = defaultWebhookRetries
+		}
+		sink := eventlog.NewWebhook(hook.URL, eventlog.WebhookOptions{
+			Headers:       headers,
+			BatchSize:     hook.BatchSize,
+			FlushInterval: time.Duration(hook.FlushIntervalMS) * time.Millisecond,
+			MaxRetries:    retries,
+			Timeout:       time.Duration(hook.TimeoutSeconds) * time.Second,
+			QueueSize:     hook.QueueSize,
+		})
+		if err := add(sink, hook.Level); err != nil {
+			fanout.Close()
+			return nil, err
+		}
+	}
+	return fanout, nil
+}
+
+// EventsVerifyCommand checks an event log for gaps, reordering and, when
+// hash chained, modification. Target is an events.jsonl file, a run's
+// artifact directory, or a run ID under ArtifactDir.
+type EventsVerifyCommand struct {
+	ArtifactDir  string
+	Target       string
+	RequireChain bool
+}
+
+type EventsVerifyResult struct {
+	Path string
+	eventlog.VerifyReport
+}
+
+func (c EventsVerifyCommand) Run() (EventsVerifyResult, error) {
+	path, err := eventLogPath(c.ArtifactDir, c.Target)
+	if err != nil {
+		return EventsVerifyResult{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return EventsVerifyResult{}, err
+	}
+	defer file.Close()
+
+	report, err := eventlog.Verify(file)
+	if err != nil {
+		return EventsVerifyResult{Path: path, VerifyReport: report}, fmt.Errorf("verify %s: %w", path, err)
+	}
+	if report.Events == 0 {
+		report.Problems = append(report.Problems, "event log is empty")
+	}
+	if c.RequireChain && report.Events > 0 && !report.Chained {
+		report.Problems = append(report.Problems, "event log is not hash chained")
+	}
+	return EventsVerifyResult{Path: path, VerifyReport: report}, nil
+}
+
+// eventLogPath resolves target (an events.jsonl file, a run's artifact
+// directory, or a run ID under artifactDir) to an event log path.
+func eventLogPath(artifactDir string, target string) (string, error) {
+	info, err := os.Stat(target)
+	if err == nil {
+		if info.IsDir() {
+			return filepath.Join(target, "events.jsonl"), nil
+		}
+		return target, nil
+	}
+	if !os.IsNotExist(err) || artifactDir == "" {
+		return "", err
+	}
+	path := filepath.Join(artifactDir, target, "events.jsonl")
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
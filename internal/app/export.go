@@ -0,0 +1,309 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"atqos/internal/artifacts"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/git"
+	"atqos/internal/store"
+)
+
+const (
+	ExportPatches = "patches"
+	ExportBundle  = "bundle"
+	ExportBranch  = "branch"
+)
+
+type ExportCommand struct {
+	DBPath   string
+	RepoPath string
+	RunID    string
+	Format   string
+	OutDir   string
+	Branch   string
+	Remote   string
+}
+
+type ExportResult struct {
+	Branch      string
+	Head        string
+	Files       []string
+	Description string
+	Applied     int
+	Conflicts   int
+}
+
+type exportItem struct {
+	task    core.TaskRecord
+	attempt core.AttemptRecord
+	summary exportSummary
+	patch   []byte
+}
+
+type exportSummary struct {
+	Agent struct {
+		Summary string `json:"summary"`
+	} `json:"agent"`
+	Validation struct {
+		Passed           bool `json:"passed"`
+		NewFindings      int  `json:"new_findings"`
+		ResolvedFindings int  `json:"resolved_findings"`
+		Commands         []struct {
+			Args     []string `json:"args"`
+			ExitCode int      `json:"exit_code"`
+		} `json:"commands"`
+		Coverage *struct {
+			Before float64 `json:"before"`
+			After  float64 `json:"after"`
+			Delta  float64 `json:"delta"`
+		} `json:"coverage"`
+	} `json:"validation"`
+}
+
+func (c ExportCommand) Run(ctx context.Context) (ExportResult, error) {
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return ExportResult{}, err
+	}
+	defer storeDB.Close()
+
+	run, err := storeDB.GetRun(ctx, c.RunID)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("load run %s: %w", c.RunID, err)
+	}
+	cfg := config.Default()
+	_ = json.Unmarshal([]byte(run.Config), &cfg)
+
+	repoPath := c.RepoPath
+	if repoPath == "" {
+		repoPath = run.RepoPath
+	}
+	base := run.SnapshotCommit
+	if base == "" {
+		base = run.BaseCommit
+	}
+	if base == "" {
+		return ExportResult{}, fmt.Errorf("run %s has no recorded base commit", c.RunID)
+	}
+
+	format := c.Format
+	if format == "" {
+		format = ExportPatches
+	}
+	branch := c.Branch
+	if branch == "" {
+		branch = cfg.Export.BranchPrefix + c.RunID
+	}
+	remote := c.Remote
+	if remote == "" {
+		remote = cfg.Export.Remote
+	}
+	outDir := c.OutDir
+	if outDir == "" {
+		outDir = filepath.Join("exports", c.RunID)
+	}
+
+	var cas *artifacts.CAS
+	if cfg.Artifacts.CAS && cfg.Artifacts.CASDir != "" {
+		if cas, err = artifacts.NewCAS(cfg.Artifacts.CASDir, cfg.Artifacts.Compression); err != nil {
+			return ExportResult{}, err
+		}
+	}
+
+	items, err := exportItems(ctx, storeDB, cas, c.RunID)
+	if err != nil {
+		return ExportResult{}, err
+	}
+	patches := make([]git.SeriesPatch, 0, len(items))
+	for _, item := range items {
+		patches = append(patches, git.SeriesPatch{
+			Subject: item.task.Title,
+			Body: fmt.Sprintf("%s\n\nTask: %d (%s/%s)\nAttempt: %d\nAgent: %s\nRun: %s",
+				strings.TrimSpace(item.summary.Agent.Summary), item.task.ID, item.task.Tool, item.task.TaskType, item.attempt.ID, item.attempt.AgentName, c.RunID),
+			Data: item.patch,
+		})
+	}
+
+	series, err := git.BuildSeries(ctx, repoPath, base, branch, patches)
+	if err != nil {
+		return ExportResult{}, err
+	}
+	if len(series.Applied) == 0 {
+		return ExportResult{}, fmt.Errorf("run %s has no changes to export", c.RunID)
+	}
+
+	result := ExportResult{
+		Branch:    branch,
+		Head:      series.Head,
+		Applied:   len(series.Applied),
+		Conflicts: len(series.Conflicts),
+	}
+	switch format {
+	case ExportPatches:
+		result.Files, err = git.FormatPatches(ctx, repoPath, series, outDir)
+	case ExportBundle:
+		path := filepath.Join(outDir, c.RunID+".bundle")
+		err = git.CreateBundle(ctx, repoPath, series, path)
+		result.Files = []string{path}
+	case ExportBranch:
+		if remote != "" {
+			err = git.Push(ctx, repoPath, remote, branch)
+		}
+	default:
+		err = fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return result, err
+	}
+	result.Description = filepath.Join(outDir, "PR.md")
+	if err := os.WriteFile(result.Description, []byte(prDescription(run, items, series)), 0o644); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func exportItems(ctx context.Context, storeDB store.Store, cas *artifacts.CAS, runID string) ([]exportItem, error) {
+	tasks, err := storeDB.ListTasks(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := storeDB.ListArtifacts(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]core.ArtifactRecord, len(records))
+	for _, record := range records {
+		byPath[record.Path] = record
+	}
+	var items []exportItem
+	for _, task := range tasks {
+		if task.Status != "succeeded" {
+			items = append(items, exportItem{task: task})
+			continue
+		}
+		attempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		var winner *core.AttemptRecord
+		for i := range attempts {
+			if attempts[i].Status == "succeeded" {
+				winner = &attempts[i]
+			}
+		}
+		if winner == nil {
+			items = append(items, exportItem{task: task})
+			continue
+		}
+
+		item := exportItem{task: task, attempt: *winner}
+		_ = json.Unmarshal([]byte(winner.SummaryJSON), &item.summary)
+		var refs struct {
+			PatchRef string `json:"patch_ref"`
+		}
+		_ = json.Unmarshal([]byte(winner.ArtifactsJSON), &refs)
+		if refs.PatchRef != "" {
+			record, ok := byPath[refs.PatchRef]
+			if !ok {
+				record = core.ArtifactRecord{Path: refs.PatchRef}
+			}
+			item.patch, err = artifacts.ReadArtifact(cas, record)
+			if err != nil {
+				return nil, fmt.Errorf("read patch for task %d: %w", task.ID, err)
+			}
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].attempt.FinishedAt.Equal(items[j].attempt.FinishedAt) {
+			return items[i].attempt.ID < items[j].attempt.ID
+		}
+		return items[i].attempt.FinishedAt.Before(items[j].attempt.FinishedAt)
+	})
+	return items, nil
+}
+
+func prDescription(run core.RunRecord, items []exportItem, series git.Series) string {
+	applied := make(map[int]bool)
+	for _, i := range series.Applied {
+		applied[i] = true
+	}
+	conflicted := make(map[int]bool)
+	for _, i := range series.Conflicts {
+		conflicted[i] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# ATQOS fixes from run %s\n\n", run.RunID)
+	fmt.Fprintf(&b, "Base commit: `%s`", run.BaseCommit)
+	if run.SnapshotCommit != "" {
+		fmt.Fprintf(&b, " (with uncommitted changes, snapshot `%s`)", run.SnapshotCommit)
+	}
+	fmt.Fprintf(&b, "\n\n%d change(s) included.\n\n", len(series.Applied))
+
+	b.WriteString("## Tasks\n\n")
+	b.WriteString("| Task | Tool | Type | Severity | Status | Attempt |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	resolved := 0
+	for i, item := range items {
+		status := item.task.Status
+		switch {
+		case applied[i]:
+			status = "included"
+		case conflicted[i]:
+			status = "conflict (not included)"
+		}
+		attempt := "-"
+		if item.attempt.ID != 0 {
+			attempt = fmt.Sprintf("%d (%s)", item.attempt.ID, item.attempt.AgentName)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", markdownCell(item.task.Title), item.task.Tool, item.task.TaskType, item.task.Severity, status, attempt)
+		if applied[i] {
+			resolved += item.summary.Validation.ResolvedFindings
+		}
+	}
+
+	b.WriteString("\n## Findings resolved\n\n")
+	fmt.Fprintf(&b, "%d finding(s) resolved across included changes.\n\n", resolved)
+	for i, item := range items {
+		if !applied[i] {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %d resolved, %d new\n", item.task.Title, item.summary.Validation.ResolvedFindings, item.summary.Validation.NewFindings)
+	}
+
+	b.WriteString("\n## Validation evidence\n")
+	for i, item := range items {
+		if !applied[i] {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", item.task.Title)
+		if summary := strings.TrimSpace(item.summary.Agent.Summary); summary != "" {
+			fmt.Fprintf(&b, "%s\n\n", summary)
+		}
+		for _, command := range item.summary.Validation.Commands {
+			fmt.Fprintf(&b, "- `%s` exited %d\n", strings.Join(command.Args, " "), command.ExitCode)
+		}
+		if coverage := item.summary.Validation.Coverage; coverage != nil {
+			fmt.Fprintf(&b, "- coverage %.2f%% -> %.2f%% (%+.2f%%)\n", coverage.Before*100, coverage.After*100, coverage.Delta*100)
+		}
+	}
+	return b.String()
+}
+
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"atqos/internal/artifacts"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/git"
+	"atqos/internal/store"
+)
+
+type GCCommand struct {
+	RepoPath    string
+	DBPath      string
+	ArtifactDir string
+	CASDir      string
+	Retention   config.RetentionConfig
+	DryRun      bool
+}
+
+type GCResult struct {
+	Worktrees  []string
+	Branches   []string
+	Runs       []RunRemoval
+	Blobs      []string
+	Kept       int
+	FreedBytes int64
+	DryRun     bool
+}
+
+type RunRemoval struct {
+	RunID     string
+	RepoPath  string
+	Status    string
+	StartedAt time.Time
+	Dir       string
+	Bytes     int64
+	Reason    string
+}
+
+type retentionRun struct {
+	info  core.RunInfo
+	dir   string
+	bytes int64
+}
+
+func (c GCCommand) Run(ctx context.Context) (GCResult, error) {
+	result := GCResult{DryRun: c.DryRun}
+	var storeDB store.Store
+	if c.DBPath != "" && (!store.IsPath(c.DBPath) || fileExists(c.DBPath)) {
+		var err error
+		storeDB, err = store.Open(c.DBPath, "")
+		if err != nil {
+			return result, err
+		}
+		defer storeDB.Close()
+		if err := storeDB.Init(ctx); err != nil {
+			return result, err
+		}
+	}
+
+	if c.RepoPath != "" {
+		prune := git.PruneOrphans
+		if c.DryRun {
+			prune = git.FindOrphans
+		}
+		report, err := prune(ctx, c.RepoPath, worktreeOwnerActive(ctx, storeDB))
+		if err != nil {
+			return result, err
+		}
+		result.Worktrees = report.Worktrees
+		result.Branches = report.Branches
+	}
+
+	if storeDB == nil {
+		return result, nil
+	}
+
+	infos, err := storeDB.ListRuns(ctx)
+	if err != nil {
+		return result, fmt.Errorf("list runs: %w", err)
+	}
+	runs := make([]retentionRun, 0, len(infos))
+	for _, info := range infos {
+		run := retentionRun{info: info}
+		if c.ArtifactDir != "" {
+			run.dir = filepath.Join(c.ArtifactDir, info.RunID)
+			run.bytes = dirSize(run.dir)
+		}
+		runs = append(runs, run)
+	}
+
+	removals := planRetention(c.Retention, runs, time.Now())
+	result.Kept = len(runs) - len(removals)
+	for _, removal := range removals {
+		if !c.DryRun {
+			if removal.Dir != "" {
+				if err := os.RemoveAll(removal.Dir); err != nil {
+					return result, fmt.Errorf("remove artifacts for %s: %w", removal.RunID, err)
+				}
+			}
+			if err := storeDB.DeleteRun(ctx, removal.RunID); err != nil {
+				return result, fmt.Errorf("delete run %s: %w", removal.RunID, err)
+			}
+		}
+		result.Runs = append(result.Runs, removal)
+		result.FreedBytes += removal.Bytes
+	}
+
+	if c.CASDir == "" {
+		return result, nil
+	}
+	removed := make(map[string]bool, len(removals))
+	for _, removal := range removals {
+		removed[removal.RunID] = true
+	}
+	referenced := make(map[string]bool)
+	for _, info := range infos {
+		if removed[info.RunID] {
+			continue
+		}
+		records, err := storeDB.ListArtifacts(ctx, info.RunID)
+		if err != nil {
+			return result, fmt.Errorf("list artifacts for %s: %w", info.RunID, err)
+		}
+		for _, record := range records {
+			referenced[record.SHA256] = true
+		}
+	}
+	if err := sweepBlobs(c.CASDir, casOwner(c.DBPath), referenced, c.DryRun, &result); err != nil {
+		return result, fmt.Errorf("sweep artifact store: %w", err)
+	}
+	return result, nil
+}
+
+// worktreeOwnerActive treats a worktree as in use while its owning process
+// is alive and, when the store knows its run, that run is still running.
+func worktreeOwnerActive(ctx context.Context, storeDB store.Store) func(git.Owner) bool {
+	return func(owner git.Owner) bool {
+		if !owner.Alive() {
+			return false
+		}
+		if storeDB == nil || owner.RunID == "" {
+			return true
+		}
+		run, err := storeDB.GetRun(ctx, owner.RunID)
+		if err != nil {
+			return true
+		}
+		return run.Status == core.RunStatusRunning
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// sweepBlobs releases this database's claim on blobs none of its runs
+// reference. A blob is only deleted once no other database sharing the
+// store claims it; blobs stored before claims were recorded are kept.
+func sweepBlobs(root string, owner string, referenced map[string]bool, dryRun bool, result *GCResult) error {
+	cas, err := artifacts.NewCAS(root, "")
+	if err != nil {
+		return err
+	}
+	cas.WithOwner(owner)
+	sums, err := cas.Owned()
+	if err != nil {
+		return err
+	}
+	for _, sum := range sums {
+		if referenced[sum] {
+			continue
+		}
+		shared, err := cas.Shared(sum)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			if !shared {
+				result.FreedBytes += cas.Size(sum)
+				result.Blobs = append(result.Blobs, sum)
+			}
+			continue
+		}
+		freed, err := cas.Release(sum)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			result.FreedBytes += freed
+			result.Blobs = append(result.Blobs, sum)
+		}
+	}
+	return nil
+}
+
+// casOwner identifies a database among the users of a shared artifact
+// store.
+func casOwner(dsn string) string {
+	if store.IsPath(dsn) {
+		if abs, err := filepath.Abs(dsn); err == nil {
+			dsn = abs
+		}
+	}
+	sum := sha256.Sum256([]byte(dsn))
+	return hex.EncodeToString(sum[:8])
+}
+
+func planRetention(policy config.RetentionConfig, runs []retentionRun, now time.Time) []RunRemoval {
+	var (
+		removals []RunRemoval
+		kept     []retentionRun
+		perRepo  = make(map[string]int)
+		total    int64
+	)
+	remove := func(run retentionRun, reason string) {
+		removals = append(removals, RunRemoval{
+			RunID:     run.info.RunID,
+			RepoPath:  run.info.RepoPath,
+			Status:    run.info.Status,
+			StartedAt: run.info.StartedAt,
+			Dir:       run.dir,
+			Bytes:     run.bytes,
+			Reason:    reason,
+		})
+	}
+
+	// runs are newest first.
+	for _, run := range runs {
+		perRepo[run.info.RepoPath]++
+		switch {
+		case retained(policy, run.info):
+			kept = append(kept, run)
+			total += run.bytes
+		case policy.KeepLast > 0 && perRepo[run.info.RepoPath] > policy.KeepLast:
+			remove(run, fmt.Sprintf("beyond last %d runs for repository", policy.KeepLast))
+		case policy.MaxAgeDays > 0 && now.Sub(run.info.StartedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour:
+			remove(run, fmt.Sprintf("older than %d days", policy.MaxAgeDays))
+		default:
+			kept = append(kept, run)
+			total += run.bytes
+		}
+	}
+
+	if policy.MaxTotalBytes > 0 {
+		for i := len(kept) - 1; i >= 0 && total > policy.MaxTotalBytes; i-- {
+			if retained(policy, kept[i].info) {
+				continue
+			}
+			remove(kept[i], fmt.Sprintf("artifacts exceed %d bytes", policy.MaxTotalBytes))
+			total -= kept[i].bytes
+		}
+	}
+	return removals
+}
+
+func retained(policy config.RetentionConfig, run core.RunInfo) bool {
+	switch {
+	case run.Status == core.RunStatusRunning:
+		return true
+	case policy.KeepFailed && run.Status != core.RunStatusSucceeded:
+		return true
+	case policy.KeepFlagged && run.Flagged:
+		return true
+	}
+	return false
+}
+
+// dirSize counts the bytes removing dir would free. Hard-linked files are
+// left out: their data stays reachable through the other link, and a blob
+// in the artifact store is counted when the sweep removes it.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil && !hardLinked(info) {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
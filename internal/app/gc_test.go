@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"atqos/internal/artifacts"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/store"
+)
+
+func putBlob(t *testing.T, root string, owner string, content string) string {
+	t.Helper()
+	cas, err := artifacts.NewCAS(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner != "" {
+		cas.WithOwner(owner)
+	}
+	blob, err := cas.Put(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob.SHA256
+}
+
+func TestSweepBlobsKeepsBlobsOtherDatabasesClaim(t *testing.T) {
+	root := t.TempDir()
+	shared := putBlob(t, root, "db-a", "shared")
+	onlyA := putBlob(t, root, "db-a", "only a")
+	kept := putBlob(t, root, "db-a", "referenced")
+	putBlob(t, root, "db-b", "shared")
+	legacy := putBlob(t, root, "", "stored before owners")
+	referenced := map[string]bool{kept: true}
+	cas, _ := artifacts.NewCAS(root, "")
+
+	var dry GCResult
+	if err := sweepBlobs(root, "db-a", referenced, true, &dry); err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Blobs) != 1 || dry.Blobs[0] != onlyA || dry.FreedBytes != int64(len("only a")) {
+		t.Fatalf("dry run = %v, %d bytes, want only %s", dry.Blobs, dry.FreedBytes, onlyA)
+	}
+	if !cas.Has(onlyA) {
+		t.Fatal("dry run removed a blob")
+	}
+
+	var result GCResult
+	if err := sweepBlobs(root, "db-a", referenced, false, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Blobs) != 1 || result.Blobs[0] != onlyA {
+		t.Fatalf("removed %v, want only %s", result.Blobs, onlyA)
+	}
+	for sum, want := range map[string]bool{onlyA: false, shared: true, kept: true, legacy: true} {
+		if cas.Has(sum) != want {
+			t.Errorf("blob %s present = %v, want %v", sum[:8], !want, want)
+		}
+	}
+
+	// Once the other database lets go too, the shared blob goes.
+	result = GCResult{}
+	if err := sweepBlobs(root, "db-b", nil, false, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Blobs) != 1 || result.Blobs[0] != shared || cas.Has(shared) {
+		t.Fatalf("second sweep removed %v, want %s", result.Blobs, shared)
+	}
+	if !cas.Has(legacy) {
+		t.Fatal("unclaimed blob removed")
+	}
+}
+
+func TestDirSizeSkipsHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not read on windows")
+	}
+	dir := t.TempDir()
+	blob := filepath.Join(t.TempDir(), "blob")
+	for path, content := range map[string]string{filepath.Join(dir, "own.log"): "12345", blob: "1234567890"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(blob, filepath.Join(dir, "linked.xml")); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+	if got := dirSize(dir); got != 5 {
+		t.Fatalf("dirSize = %d, want 5", got)
+	}
+}
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	run := func(id string, status string, age time.Duration, bytes int64) retentionRun {
+		return retentionRun{info: core.RunInfo{RunID: id, RepoPath: "/repo", Status: status, StartedAt: now.Add(-age)}, bytes: bytes}
+	}
+	succeeded, failed, running := core.RunStatusSucceeded, core.RunStatusFailed, core.RunStatusRunning
+
+	for _, tc := range []struct {
+		name   string
+		policy config.RetentionConfig
+		runs   []retentionRun
+		want   []string
+	}{
+		{
+			name:   "age boundary",
+			policy: config.RetentionConfig{MaxAgeDays: 7},
+			runs:   []retentionRun{run("new", succeeded, day, 1), run("exactly", succeeded, 7*day, 1), run("just-over", succeeded, 7*day+time.Second, 1)},
+			want:   []string{"just-over"},
+		},
+		{
+			name:   "active runs outlive their age",
+			policy: config.RetentionConfig{MaxAgeDays: 1},
+			runs:   []retentionRun{run("active", running, 30*day, 1), run("done", succeeded, 30*day, 1)},
+			want:   []string{"done"},
+		},
+		{
+			name:   "keep last counts active runs but keeps them",
+			policy: config.RetentionConfig{KeepLast: 1},
+			runs:   []retentionRun{run("newest", succeeded, day, 1), run("active", running, 2*day, 1), run("oldest", succeeded, 3*day, 1)},
+			want:   []string{"oldest"},
+		},
+		{
+			name:   "keep failed",
+			policy: config.RetentionConfig{MaxAgeDays: 1, KeepFailed: true},
+			runs:   []retentionRun{run("failed", failed, 30*day, 1), run("done", succeeded, 30*day, 1)},
+			want:   []string{"done"},
+		},
+		{
+			name:   "size cap removes oldest first and skips active runs",
+			policy: config.RetentionConfig{MaxTotalBytes: 25},
+			runs:   []retentionRun{run("a", succeeded, day, 10), run("b", succeeded, 2*day, 10), run("c", succeeded, 3*day, 10), run("d", running, 4*day, 10)},
+			want:   []string{"c", "b"},
+		},
+		{
+			name:   "size cap at the limit",
+			policy: config.RetentionConfig{MaxTotalBytes: 20},
+			runs:   []retentionRun{run("a", succeeded, day, 10), run("b", succeeded, 2*day, 10)},
+			want:   nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, removal := range planRetention(tc.policy, tc.runs, now) {
+				got = append(got, removal.RunID)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("removed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGCDryRunReportsWithoutRemoving(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := filepath.Join(dir, "atqos.db")
+	artifactDir := filepath.Join(dir, "artifacts")
+	storeDB, err := store.Open(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storeDB.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"run-new", "run-old"} {
+		if err := storeDB.CreateRun(ctx, core.RunRecord{RunID: id, RepoPath: "/repo", StartedAt: time.Now().Add(-time.Duration(i*30*24) * time.Hour), Status: core.RunStatusSucceeded, Config: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(artifactDir, id), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(artifactDir, id, "summary.json"), []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storeDB.Close()
+
+	cmd := GCCommand{DBPath: db, ArtifactDir: artifactDir, Retention: config.RetentionConfig{MaxAgeDays: 7}, DryRun: true}
+	dry, err := cmd.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Runs) != 1 || dry.Runs[0].RunID != "run-old" || dry.FreedBytes != 10 || dry.Kept != 1 || !dry.DryRun {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "run-old")); err != nil {
+		t.Fatalf("dry run removed artifacts: %v", err)
+	}
+
+	cmd.DryRun = false
+	result, err := cmd.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Runs) != 1 || result.FreedBytes != dry.FreedBytes || result.Kept != dry.Kept {
+		t.Fatalf("real run = %+v, want what the dry run reported", result)
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "run-old")); !os.IsNotExist(err) {
+		t.Fatalf("artifacts left behind: %v", err)
+	}
+	if again, err := cmd.Run(ctx); err != nil || len(again.Runs) != 0 || again.Kept != 1 {
+		t.Fatalf("second gc = %+v, %v", again, err)
+	}
+}
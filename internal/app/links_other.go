@@ -0,0 +1,9 @@
+//go:build !unix
+
+package app
+
+import "io/fs"
+
+func hardLinked(info fs.FileInfo) bool {
+	return false
+}
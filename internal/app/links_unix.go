@@ -0,0 +1,15 @@
+//go:build unix
+
+package app
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// hardLinked reports whether the file has other names, such as a run file
+// linked to its artifact store blob.
+func hardLinked(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Nlink > 1
+}
@@ -0,0 +1,458 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"atqos/internal/core"
+	"atqos/internal/eventlog"
+	"atqos/internal/store"
+)
+
+// ReplayEventsCommand rebuilds a run's rows in DBPath from the row events
+// in its event log. Task and attempt IDs are reassigned by the store.
+type ReplayEventsCommand struct {
+	DBPath      string
+	ArtifactDir string
+	Target      string
+}
+
+type ReplayEventsResult struct {
+	RunID     string
+	Findings  int
+	Tasks     int
+	Attempts  int
+	Artifacts int
+}
+
+// EventsCheckCommand compares the state derived from a run's event log with
+// the rows stored for that run in DBPath.
+type EventsCheckCommand struct {
+	DBPath      string
+	ArtifactDir string
+	Target      string
+}
+
+type EventsCheckResult struct {
+	Path  string
+	RunID string
+	Diffs []string
+}
+
+func (c ReplayEventsCommand) Run(ctx context.Context) (ReplayEventsResult, error) {
+	path, err := eventLogPath(c.ArtifactDir, c.Target)
+	if err != nil {
+		return ReplayEventsResult{}, err
+	}
+	state, err := replayEvents(path)
+	if err != nil {
+		return ReplayEventsResult{}, err
+	}
+
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return ReplayEventsResult{}, err
+	}
+	defer storeDB.Close()
+	if err := storeDB.Init(ctx); err != nil {
+		return ReplayEventsResult{}, err
+	}
+	if _, err := storeDB.GetRun(ctx, state.run.RunID); err == nil {
+		return ReplayEventsResult{}, fmt.Errorf("run %s already exists", state.run.RunID)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return ReplayEventsResult{}, err
+	}
+
+	imp := newBundleImport(storeDB, "", "")
+	if err := imp.load(ctx, state.run, state.findings, state.tasks, state.attempts, state.records); err != nil {
+		if cleanupErr := storeDB.DeleteRun(context.Background(), state.run.RunID); cleanupErr != nil {
+			err = fmt.Errorf("%w; removing the partial run %s also failed: %v", err, state.run.RunID, cleanupErr)
+		}
+		return ReplayEventsResult{}, err
+	}
+	_, _ = indexOutputs(ctx, storeDB, state.run.RunID)
+	return ReplayEventsResult{
+		RunID:     state.run.RunID,
+		Findings:  len(state.findings),
+		Tasks:     len(state.tasks),
+		Attempts:  len(state.attempts),
+		Artifacts: len(state.records),
+	}, nil
+}
+
+func (c EventsCheckCommand) Run(ctx context.Context) (EventsCheckResult, error) {
+	path, err := eventLogPath(c.ArtifactDir, c.Target)
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+	state, err := replayEvents(path)
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+	defer storeDB.Close()
+
+	result := EventsCheckResult{Path: path, RunID: state.run.RunID}
+	run, err := storeDB.GetRun(ctx, state.run.RunID)
+	if errors.Is(err, store.ErrNotFound) {
+		result.Diffs = append(result.Diffs, fmt.Sprintf("run %s is not in the database", state.run.RunID))
+		return result, nil
+	} else if err != nil {
+		return EventsCheckResult{}, err
+	}
+	findings, err := storeDB.ListFindings(ctx, run.RunID)
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+	tasks, err := storeDB.ListTasks(ctx, run.RunID)
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+	var attempts []core.AttemptRecord
+	for _, task := range tasks {
+		taskAttempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			return EventsCheckResult{}, err
+		}
+		for _, attempt := range taskAttempts {
+			attempt.TaskID = task.ID
+			attempts = append(attempts, attempt)
+		}
+	}
+	records, err := storeDB.ListArtifacts(ctx, run.RunID)
+	if err != nil {
+		return EventsCheckResult{}, err
+	}
+
+	d := &stateDiff{}
+	d.run(state.run, run)
+	d.findings(state.findings, findings)
+	d.tasks(state.tasks, tasks)
+	d.attempts(state.attempts, attempts)
+	d.artifacts(state.records, records)
+	result.Diffs = d.diffs
+	return result, nil
+}
+
+type replayState struct {
+	run      core.RunRecord
+	findings []core.FindingRecord
+	tasks    []core.TaskRecord
+	attempts []core.AttemptRecord
+	records  []core.ArtifactRecord
+}
+
+// replayEvents folds the row events of an event log into the rows they
+// describe. Other events are ignored.
+func replayEvents(path string) (*replayState, error) {
+	state := &replayState{}
+	taskIndex := make(map[int64]int)
+	attemptIndex := make(map[int64]int)
+
+	err := eventlog.ScanFile(path, func(record eventlog.Record) error {
+		if record.EventType != core.EventRunCreated && state.run.RunID == "" {
+			return nil
+		}
+		if state.run.RunID != "" && record.RunID != state.run.RunID {
+			return fmt.Errorf("line %d: event for run %s in log of run %s", record.LineNo, record.RunID, state.run.RunID)
+		}
+		ts := record.TS
+
+		switch payload := record.Payload.(type) {
+		case *core.RunCreatedPayload:
+			if state.run.RunID != "" {
+				return fmt.Errorf("line %d: run created twice", record.LineNo)
+			}
+			state.run = core.RunRecord{
+				RunID:          record.RunID,
+				RepoPath:       payload.RepoPath,
+				StartedAt:      payload.StartedAt,
+				Status:         payload.Status,
+				Config:         payload.Config,
+				BaseCommit:     payload.BaseCommit,
+				DirtyDiff:      payload.DirtyDiff,
+				SnapshotCommit: payload.SnapshotCommit,
+			}
+		case *core.RunStatusChangedPayload:
+			state.run.Status = payload.Status
+			state.run.SummaryJSON = payload.SummaryJSON
+			state.run.FinishedAt = ts
+		case *core.FindingsRecordedPayload:
+			for _, finding := range payload.Findings {
+				state.findings = append(state.findings, core.FindingRecord{
+					RunID:       record.RunID,
+					Tool:        finding.Tool,
+					Kind:        finding.Kind,
+					Severity:    finding.Severity,
+					Fingerprint: finding.Fingerprint,
+					Message:     finding.Message,
+					FilePath:    finding.FilePath,
+					Line:        finding.Line,
+					Column:      finding.Column,
+					Symbol:      finding.Symbol,
+					TestID:      finding.TestID,
+					RawRef:      finding.RawRef,
+					MetaJSON:    finding.MetaJSON,
+					CreatedAt:   finding.CreatedAt,
+				})
+			}
+		case *core.ArtifactRecordedPayload:
+			state.records = append(state.records, core.ArtifactRecord{
+				RunID:     record.RunID,
+				Tool:      record.Tool,
+				Kind:      payload.Kind,
+				Path:      payload.Path,
+				SHA256:    payload.SHA256,
+				SizeBytes: payload.SizeBytes,
+				CreatedAt: payload.CreatedAt,
+				MetaJSON:  payload.MetaJSON,
+			})
+		case *core.TaskCreatedPayload:
+			if _, ok := taskIndex[record.TaskID]; ok || record.TaskID == 0 {
+				return fmt.Errorf("line %d: task %d created twice", record.LineNo, record.TaskID)
+			}
+			taskIndex[record.TaskID] = len(state.tasks)
+			state.tasks = append(state.tasks, core.TaskRecord{
+				ID:              record.TaskID,
+				RunID:           record.RunID,
+				Tool:            record.Tool,
+				TaskType:        payload.TaskType,
+				Severity:        payload.Severity,
+				Priority:        payload.Priority,
+				Status:          payload.Status,
+				Fingerprint:     payload.Fingerprint,
+				Title:           payload.Title,
+				Description:     payload.Description,
+				TargetsJSON:     payload.TargetsJSON,
+				ValidationJSON:  payload.ValidationJSON,
+				RetryPolicyJSON: payload.RetryPolicyJSON,
+				DependsOnJSON:   payload.DependsOnJSON,
+				ExtraJSON:       payload.ExtraJSON,
+				CreatedAt:       payload.CreatedAt,
+				UpdatedAt:       payload.CreatedAt,
+			})
+		case *core.TaskClaimedPayload:
+			i, ok := taskIndex[record.TaskID]
+			if !ok {
+				return fmt.Errorf("line %d: claim of unknown task %d", record.LineNo, record.TaskID)
+			}
+			state.tasks[i].Status = "running"
+			state.tasks[i].ClaimedBy = payload.WorkerID
+			state.tasks[i].ClaimedAt = ts
+			state.tasks[i].UpdatedAt = ts
+		case *core.TaskStatusChangedPayload:
+			i, ok := taskIndex[record.TaskID]
+			if !ok {
+				return fmt.Errorf("line %d: status change of unknown task %d", record.LineNo, record.TaskID)
+			}
+			state.tasks[i].Status = payload.Status
+			state.tasks[i].ExtraJSON = payload.ExtraJSON
+			state.tasks[i].UpdatedAt = ts
+		case *core.AttemptStartedPayload:
+			if _, ok := taskIndex[record.TaskID]; !ok {
+				return fmt.Errorf("line %d: attempt for unknown task %d", record.LineNo, record.TaskID)
+			}
+			if _, ok := attemptIndex[record.AttemptID]; ok || record.AttemptID == 0 {
+				return fmt.Errorf("line %d: attempt %d started twice", record.LineNo, record.AttemptID)
+			}
+			attemptIndex[record.AttemptID] = len(state.attempts)
+			state.attempts = append(state.attempts, core.AttemptRecord{
+				ID:                 record.AttemptID,
+				TaskID:             record.TaskID,
+				AttemptNo:          payload.AttemptNo,
+				Status:             payload.Status,
+				AgentName:          payload.AgentName,
+				AgentExitCode:      payload.AgentExitCode,
+				ValidationExitCode: payload.ValidationExitCode,
+				StartedAt:          payload.StartedAt,
+				SummaryJSON:        payload.SummaryJSON,
+				DiffStatsJSON:      payload.DiffStatsJSON,
+				ArtifactsJSON:      payload.ArtifactsJSON,
+			})
+		case *core.AttemptFinishedPayload:
+			i, ok := attemptIndex[record.AttemptID]
+			if !ok {
+				return fmt.Errorf("line %d: finish of unknown attempt %d", record.LineNo, record.AttemptID)
+			}
+			attempt := &state.attempts[i]
+			if record.TaskID != 0 && record.TaskID != attempt.TaskID {
+				return fmt.Errorf("line %d: attempt %d finished under task %d but started under task %d", record.LineNo, record.AttemptID, record.TaskID, attempt.TaskID)
+			}
+			attempt.Status = payload.Status
+			attempt.AgentExitCode = payload.AgentExitCode
+			attempt.ValidationExitCode = payload.ValidationExitCode
+			attempt.SummaryJSON = payload.SummaryJSON
+			attempt.DiffStatsJSON = payload.DiffStatsJSON
+			attempt.ArtifactsJSON = payload.ArtifactsJSON
+			attempt.FinishedAt = payload.FinishedAt
+			if attempt.FinishedAt.IsZero() {
+				attempt.FinishedAt = ts
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	if state.run.RunID == "" {
+		return nil, fmt.Errorf("replay %s: no %s event; the log predates row events", path, core.EventRunCreated)
+	}
+	return state, nil
+}
+
+// stateDiff collects differences between event-derived rows (want) and
+// stored rows (got). Timestamps are not compared: the store and the log
+// stamp them independently and at different precision.
+type stateDiff struct {
+	diffs []string
+}
+
+func (d *stateDiff) add(format string, args ...interface{}) {
+	d.diffs = append(d.diffs, fmt.Sprintf(format, args...))
+}
+
+func (d *stateDiff) field(what string, name string, want interface{}, got interface{}) {
+	if want != got {
+		d.add("%s %s: events %s, database %s", what, name, diffValue(want), diffValue(got))
+	}
+}
+
+func (d *stateDiff) run(want core.RunRecord, got core.RunRecord) {
+	d.field("run", "status", want.Status, got.Status)
+	d.field("run", "repo_path", want.RepoPath, got.RepoPath)
+	d.field("run", "base_commit", want.BaseCommit, got.BaseCommit)
+	d.field("run", "snapshot_commit", want.SnapshotCommit, got.SnapshotCommit)
+	d.field("run", "config", want.Config, got.Config)
+	d.field("run", "summary_json", want.SummaryJSON, got.SummaryJSON)
+}
+
+func (d *stateDiff) findings(want []core.FindingRecord, got []core.FindingRecord) {
+	key := func(f core.FindingRecord) string {
+		return f.Tool + "/" + f.Kind + "/" + f.Fingerprint
+	}
+	counts := make(map[string]int)
+	for _, finding := range want {
+		counts[key(finding)]++
+	}
+	for _, finding := range got {
+		counts[key(finding)]--
+	}
+	d.counts("finding", counts)
+}
+
+func (d *stateDiff) artifacts(want []core.ArtifactRecord, got []core.ArtifactRecord) {
+	key := func(r core.ArtifactRecord) string {
+		return r.Kind + " " + r.Path + " " + r.SHA256
+	}
+	counts := make(map[string]int)
+	for _, record := range want {
+		counts[key(record)]++
+	}
+	for _, record := range got {
+		counts[key(record)]--
+	}
+	d.counts("artifact", counts)
+}
+
+func (d *stateDiff) counts(what string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key, n := range counts {
+		if n != 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if n := counts[key]; n > 0 {
+			d.add("%s %s: %d only in events", what, key, n)
+		} else {
+			d.add("%s %s: %d only in database", what, key, -n)
+		}
+	}
+}
+
+func (d *stateDiff) tasks(want []core.TaskRecord, got []core.TaskRecord) {
+	stored := make(map[int64]core.TaskRecord, len(got))
+	for _, task := range got {
+		stored[task.ID] = task
+	}
+	for _, w := range want {
+		g, ok := stored[w.ID]
+		if !ok {
+			d.add("task %d: only in events", w.ID)
+			continue
+		}
+		delete(stored, w.ID)
+		what := fmt.Sprintf("task %d", w.ID)
+		d.field(what, "status", w.Status, g.Status)
+		d.field(what, "tool", w.Tool, g.Tool)
+		d.field(what, "task_type", w.TaskType, g.TaskType)
+		d.field(what, "severity", w.Severity, g.Severity)
+		d.field(what, "priority", w.Priority, g.Priority)
+		d.field(what, "fingerprint", w.Fingerprint, g.Fingerprint)
+		d.field(what, "title", w.Title, g.Title)
+		d.field(what, "validation_json", w.ValidationJSON, g.ValidationJSON)
+		d.field(what, "depends_on_json", w.DependsOnJSON, g.DependsOnJSON)
+		d.field(what, "extra_json", w.ExtraJSON, g.ExtraJSON)
+		d.field(what, "claimed_by", w.ClaimedBy, g.ClaimedBy)
+	}
+	ids := make([]int64, 0, len(stored))
+	for id := range stored {
+		ids = append(ids, id)
+	}
+	for _, id := range sortIDs(ids) {
+		d.add("task %d: only in database", id)
+	}
+}
+
+func (d *stateDiff) attempts(want []core.AttemptRecord, got []core.AttemptRecord) {
+	stored := make(map[int64]core.AttemptRecord, len(got))
+	for _, attempt := range got {
+		stored[attempt.ID] = attempt
+	}
+	for _, w := range want {
+		g, ok := stored[w.ID]
+		if !ok {
+			d.add("attempt %d: only in events", w.ID)
+			continue
+		}
+		delete(stored, w.ID)
+		what := fmt.Sprintf("attempt %d", w.ID)
+		d.field(what, "task_id", w.TaskID, g.TaskID)
+		d.field(what, "attempt_no", w.AttemptNo, g.AttemptNo)
+		d.field(what, "status", w.Status, g.Status)
+		d.field(what, "agent_name", w.AgentName, g.AgentName)
+		d.field(what, "agent_exit_code", w.AgentExitCode, g.AgentExitCode)
+		d.field(what, "validation_exit_code", w.ValidationExitCode, g.ValidationExitCode)
+		d.field(what, "summary_json", w.SummaryJSON, g.SummaryJSON)
+		d.field(what, "diff_stats_json", w.DiffStatsJSON, g.DiffStatsJSON)
+		d.field(what, "artifacts_json", w.ArtifactsJSON, g.ArtifactsJSON)
+		d.field(what, "finished", !w.FinishedAt.IsZero(), !g.FinishedAt.IsZero())
+	}
+	ids := make([]int64, 0, len(stored))
+	for id := range stored {
+		ids = append(ids, id)
+	}
+	for _, id := range sortIDs(ids) {
+		d.add("attempt %d: only in database", id)
+	}
+}
+
+func sortIDs(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func diffValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if len(s) > 80 {
+		s = s[:80] + "..."
+	}
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"atqos/internal/core"
+	"atqos/internal/eventlog"
+	"atqos/internal/store"
+)
+
+func TestEventsMatchDatabaseAfterRun(t *testing.T) {
+	ctx := context.Background()
+	run := runFixture(t, newFixtureRepo(t), nil)
+
+	storeDB, err := store.Open(run.db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := storeDB.ListTasks(ctx, run.result.RunID)
+	storeDB.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Status != "succeeded" {
+		t.Fatalf("tasks = %+v, want one succeeded task", tasks)
+	}
+
+	check, err := EventsCheckCommand{DBPath: run.db, ArtifactDir: run.artifactDir, Target: run.result.RunID}.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Diffs) > 0 {
+		t.Fatalf("events and database differ:\n%v", check.Diffs)
+	}
+
+	started := make(map[int64]int64)
+	finished := 0
+	if err := eventlog.ScanFile(check.Path, func(record eventlog.Record) error {
+		switch record.EventType {
+		case core.EventAttemptStarted:
+			started[record.AttemptID] = record.TaskID
+		case core.EventAttemptFinished:
+			finished++
+			if want := started[record.AttemptID]; record.TaskID != want {
+				t.Errorf("attempt %d finished under task %d, started under %d", record.AttemptID, record.TaskID, want)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if finished == 0 {
+		t.Fatal("no attempt_finished events")
+	}
+
+	replayed, err := ReplayEventsCommand{DBPath: filepath.Join(t.TempDir(), "replayed.db"), ArtifactDir: run.artifactDir, Target: run.result.RunID}.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.RunID != run.result.RunID || replayed.Tasks != 1 || replayed.Attempts != finished {
+		t.Fatalf("replayed %+v, want 1 task and %d attempts", replayed, finished)
+	}
+}
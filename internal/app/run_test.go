@@ -0,0 +1,318 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"atqos/internal/agent"
+	"atqos/internal/core"
+	"atqos/internal/engine"
+	"atqos/internal/store"
+)
+
+// fakePython stands in for the repository's pytest: tests/test_a.py::test_x
+// fails until src/a.py contains FIXED.
+const fakePython = `#!/bin/sh
+status=failed
+grep -q FIXED src/a.py 2>/dev/null && status=passed
+for arg in "$@"; do
+  case "$arg" in
+    --json-report-file=*) printf '{"tests":[{"nodeid":"tests/test_a.py::test_x","outcome":"%s","longrepr":{"reprcrash":{"message":"AssertionError: boom"}}}]}' "$status" > "${arg#--json-report-file=}";;
+  esac
+done
+[ "$status" = passed ] && echo "validation marker zebra-quartz"
+echo "fake pytest $*"
+[ "$status" = passed ]
+`
+
+const helperAgentEnv = "ATQOS_TEST_HELPER_AGENT"
+
+// helperUsage is what every helper agent invocation reports spending.
+var helperUsage = agent.Usage{InputTokens: 600, OutputTokens: 400, CostUSD: 0.25}
+
+// TestHelperAgent is not a test: runs started by runFixture invoke this test
+// binary as their agent. In "fix" mode it appends FIXED to src/a.py; in
+// "noop" mode it gives up; in "repair" mode it claims success without a
+// change until it is shown the failing pytest output, then fixes.
+func TestHelperAgent(t *testing.T) {
+	mode := os.Getenv(helperAgentEnv)
+	if mode == "" {
+		return
+	}
+	var req agent.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(2)
+	}
+	fix := mode == "fix"
+	for _, previous := range req.PreviousAttempts {
+		fix = fix || mode == "repair" && strings.Contains(previous.Output, "fake pytest")
+	}
+	result := agent.Result{
+		SchemaVersion: req.SchemaVersion,
+		RunID:         req.RunID,
+		TaskID:        req.TaskID,
+		Status:        "success",
+		Summary:       "nothing to change",
+		Usage:         &helperUsage,
+	}
+	switch {
+	case fix:
+		file, err := os.OpenFile(filepath.Join(req.WorkspacePath, "src", "a.py"), os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			os.Exit(2)
+		}
+		file.WriteString("# FIXED\n")
+		file.Close()
+		result.Summary = "fixed"
+		result.FilesChanged = []string{"src/a.py"}
+	case mode == "noop":
+		result.Status = "failure"
+		result.Summary = "gave up"
+	}
+	json.NewEncoder(os.Stdout).Encode(result)
+	os.Exit(0)
+}
+
+// helperAgent configures TestHelperAgent as a command agent in mode.
+func helperAgent(mode string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "command",
+		"command": []string{os.Args[0], "-test.run=^TestHelperAgent$"},
+		"env":     map[string]string{helperAgentEnv: mode},
+	}
+}
+
+type fixtureRun struct {
+	repo        string
+	artifactDir string
+	db          string
+	result      Result
+}
+
+func newFixtureRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":       ".venv\n",
+		"src/a.py":         "x = 1\n",
+		"tests/test_a.py":  "def test_x():\n    assert False\n",
+		".venv/bin/python": fakePython,
+	} {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=atqos", "-c", "user.email=atqos@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return repo
+}
+
+// runFixture runs atqos once over repo with the helper agent; overrides are
+// merged into the top level of the config.
+func runFixture(t *testing.T, repo string, overrides map[string]interface{}) fixtureRun {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := map[string]interface{}{
+		"coverage":      map[string]interface{}{"enabled": false},
+		"agents":        map[string]interface{}{"fixer": helperAgent("fix")},
+		"default_agent": "fixer",
+	}
+	for key, value := range overrides {
+		cfg[key] = value
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "atqos.json")
+	if err := os.WriteFile(configPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := fixtureRun{repo: repo, artifactDir: filepath.Join(dir, "artifacts"), db: filepath.Join(dir, "atqos.db")}
+	run.result, err = Command{RepoPath: repo, ArtifactDir: run.artifactDir, DBPath: run.db, ConfigPath: configPath}.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	return run
+}
+
+// attempts returns the run's attempts in the order they were made.
+func (r fixtureRun) attempts(t *testing.T) []core.AttemptRecord {
+	t.Helper()
+	ctx := context.Background()
+	storeDB, err := store.Open(r.db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storeDB.Close()
+	tasks, err := storeDB.ListTasks(ctx, r.result.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attempts []core.AttemptRecord
+	for _, task := range tasks {
+		taskAttempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attempts = append(attempts, taskAttempts...)
+	}
+	return attempts
+}
+
+type attemptSummary struct {
+	Turns   int         `json:"turns"`
+	Usage   agent.Usage `json:"usage"`
+	History []struct {
+		Validation struct {
+			Passed bool `json:"passed"`
+		} `json:"validation"`
+	} `json:"history"`
+}
+
+func summaryOf(t *testing.T, attempt core.AttemptRecord) attemptSummary {
+	t.Helper()
+	var summary attemptSummary
+	if err := json.Unmarshal([]byte(attempt.SummaryJSON), &summary); err != nil {
+		t.Fatalf("attempt %d summary: %v", attempt.ID, err)
+	}
+	return summary
+}
+
+func TestRepairLoopFeedsValidationFailuresBack(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		maxTurns int
+		statuses []string
+		turns    []int
+	}{
+		// Without a second turn the agent never sees the failure.
+		{name: "single turn", maxTurns: 1, statuses: []string{"failed", "failed"}, turns: []int{1, 1}},
+		{name: "repair turns", maxTurns: 3, statuses: []string{"succeeded"}, turns: []int{2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := runFixture(t, newFixtureRepo(t), map[string]interface{}{
+				"agents": map[string]interface{}{"fixer": helperAgent("repair")},
+				"repair": map[string]interface{}{"enabled": true, "max_turns": tc.maxTurns, "max_minutes": 5, "output_bytes": 4000},
+			})
+			attempts := run.attempts(t)
+			if len(attempts) != len(tc.statuses) {
+				t.Fatalf("%d attempts, want %d", len(attempts), len(tc.statuses))
+			}
+			for i, attempt := range attempts {
+				summary := summaryOf(t, attempt)
+				if attempt.Status != tc.statuses[i] || summary.Turns != tc.turns[i] {
+					t.Errorf("attempt %d = %s after %d turns, want %s after %d", i+1, attempt.Status, summary.Turns, tc.statuses[i], tc.turns[i])
+				}
+				if len(summary.History) != summary.Turns-1 {
+					t.Errorf("attempt %d records %d earlier turns, want %d", i+1, len(summary.History), summary.Turns-1)
+				}
+				for _, turn := range summary.History {
+					if turn.Validation.Passed {
+						t.Errorf("attempt %d repaired a turn that passed validation", i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAgentRoutesFallBackAfterFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		route  map[string]interface{}
+		agents []string
+	}{
+		{name: "fallback", route: map[string]interface{}{"tool": "pytest", "agent": "broken", "fallback": "fixer", "fallback_after": 1}, agents: []string{"broken", "fixer"}},
+		{name: "no fallback", route: map[string]interface{}{"tool": "pytest", "agent": "broken"}, agents: []string{"broken", "broken"}},
+		{name: "unmatched route", route: map[string]interface{}{"tool": "ruff", "agent": "broken"}, agents: []string{"fixer"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := runFixture(t, newFixtureRepo(t), map[string]interface{}{
+				"agents":       map[string]interface{}{"fixer": helperAgent("fix"), "broken": helperAgent("noop")},
+				"agent_routes": []interface{}{tc.route},
+			})
+			var agents []string
+			for _, attempt := range run.attempts(t) {
+				agents = append(agents, attempt.AgentName)
+			}
+			if !reflect.DeepEqual(agents, tc.agents) {
+				t.Fatalf("attempts by %v, want %v", agents, tc.agents)
+			}
+		})
+	}
+}
+
+func TestBudgetStopsRepairAndDispatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		budget   map[string]interface{}
+		exceeded bool
+		turns    int
+	}{
+		{name: "unlimited", budget: map[string]interface{}{}, turns: 2},
+		{name: "under budget", budget: map[string]interface{}{"max_tokens": 5000, "max_cost_usd": 1.0}, turns: 2},
+		{name: "tokens", budget: map[string]interface{}{"max_tokens": 1000}, exceeded: true, turns: 1},
+		{name: "cost", budget: map[string]interface{}{"max_cost_usd": 0.25}, exceeded: true, turns: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := runFixture(t, newFixtureRepo(t), map[string]interface{}{
+				"agents": map[string]interface{}{"fixer": helperAgent("repair")},
+				"repair": map[string]interface{}{"enabled": true, "max_turns": 3, "max_minutes": 5, "output_bytes": 4000},
+				"budget": tc.budget,
+			})
+			// A spent budget ends the attempt after the turn that spent it
+			// and keeps the failed task from being dispatched again.
+			attempts := run.attempts(t)
+			if len(attempts) != 1 {
+				t.Fatalf("%d attempts, want 1", len(attempts))
+			}
+			summary := summaryOf(t, attempts[0])
+			want := helperUsage
+			want.InputTokens *= tc.turns
+			want.OutputTokens *= tc.turns
+			want.CostUSD *= float64(tc.turns)
+			if summary.Turns != tc.turns || summary.Usage.Tokens() != want.Tokens() || summary.Usage.CostUSD != want.CostUSD {
+				t.Fatalf("attempt took %d turns using %+v, want %d turns using %+v", summary.Turns, summary.Usage, tc.turns, want)
+			}
+
+			data, err := os.ReadFile(filepath.Join(run.artifactDir, run.result.RunID, "summary.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report struct {
+				Usage engine.UsageReport `json:"usage"`
+			}
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatal(err)
+			}
+			if (report.Usage.BudgetExceeded != "") != tc.exceeded {
+				t.Errorf("budget exceeded = %q, want exceeded %v", report.Usage.BudgetExceeded, tc.exceeded)
+			}
+			if report.Usage.Total.Tokens() != want.Tokens() || report.Usage.Tasks[attempts[0].TaskID].Tokens() != want.Tokens() {
+				t.Errorf("run usage = %+v, want %d tokens for the run and its task", report.Usage, want.Tokens())
+			}
+		})
+	}
+}
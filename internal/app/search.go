@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"atqos/internal/core"
+	"atqos/internal/engine"
+	"atqos/internal/store"
+)
+
+const maxIndexedOutput = 1 << 20
+
+var outputKinds = map[string]bool{
+	"stdout": true,
+	"stderr": true,
+}
+
+type SearchCommand struct {
+	DBPath string
+	Query  string
+	RunID  string
+	Tool   string
+	Limit  int
+}
+
+func (c SearchCommand) Run(ctx context.Context) ([]store.SearchHit, error) {
+	if store.IsPath(c.DBPath) {
+		if _, err := os.Stat(c.DBPath); err != nil {
+			return nil, fmt.Errorf("open database: %w", err)
+		}
+	}
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return nil, err
+	}
+	defer storeDB.Close()
+	if err := storeDB.Init(ctx); err != nil {
+		return nil, err
+	}
+	return storeDB.Search(ctx, store.SearchQuery{
+		Text:  c.Query,
+		RunID: c.RunID,
+		Tool:  c.Tool,
+		Limit: c.Limit,
+	})
+}
+
+// indexOutputs adds the captured stdout and stderr of a run's commands,
+// including each attempt's validation commands, to the search index.
+// Output is truncated to keep the index bounded.
+func indexOutputs(ctx context.Context, storeDB store.Store, runID string) (int, error) {
+	records, err := storeDB.ListArtifacts(ctx, runID)
+	if err != nil {
+		return 0, err
+	}
+	var docs []store.SearchDocument
+	for _, record := range records {
+		if !outputKinds[record.Kind] {
+			continue
+		}
+		body, err := readPrefix(record.Path, maxIndexedOutput)
+		if err != nil || body == "" {
+			continue
+		}
+		var meta struct {
+			TaskID int64 `json:"task_id"`
+		}
+		_ = json.Unmarshal([]byte(record.MetaJSON), &meta)
+		docs = append(docs, store.SearchDocument{
+			RunID: runID,
+			Kind:  store.SearchOutput,
+			RefID: meta.TaskID,
+			Tool:  record.Tool,
+			Path:  record.Path,
+			Title: record.Tool + " " + record.Kind,
+			Body:  body,
+		})
+	}
+	validation, err := validationOutputs(ctx, storeDB, runID)
+	if err != nil {
+		return 0, err
+	}
+	docs = append(docs, validation...)
+	return len(docs), storeDB.IndexDocuments(ctx, docs)
+}
+
+// validationOutputs returns documents for the validation command logs
+// recorded in the summaries of a run's attempts, one per log and turn.
+func validationOutputs(ctx context.Context, storeDB store.Store, runID string) ([]store.SearchDocument, error) {
+	tasks, err := storeDB.ListTasks(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	var docs []store.SearchDocument
+	for _, task := range tasks {
+		attempts, err := storeDB.ListAttempts(ctx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, attempt := range attempts {
+			for _, log := range validationLogs(attempt.SummaryJSON) {
+				body, err := readPrefix(log.path, maxIndexedOutput)
+				if err != nil || body == "" {
+					continue
+				}
+				docs = append(docs, store.SearchDocument{
+					RunID: runID,
+					Kind:  store.SearchOutput,
+					RefID: task.ID,
+					Tool:  task.Tool,
+					Path:  log.path,
+					Title: fmt.Sprintf("%s validation %s", task.Tool, log.stream),
+					Body:  body,
+				})
+			}
+		}
+	}
+	return docs, nil
+}
+
+type validationLog struct {
+	path   string
+	stream string
+}
+
+func validationLogs(summaryJSON string) []validationLog {
+	var summary struct {
+		Validation engine.ValidationResult `json:"validation"`
+		History    []struct {
+			Validation engine.ValidationResult `json:"validation"`
+		} `json:"history"`
+	}
+	if json.Unmarshal([]byte(summaryJSON), &summary) != nil {
+		return nil
+	}
+	results := []engine.ValidationResult{summary.Validation}
+	for _, turn := range summary.History {
+		results = append(results, turn.Validation)
+	}
+	var logs []validationLog
+	for _, result := range results {
+		for _, command := range result.Commands {
+			if command.StdoutPath != "" {
+				logs = append(logs, validationLog{command.StdoutPath, "stdout"})
+			}
+			if command.StderrPath != "" {
+				logs = append(logs, validationLog{command.StderrPath, "stderr"})
+			}
+		}
+	}
+	return logs
+}
+
+func readPrefix(path string, limit int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(data), ""), nil
+}
+
+func indexRunOutputs(ctx context.Context, storeDB store.Store, logger core.EventLogger, runID string) {
+	if _, err := indexOutputs(ctx, storeDB, runID); err != nil && logger != nil {
+		_ = logger.Emit(core.Event{
+			RunID:     runID,
+			Level:     "warn",
+			EventType: core.EventSearchIndexFailed,
+			Payload: core.ErrorPayload{
+				Error: err.Error(),
+			},
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSearchFindsValidationOutput(t *testing.T) {
+	run := runFixture(t, newFixtureRepo(t), nil)
+
+	// The marker is printed only by the passing validation command, never
+	// by the baseline collection.
+	hits, err := SearchCommand{DBPath: run.db, Query: "zebra", RunID: run.result.RunID}.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("hits = %+v, want the validation stdout", hits)
+	}
+	hit := hits[0]
+	if !strings.HasSuffix(hit.Path, "command-1.stdout.log") || !strings.Contains(hit.Path, "attempt-") || hit.Tool != "pytest" || hit.RefID == 0 {
+		t.Fatalf("hit = %+v", hit)
+	}
+}
@@ -0,0 +1,508 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/eventlog"
+	"atqos/internal/store"
+)
+
+const (
+	watchFailures = 5
+	watchEvents   = 8
+)
+
+// WatchCommand follows a run's event log and polls the store, rendering a
+// live view until the run finishes or ctx is cancelled. On a terminal the
+// view is redrawn in place; otherwise events and status changes are
+// printed as plain lines.
+type WatchCommand struct {
+	DBPath      string
+	ArtifactDir string
+	Target      string
+	Interval    time.Duration
+	Out         io.Writer
+}
+
+func (c WatchCommand) Run(ctx context.Context) error {
+	path, err := eventLogPath(c.ArtifactDir, c.Target)
+	if err != nil {
+		return err
+	}
+	storeDB, err := store.Open(c.DBPath, "")
+	if err != nil {
+		return err
+	}
+	defer storeDB.Close()
+
+	out := c.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	tty := isTerminal(out)
+	state := newWatchState()
+	tail := &logFollower{path: path}
+	defer tail.Close()
+	var plain *eventlog.Console
+	if !tty {
+		plain = eventlog.NewConsole(out, eventlog.ColorNever)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := tail.poll(func(record eventlog.Record) {
+			state.apply(record)
+			if plain != nil {
+				state.printPlain(out, plain, record)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if state.runID != "" {
+			state.poll(ctx, storeDB)
+		}
+		if tty {
+			io.WriteString(out, "\x1b[H\x1b[2J")
+			state.render(out, time.Now())
+		} else {
+			state.printStatus(out)
+		}
+		if state.finished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// logFollower reads the lines appended to an event log since the last
+// poll, holding back a trailing partial line until it is complete. A log
+// that is replaced (rotated) is read to its end before following the new
+// file; one that shrinks (truncated) is followed from the start.
+type logFollower struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	lineNo  int
+}
+
+func (f *logFollower) poll(fn func(eventlog.Record)) error {
+	if f.file != nil {
+		info, err := os.Stat(f.path)
+		switch {
+		case os.IsNotExist(err) || err == nil && !os.SameFile(info, f.info):
+			if err := f.read(fn); err != nil {
+				return err
+			}
+			f.Close()
+		case err != nil:
+			return err
+		case info.Size() < f.offset:
+			f.Close()
+		}
+	}
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		f.file, f.info = file, info
+	}
+	return f.read(fn)
+}
+
+func (f *logFollower) read(fn func(eventlog.Record)) error {
+	data, err := io.ReadAll(f.file)
+	if err != nil {
+		return err
+	}
+	f.offset += int64(len(data))
+	f.partial = append(f.partial, data...)
+	for {
+		i := bytes.IndexByte(f.partial, '\n')
+		if i < 0 {
+			return nil
+		}
+		line := bytes.TrimRight(f.partial[:i], "\r")
+		f.partial = f.partial[i+1:]
+		f.lineNo++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record, err := eventlog.DecodeRecord(line, f.lineNo)
+		if err != nil {
+			return fmt.Errorf("follow %s: %w", f.path, err)
+		}
+		fn(record)
+	}
+}
+
+// Close closes the followed file; the next poll reopens the log from its
+// start.
+func (f *logFollower) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.info, f.offset, f.partial, f.lineNo = nil, nil, 0, nil, 0
+	return err
+}
+
+type watchWorker struct {
+	taskID int64
+	since  time.Time
+}
+
+type watchAttempt struct {
+	id      int64
+	taskID  int64
+	agent   string
+	started time.Time
+}
+
+type watchState struct {
+	runID         string
+	runStatus     string
+	started       time.Time
+	finishedAt    time.Time
+	lastEvent     time.Time
+	events        int
+	maxWorkers    int
+	checkpointMin int
+	ended         bool
+	terminalPolls int
+
+	workers     map[string]*watchWorker
+	taskWorker  map[int64]string
+	taskTitles  map[int64]string
+	attempts    map[int64]*watchAttempt
+	failures    []string
+	recent      []string
+	counts      map[string]int
+	lastCounts  string
+	checkpoints int
+	checkpoint  time.Time
+	lastCheck   time.Time
+}
+
+func newWatchState() *watchState {
+	return &watchState{
+		workers:    make(map[string]*watchWorker),
+		taskWorker: make(map[int64]string),
+		taskTitles: make(map[int64]string),
+		attempts:   make(map[int64]*watchAttempt),
+		counts:     make(map[string]int),
+	}
+}
+
+func (s *watchState) apply(record eventlog.Record) {
+	s.events++
+	s.lastEvent = record.TS
+	if s.runID == "" {
+		s.runID = record.RunID
+	}
+	if s.started.IsZero() {
+		s.started = record.TS
+	}
+
+	switch payload := record.Payload.(type) {
+	case *core.RunCreatedPayload:
+		s.runStatus = payload.Status
+		if !payload.StartedAt.IsZero() {
+			s.started = payload.StartedAt
+		}
+		cfg := config.Default()
+		if json.Unmarshal([]byte(payload.Config), &cfg) == nil {
+			s.maxWorkers = cfg.MaxWorkers
+			s.checkpointMin = cfg.CheckpointMins
+		}
+	case *core.RunStatusChangedPayload:
+		s.runStatus = payload.Status
+		s.finishedAt = record.TS
+	case *core.RunFinishedPayload:
+		s.runStatus = payload.Status
+		s.finishedAt = record.TS
+		s.ended = true
+	case *core.ErrorPayload:
+		s.fail(record, record.EventType+": "+payload.Error)
+		if record.EventType == core.EventRunFailed {
+			s.runStatus = core.RunStatusFailed
+			s.finishedAt = record.TS
+			s.ended = true
+		}
+	case *core.TaskCreatedPayload:
+		s.taskTitles[record.TaskID] = payload.Title
+	case *core.TaskClaimedPayload:
+		s.workers[payload.WorkerID] = &watchWorker{taskID: record.TaskID, since: record.TS}
+		s.taskWorker[record.TaskID] = payload.WorkerID
+	case *core.TaskStatusChangedPayload:
+		if workerID, ok := s.taskWorker[record.TaskID]; ok && payload.Status != "running" {
+			delete(s.taskWorker, record.TaskID)
+			if worker := s.workers[workerID]; worker != nil && worker.taskID == record.TaskID {
+				worker.taskID = 0
+				worker.since = record.TS
+			}
+		}
+		if payload.Status == "blocked" {
+			s.fail(record, fmt.Sprintf("task %d blocked", record.TaskID))
+		}
+	case *core.AttemptStartedPayload:
+		s.attempts[record.AttemptID] = &watchAttempt{id: record.AttemptID, taskID: record.TaskID, agent: payload.AgentName, started: record.TS}
+	case *core.AttemptFinishedPayload:
+		attempt := s.attempts[record.AttemptID]
+		delete(s.attempts, record.AttemptID)
+		if payload.Status != "succeeded" && attempt != nil {
+			s.fail(record, fmt.Sprintf("task %d attempt %d %s (%s)", attempt.taskID, attempt.id, payload.Status, attempt.agent))
+		}
+	case *core.EscalationPayload:
+		s.fail(record, fmt.Sprintf("task %d escalated: %s", record.TaskID, payload.Reason))
+	}
+
+	switch record.EventType {
+	case core.EventCheckpointStarted:
+		s.checkpoint = record.TS
+	case core.EventCheckpointFinished:
+		s.checkpoint = time.Time{}
+		s.checkpoints++
+		s.lastCheck = record.TS
+	}
+
+	if record.Level != eventlog.LevelDebug {
+		s.recent = appendLimited(s.recent, watchLine(record), watchEvents)
+	}
+}
+
+func (s *watchState) fail(record eventlog.Record, message string) {
+	s.failures = appendLimited(s.failures, record.TS.Local().Format("15:04:05")+" "+message, watchFailures)
+}
+
+// poll refreshes task counts and the run status from the store; the event
+// log stays the source for everything else.
+func (s *watchState) poll(ctx context.Context, storeDB store.Store) {
+	if run, err := storeDB.GetRun(ctx, s.runID); err == nil {
+		s.runStatus = run.Status
+		if run.Status != core.RunStatusRunning {
+			s.terminalPolls++
+		}
+		if !run.FinishedAt.IsZero() && s.finishedAt.IsZero() {
+			s.finishedAt = run.FinishedAt
+		}
+	}
+	tasks, err := storeDB.ListTasks(ctx, s.runID)
+	if err != nil {
+		return
+	}
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		counts[task.Status]++
+		if _, ok := s.taskTitles[task.ID]; !ok {
+			s.taskTitles[task.ID] = task.Title
+		}
+	}
+	s.counts = counts
+}
+
+// finished reports whether the run has ended: its final event has been
+// read, or the store has shown it finished for more than one poll, which
+// covers logs that end without a final event.
+func (s *watchState) finished() bool {
+	return s.ended || s.terminalPolls > 1
+}
+
+func (s *watchState) render(w io.Writer, now time.Time) {
+	end := now
+	if s.finished() && !s.finishedAt.IsZero() {
+		end = s.finishedAt
+	}
+	status := s.runStatus
+	if status == "" {
+		status = "waiting for events"
+	}
+	fmt.Fprintf(w, "run %s  %s  elapsed %s  events %d (last %s ago)\n\n", s.runID, status, watchDuration(end.Sub(s.started)), s.events, watchDuration(now.Sub(s.lastEvent)))
+	fmt.Fprintf(w, "tasks     %s\n", s.countLine())
+
+	fmt.Fprintf(w, "workers   %d active", len(s.taskWorker))
+	if s.maxWorkers > 0 {
+		fmt.Fprintf(w, " of %d", s.maxWorkers)
+	}
+	fmt.Fprintln(w)
+	ids := make([]string, 0, len(s.workers))
+	for id := range s.workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		worker := s.workers[id]
+		if worker.taskID == 0 {
+			fmt.Fprintf(w, "  %-10s idle %s\n", id, watchDuration(now.Sub(worker.since)))
+			continue
+		}
+		fmt.Fprintf(w, "  %-10s task %-5d %-8s %s\n", id, worker.taskID, watchDuration(now.Sub(worker.since)), watchTruncate(s.taskTitles[worker.taskID], 60))
+	}
+
+	fmt.Fprintf(w, "attempts  %d running\n", len(s.attempts))
+	attempts := make([]*watchAttempt, 0, len(s.attempts))
+	for _, attempt := range s.attempts {
+		attempts = append(attempts, attempt)
+	}
+	sort.Slice(attempts, func(i, j int) bool { return attempts[i].id < attempts[j].id })
+	for _, attempt := range attempts {
+		fmt.Fprintf(w, "  attempt %-5d task %-5d %-12s %s\n", attempt.id, attempt.taskID, attempt.agent, watchDuration(now.Sub(attempt.started)))
+	}
+
+	fmt.Fprintf(w, "checkpts  %s\n", s.checkpointLine(now))
+
+	fmt.Fprintln(w, "\nrecent failures")
+	if len(s.failures) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, failure := range s.failures {
+		fmt.Fprintf(w, "  %s\n", watchTruncate(failure, 120))
+	}
+	fmt.Fprintln(w, "\nrecent events")
+	for _, line := range s.recent {
+		fmt.Fprintf(w, "  %s\n", watchTruncate(line, 120))
+	}
+}
+
+func (s *watchState) countLine() string {
+	if len(s.counts) == 0 {
+		return "none"
+	}
+	statuses := make([]string, 0, len(s.counts))
+	for status := range s.counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	parts := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%s %d", status, s.counts[status]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (s *watchState) checkpointLine(now time.Time) string {
+	line := fmt.Sprintf("%d finished", s.checkpoints)
+	if !s.checkpoint.IsZero() {
+		return line + fmt.Sprintf(", running for %s", watchDuration(now.Sub(s.checkpoint)))
+	}
+	if !s.lastCheck.IsZero() {
+		line += fmt.Sprintf(", last %s ago", watchDuration(now.Sub(s.lastCheck)))
+	}
+	if s.checkpointMin > 0 && !s.finished() {
+		last := s.lastCheck
+		if last.IsZero() {
+			last = s.started
+		}
+		next := last.Add(time.Duration(s.checkpointMin) * time.Minute).Sub(now)
+		if next < 0 {
+			next = 0
+		}
+		line += fmt.Sprintf(", next due in %s", watchDuration(next))
+	}
+	return line
+}
+
+func (s *watchState) printPlain(w io.Writer, console *eventlog.Console, record eventlog.Record) {
+	if record.Level != eventlog.LevelDebug {
+		_ = console.Emit(record.Event)
+		return
+	}
+	switch payload := record.Payload.(type) {
+	case *core.TaskClaimedPayload:
+		fmt.Fprintf(w, "%s task %d claimed by %s\n", record.TS.Local().Format("15:04:05.000"), record.TaskID, payload.WorkerID)
+	case *core.AttemptStartedPayload:
+		fmt.Fprintf(w, "%s attempt %d started for task %d (%s)\n", record.TS.Local().Format("15:04:05.000"), record.AttemptID, record.TaskID, payload.AgentName)
+	case *core.AttemptFinishedPayload:
+		fmt.Fprintf(w, "%s attempt %d %s\n", record.TS.Local().Format("15:04:05.000"), record.AttemptID, payload.Status)
+	case *core.TaskStatusChangedPayload:
+		fmt.Fprintf(w, "%s task %d %s\n", record.TS.Local().Format("15:04:05.000"), record.TaskID, payload.Status)
+	}
+}
+
+// printStatus prints the task counts whenever they change.
+func (s *watchState) printStatus(w io.Writer) {
+	line := fmt.Sprintf("status %s: tasks %s; %d attempts running", s.runStatus, s.countLine(), len(s.attempts))
+	if line == s.lastCounts {
+		return
+	}
+	s.lastCounts = line
+	fmt.Fprintln(w, line)
+}
+
+func watchLine(record eventlog.Record) string {
+	line := record.TS.Local().Format("15:04:05") + " " + record.EventType
+	if record.Tool != "" {
+		line += " tool=" + record.Tool
+	}
+	if record.TaskID != 0 {
+		line += fmt.Sprintf(" task=%d", record.TaskID)
+	}
+	if len(record.RawPayload) > 0 {
+		line += " " + string(record.RawPayload)
+	}
+	return line
+}
+
+func watchDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return d.Truncate(time.Second).String()
+}
+
+// watchTruncate shortens s to n characters.
+func watchTruncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
+
+func appendLimited(list []string, value string, limit int) []string {
+	list = append(list, value)
+	if len(list) > limit {
+		list = list[len(list)-limit:]
+	}
+	return list
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
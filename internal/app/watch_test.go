@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"atqos/internal/core"
+	"atqos/internal/eventlog"
+)
+
+func appendLog(t *testing.T, path string, flag int, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|flag, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventLine(t *testing.T, seq int64) string {
+	t.Helper()
+	line, err := json.Marshal(core.Event{Schema: 1, Seq: seq, RunID: "run-1", Level: "info", EventType: "test_event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line) + "\n"
+}
+
+func TestLogFollowerFollowsTruncationAndRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	f := &logFollower{path: path}
+	defer f.Close()
+
+	var seqs []int64
+	collect := func(record eventlog.Record) { seqs = append(seqs, record.Seq) }
+	partial := eventLine(t, 3)
+
+	steps := []struct {
+		name   string
+		change func()
+		want   []int64
+	}{
+		{"missing log", func() {}, nil},
+		{"partial line held back", func() {
+			appendLog(t, path, os.O_APPEND, eventLine(t, 1)+eventLine(t, 2)+partial[:10])
+		}, []int64{1, 2}},
+		{"partial line completed", func() {
+			appendLog(t, path, os.O_APPEND, partial[10:])
+		}, []int64{3}},
+		{"rotated", func() {
+			appendLog(t, path, os.O_APPEND, eventLine(t, 4))
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+			appendLog(t, path, 0, eventLine(t, 5))
+		}, []int64{4, 5}},
+		{"truncated", func() {
+			appendLog(t, path, os.O_TRUNC, "")
+		}, nil},
+		{"written from the start", func() {
+			appendLog(t, path, os.O_APPEND, eventLine(t, 1))
+		}, []int64{1}},
+		{"rotated away", func() {
+			appendLog(t, path, os.O_APPEND, eventLine(t, 2))
+			if err := os.Rename(path, path+".2"); err != nil {
+				t.Fatal(err)
+			}
+		}, []int64{2}},
+	}
+	for _, step := range steps {
+		step.change()
+		seqs = nil
+		if err := f.poll(collect); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(seqs, step.want) {
+			t.Fatalf("%s: read %v, want %v", step.name, seqs, step.want)
+		}
+	}
+
+	if err := f.Close(); err != nil || f.file != nil {
+		t.Fatalf("Close = %v, file still open: %v", err, f.file != nil)
+	}
+}
+
+func TestWatchTruncateKeepsCharacters(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"too long", 3, "too..."},
+		{"héllo wörld", 2, "hé..."},
+		{"日本語のテスト", 3, "日本語..."},
+	} {
+		got := watchTruncate(tc.in, tc.n)
+		if got != tc.want || !utf8.ValidString(got) {
+			t.Errorf("watchTruncate(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestWatchReturnsWhenRunFinishes(t *testing.T) {
+	run := runFixture(t, newFixtureRepo(t), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := WatchCommand{DBPath: run.db, ArtifactDir: run.artifactDir, Target: run.result.RunID, Interval: 10 * time.Millisecond, Out: &out}
+	if err := cmd.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("watch did not return after the run finished")
+	}
+	if !strings.Contains(out.String(), "succeeded 1") {
+		t.Fatalf("watch output has no final status:\n%s", out.String())
+	}
+}
@@ -0,0 +1,395 @@
+package artifacts
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	ErrNotFound = errors.New("blob not found")
+	ErrCorrupt  = errors.New("blob hash mismatch")
+)
+
+var extensions = map[string]string{
+	CompressionNone: "",
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
+type Store interface {
+	Put(r io.Reader) (Blob, error)
+	Open(sum string) (io.ReadCloser, error)
+}
+
+type Blob struct {
+	SHA256      string
+	Size        int64
+	StoredSize  int64
+	Compression string
+	Path        string
+	Existed     bool
+}
+
+// CAS is a content-addressed blob store. Several databases may share one
+// root; each records the blobs it stores under .owners/<owner> so that gc
+// for one database only removes blobs no other database claims.
+type CAS struct {
+	Root        string
+	Compression string
+	Owner       string
+}
+
+func NewCAS(root string, compression string) (*CAS, error) {
+	if compression == "" {
+		compression = CompressionNone
+	}
+	if _, ok := extensions[compression]; !ok {
+		return nil, fmt.Errorf("unknown artifact compression %q", compression)
+	}
+	return &CAS{Root: root, Compression: compression}, nil
+}
+
+// WithOwner makes Put claim every blob it stores for owner.
+func (c *CAS) WithOwner(owner string) *CAS {
+	c.Owner = owner
+	return c
+}
+
+func (c *CAS) Put(r io.Reader) (Blob, error) {
+	if err := os.MkdirAll(c.Root, 0o755); err != nil {
+		return Blob{}, err
+	}
+	tmp, err := os.CreateTemp(c.Root, ".blob-")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w, err := compressor(tmp, c.Compression)
+	if err != nil {
+		return Blob{}, err
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hasher), r)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return Blob{}, fmt.Errorf("write blob: %w", err)
+	}
+
+	blob := Blob{
+		SHA256:      hex.EncodeToString(hasher.Sum(nil)),
+		Size:        size,
+		Compression: c.Compression,
+	}
+	if err := c.claim(blob.SHA256); err != nil {
+		return Blob{}, err
+	}
+	if path, compression, err := c.locate(blob.SHA256); err == nil {
+		blob.Path = path
+		blob.Compression = compression
+		blob.Existed = true
+		blob.StoredSize = fileSize(path)
+		return blob, nil
+	}
+
+	blob.Path = c.path(blob.SHA256, c.Compression)
+	if err := os.MkdirAll(filepath.Dir(blob.Path), 0o755); err != nil {
+		return Blob{}, err
+	}
+	if err := os.Rename(tmp.Name(), blob.Path); err != nil {
+		return Blob{}, fmt.Errorf("store blob: %w", err)
+	}
+	_ = os.Chmod(blob.Path, 0o444)
+	blob.StoredSize = fileSize(blob.Path)
+	return blob, nil
+}
+
+func (c *CAS) PutFile(path string) (Blob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Blob{}, err
+	}
+	defer f.Close()
+	return c.Put(f)
+}
+
+func (c *CAS) Has(sum string) bool {
+	_, _, err := c.locate(sum)
+	return err == nil
+}
+
+func (c *CAS) Open(sum string) (io.ReadCloser, error) {
+	path, compression, err := c.locate(sum)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompressor(f, compression)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &verifyingReader{r: r, closer: f, want: sum, hash: sha256.New()}, nil
+}
+
+func (c *CAS) ReadFile(sum string) ([]byte, error) {
+	r, err := c.Open(sum)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (c *CAS) Link(sum string, dst string) error {
+	path, compression, err := c.locate(sum)
+	if err != nil {
+		return err
+	}
+	if compression != CompressionNone {
+		return fmt.Errorf("blob %s is stored with %s compression", sum, compression)
+	}
+	tmp := dst + ".cas-link"
+	_ = os.Remove(tmp)
+	if err := os.Link(path, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func (c *CAS) List() ([]string, error) {
+	var sums []string
+	err := filepath.WalkDir(c.Root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == c.Root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() && path != c.Root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			sums = append(sums, blobSum(entry.Name()))
+		}
+		return nil
+	})
+	return sums, err
+}
+
+func (c *CAS) Size(sum string) int64 {
+	path, _, err := c.locate(sum)
+	if err != nil {
+		return 0
+	}
+	return fileSize(path)
+}
+
+func (c *CAS) Remove(sum string) (int64, error) {
+	var freed int64
+	for compression := range extensions {
+		path := c.path(sum, compression)
+		size := fileSize(path)
+		if err := os.Remove(path); err == nil {
+			freed += size
+		} else if !os.IsNotExist(err) {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
+
+// Owned lists the blobs claimed by c's owner.
+func (c *CAS) Owned() ([]string, error) {
+	if c.Owner == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(c.ownerDir(c.Owner))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		sums = append(sums, entry.Name())
+	}
+	return sums, nil
+}
+
+// Shared reports whether an owner other than c's claims sum.
+func (c *CAS) Shared(sum string) (bool, error) {
+	owners, err := os.ReadDir(filepath.Join(c.Root, ownersDir))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, owner := range owners {
+		if owner.Name() == c.Owner {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(c.ownerDir(owner.Name()), sum)); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Release drops c's owner's claim on sum and removes the blob once no other
+// owner claims it, returning the bytes freed.
+func (c *CAS) Release(sum string) (int64, error) {
+	if err := os.Remove(filepath.Join(c.ownerDir(c.Owner), sum)); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	shared, err := c.Shared(sum)
+	if err != nil || shared {
+		return 0, err
+	}
+	return c.Remove(sum)
+}
+
+const ownersDir = ".owners"
+
+func (c *CAS) ownerDir(owner string) string {
+	return filepath.Join(c.Root, ownersDir, owner)
+}
+
+func (c *CAS) claim(sum string) error {
+	if c.Owner == "" {
+		return nil
+	}
+	dir := c.ownerDir(c.Owner)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("claim blob: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, sum), nil, 0o644); err != nil {
+		return fmt.Errorf("claim blob: %w", err)
+	}
+	return nil
+}
+
+func (c *CAS) path(sum string, compression string) string {
+	prefix := "xx"
+	if len(sum) >= 2 {
+		prefix = sum[:2]
+	}
+	return filepath.Join(c.Root, prefix, sum+extensions[compression])
+}
+
+func (c *CAS) locate(sum string) (string, string, error) {
+	if len(sum) != sha256.Size*2 {
+		return "", "", fmt.Errorf("invalid blob hash %q", sum)
+	}
+	for _, compression := range []string{c.Compression, CompressionNone, CompressionZstd, CompressionGzip} {
+		path := c.path(sum, compression)
+		if _, err := os.Stat(path); err == nil {
+			return path, compression, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s: %w", sum, ErrNotFound)
+}
+
+func blobSum(name string) string {
+	for _, ext := range extensions {
+		if ext != "" && strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+func compressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+func decompressor(r io.Reader, compression string) (io.Reader, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	default:
+		return r, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type verifyingReader struct {
+	r      io.Reader
+	closer io.Closer
+	want   string
+	hash   hash.Hash
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF {
+		if got := hex.EncodeToString(v.hash.Sum(nil)); got != v.want {
+			return n, fmt.Errorf("%w: want %s, got %s", ErrCorrupt, v.want, got)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	if c, ok := v.r.(io.Closer); ok && c != v.closer {
+		_ = c.Close()
+	}
+	return v.closer.Close()
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
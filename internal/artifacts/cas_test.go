@@ -0,0 +1,134 @@
+package artifacts
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atqos/internal/core"
+)
+
+var compressions = []string{CompressionNone, CompressionGzip, CompressionZstd}
+
+func TestCASRoundTripsBlobs(t *testing.T) {
+	content := []byte(strings.Repeat("collected output\n", 100))
+	for _, compression := range compressions {
+		t.Run(compression, func(t *testing.T) {
+			cas, err := NewCAS(t.TempDir(), compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cas.WithOwner("db")
+			blob, err := cas.Put(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blob.Existed || blob.Size != int64(len(content)) || blob.Compression != compression {
+				t.Fatalf("blob = %+v", blob)
+			}
+			again, err := cas.Put(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !again.Existed || again.Path != blob.Path {
+				t.Fatalf("second put not deduplicated: %+v", again)
+			}
+			listed, err := cas.List()
+			if err != nil || len(listed) != 1 || listed[0] != blob.SHA256 {
+				t.Fatalf("List = %v, %v, want only the blob", listed, err)
+			}
+			owned, err := cas.Owned()
+			if err != nil || len(owned) != 1 || owned[0] != blob.SHA256 {
+				t.Fatalf("Owned = %v, %v", owned, err)
+			}
+			got, err := cas.ReadFile(blob.SHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Fatal("blob content differs")
+			}
+		})
+	}
+}
+
+func TestCASDetectsCorruptBlob(t *testing.T) {
+	for _, compression := range compressions {
+		t.Run(compression, func(t *testing.T) {
+			cas, err := NewCAS(t.TempDir(), compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := cas.Put(strings.NewReader("original"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Replace the blob with validly encoded but different content.
+			var tampered bytes.Buffer
+			w, err := compressor(&tampered, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("tampered"))
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(blob.Path, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(blob.Path, tampered.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := cas.ReadFile(blob.SHA256); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("ReadFile error = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
+
+func TestReadArtifactVerifiesContent(t *testing.T) {
+	cas, err := NewCAS(filepath.Join(t.TempDir(), "cas"), CompressionZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := os.WriteFile(path, []byte("<testsuite/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := FileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := core.ArtifactRecord{Path: path, SHA256: sum}
+
+	if got, err := ReadArtifact(cas, record); err != nil || string(got) != "<testsuite/>" {
+		t.Fatalf("ReadArtifact = %q, %v", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte("<testsuite tests=\"1\"/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadArtifact(cas, record); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("modified artifact error = %v, want ErrCorrupt", err)
+	}
+
+	// Once the workspace copy is gone the artifact is served from the store.
+	if _, err := cas.Put(strings.NewReader("<testsuite/>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadArtifact(cas, record); err != nil || string(got) != "<testsuite/>" {
+		t.Fatalf("ReadArtifact from store = %q, %v", got, err)
+	}
+
+	record.SHA256 = strings.Repeat("0", 64)
+	if _, err := ReadArtifact(cas, record); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown blob error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,108 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"atqos/internal/core"
+)
+
+type IngestReport struct {
+	Files       int   `json:"files"`
+	Deduped     int   `json:"deduped"`
+	Skipped     int   `json:"skipped"`
+	Bytes       int64 `json:"bytes"`
+	StoredBytes int64 `json:"stored_bytes"`
+}
+
+func Ingest(ctx context.Context, cas *CAS, records []core.ArtifactRecord) (IngestReport, error) {
+	var report IngestReport
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if seen[record.Path] {
+			continue
+		}
+		seen[record.Path] = true
+		info, err := os.Lstat(record.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			report.Skipped++
+			continue
+		}
+
+		blob, err := cas.PutFile(record.Path)
+		if err != nil {
+			return report, fmt.Errorf("ingest %s: %w", record.Path, err)
+		}
+		if record.SHA256 != "" && record.SHA256 != blob.SHA256 {
+			// The file changed after it was recorded; keep it where it is.
+			report.Skipped++
+			continue
+		}
+
+		if record.SHA256 == "" && blob.Compression != CompressionNone {
+			// Without a recorded hash the path is the only way back to the blob.
+			report.Skipped++
+			continue
+		}
+
+		report.Files++
+		report.Bytes += blob.Size
+		if blob.Existed {
+			report.Deduped++
+		} else {
+			report.StoredBytes += blob.StoredSize
+		}
+		if blob.Compression == CompressionNone {
+			err = cas.Link(blob.SHA256, record.Path)
+		} else {
+			err = os.Remove(record.Path)
+		}
+		if err != nil {
+			return report, fmt.Errorf("alias %s: %w", record.Path, err)
+		}
+	}
+	return report, nil
+}
+
+func Resolve(cas *CAS, record core.ArtifactRecord) (io.ReadCloser, error) {
+	f, err := os.Open(record.Path)
+	if err == nil {
+		if record.SHA256 == "" {
+			return f, nil
+		}
+		return &verifyingReader{r: f, closer: f, want: record.SHA256, hash: sha256.New()}, nil
+	}
+	if !os.IsNotExist(err) || cas == nil || record.SHA256 == "" {
+		return nil, err
+	}
+	return cas.Open(record.SHA256)
+}
+
+func ReadArtifact(cas *CAS, record core.ArtifactRecord) ([]byte, error) {
+	r, err := Resolve(cas, record)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,251 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FormatVersion = 1
+	ManifestName  = "manifest.json"
+	FilesDir      = "files"
+)
+
+var ErrInvalid = errors.New("invalid bundle")
+
+type Manifest struct {
+	Version      int       `json:"version"`
+	RunID        string    `json:"run_id"`
+	ArtifactRoot string    `json:"artifact_root"`
+	CreatedAt    time.Time `json:"created_at"`
+	Entries      []Entry   `json:"entries"`
+	Missing      []string  `json:"missing,omitempty"`
+}
+
+type Entry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+type Writer struct {
+	file     *os.File
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	manifest Manifest
+	names    map[string]bool
+}
+
+func Create(dst string, runID string, artifactRoot string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(dst)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &Writer{
+		file: file,
+		gz:   gz,
+		tw:   tar.NewWriter(gz),
+		manifest: Manifest{
+			Version:      FormatVersion,
+			RunID:        runID,
+			ArtifactRoot: artifactRoot,
+			CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		},
+		names: make(map[string]bool),
+	}, nil
+}
+
+func (w *Writer) AddJSON(name string, payload interface{}) error {
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", name, err)
+	}
+	return w.add(name, data)
+}
+
+func (w *Writer) AddFile(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	return w.add(path.Join(FilesDir, name), data)
+}
+
+func (w *Writer) AddMissing(name string) {
+	w.manifest.Missing = append(w.manifest.Missing, name)
+}
+
+func (w *Writer) Has(name string) bool {
+	return w.names[path.Join(FilesDir, name)]
+}
+
+func (w *Writer) add(name string, data []byte) error {
+	if w.names[name] {
+		return nil
+	}
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: w.manifest.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	w.names[name] = true
+	w.manifest.Entries = append(w.manifest.Entries, Entry{
+		Name:   name,
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   int64(len(data)),
+	})
+	return nil
+}
+
+func (w *Writer) Close() error {
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err == nil {
+		err = w.tw.WriteHeader(&tar.Header{
+			Name:    ManifestName,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: w.manifest.CreatedAt,
+		})
+	}
+	if err == nil {
+		_, err = w.tw.Write(data)
+	}
+	if err == nil {
+		err = w.tw.Close()
+	}
+	if err == nil {
+		err = w.gz.Close()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (w *Writer) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+}
+
+type Bundle struct {
+	Dir      string
+	Manifest Manifest
+}
+
+// Extract unpacks the archive into dir and checks every entry against the
+// manifest before returning.
+func Extract(src string, dir string) (*Bundle, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	defer gz.Close()
+
+	sums := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%w: unsafe entry %q", ErrInvalid, header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+		hasher := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, hasher), tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", name, err)
+		}
+		sums[name] = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	b := &Bundle{Dir: dir}
+	if err := b.ReadJSON(ManifestName, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if b.Manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d is newer than supported %d", ErrInvalid, b.Manifest.Version, FormatVersion)
+	}
+	for _, entry := range b.Manifest.Entries {
+		got, ok := sums[entry.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s listed in manifest but missing", ErrInvalid, entry.Name)
+		}
+		if got != entry.SHA256 {
+			return nil, fmt.Errorf("%w: %s hash mismatch: want %s, got %s", ErrInvalid, entry.Name, entry.SHA256, got)
+		}
+	}
+	return b, nil
+}
+
+func (b *Bundle) ReadJSON(name string, out interface{}) error {
+	data, err := os.ReadFile(filepath.Join(b.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (b *Bundle) Files() []Entry {
+	var files []Entry
+	prefix := FilesDir + "/"
+	for _, entry := range b.Manifest.Entries {
+		if strings.HasPrefix(entry.Name, prefix) {
+			files = append(files, Entry{
+				Name:   strings.TrimPrefix(entry.Name, prefix),
+				SHA256: entry.SHA256,
+				Size:   entry.Size,
+			})
+		}
+	}
+	return files
+}
+
+func (b *Bundle) FilePath(name string) string {
+	return filepath.Join(b.Dir, FilesDir, filepath.FromSlash(name))
+}
@@ -6,23 +6,155 @@ import (
 )
 
 type Config struct {
-	MaxWorkers      int            `json:"max_workers"`
-	MaxAgentWorkers int            `json:"max_agent_workers"`
-	RetryCap        int            `json:"retry_cap"`
-	CheckpointMins  int            `json:"checkpoint_minutes"`
-	AllowedPaths    []string       `json:"allowed_paths"`
-	GitStrategy     string         `json:"git_strategy"`
-	Pytest          PluginConfig   `json:"pytest"`
-	Coverage        CoverageConfig `json:"coverage"`
+	MaxWorkers      int                    `json:"max_workers"`
+	MaxAgentWorkers int                    `json:"max_agent_workers"`
+	RetryCap        int                    `json:"retry_cap"`
+	CheckpointMins  int                    `json:"checkpoint_minutes"`
+	AllowedPaths    []string               `json:"allowed_paths"`
+	GitStrategy     string                 `json:"git_strategy"`
+	IncludeDirty    bool                   `json:"include_uncommitted"`
+	Conflicts       string                 `json:"conflict_granularity"`
+	Store           StoreConfig            `json:"store"`
+	Copy            CopyConfig             `json:"copy"`
+	Repair          RepairConfig           `json:"repair"`
+	Speculative     SpeculativeConfig      `json:"speculative"`
+	Budget          BudgetConfig           `json:"budget"`
+	Followups       FollowupConfig         `json:"followups"`
+	Artifacts       ArtifactConfig         `json:"artifacts"`
+	Export          ExportConfig           `json:"export"`
+	Retention       RetentionConfig        `json:"retention"`
+	Events          EventsConfig           `json:"events"`
+	Agents          map[string]AgentConfig `json:"agents,omitempty"`
+	AgentRoutes     []AgentRoute           `json:"agent_routes,omitempty"`
+	DefaultAgent    string                 `json:"default_agent,omitempty"`
+	Pytest          PluginConfig           `json:"pytest"`
+	Coverage        CoverageConfig         `json:"coverage"`
 }
 
 type PluginConfig struct {
 	Enabled bool `json:"enabled"`
 }
 
+type AgentConfig struct {
+	Type           string            `json:"type"`
+	Command        []string          `json:"command,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	Concurrency    int               `json:"concurrency,omitempty"`
+	BaseURL        string            `json:"base_url,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	APIKeyEnv      string            `json:"api_key_env,omitempty"`
+	MaxTurns       int               `json:"max_turns,omitempty"`
+	InputCost      float64           `json:"input_cost_per_mtok,omitempty"`
+	OutputCost     float64           `json:"output_cost_per_mtok,omitempty"`
+	Protocols      []int             `json:"protocol_versions,omitempty"`
+	Replay         *ReplayConfig     `json:"replay,omitempty"`
+}
+
+type ReplayConfig struct {
+	Mode        string `json:"mode"`
+	CassetteDir string `json:"cassette_dir"`
+}
+
+type AgentRoute struct {
+	TaskType      string `json:"task_type,omitempty"`
+	Tool          string `json:"tool,omitempty"`
+	Severity      string `json:"severity,omitempty"`
+	Agent         string `json:"agent"`
+	Fallback      string `json:"fallback,omitempty"`
+	FallbackAfter int    `json:"fallback_after,omitempty"`
+}
+
+type StoreConfig struct {
+	DSN string `json:"dsn,omitempty"`
+}
+
+type CopyConfig struct {
+	Mode         string   `json:"mode"`
+	Excludes     []string `json:"excludes,omitempty"`
+	UseGitignore bool     `json:"use_gitignore"`
+}
+
+type RepairConfig struct {
+	Enabled     bool `json:"enabled"`
+	MaxTurns    int  `json:"max_turns"`
+	MaxMinutes  int  `json:"max_minutes"`
+	OutputBytes int  `json:"output_bytes"`
+}
+
+type BudgetConfig struct {
+	MaxCostUSD      float64 `json:"max_cost_usd,omitempty"`
+	MaxTokens       int     `json:"max_tokens,omitempty"`
+	MaxAgentMinutes float64 `json:"max_agent_minutes,omitempty"`
+}
+
+type FollowupConfig struct {
+	Enabled    bool `json:"enabled"`
+	MaxPerTask int  `json:"max_per_task"`
+}
+
+type ArtifactConfig struct {
+	CAS         bool   `json:"cas"`
+	CASDir      string `json:"cas_dir,omitempty"`
+	Compression string `json:"compression"`
+}
+
+type ExportConfig struct {
+	Remote       string `json:"remote,omitempty"`
+	BranchPrefix string `json:"branch_prefix"`
+}
+
+type RetentionConfig struct {
+	KeepLast      int   `json:"keep_last,omitempty"`
+	MaxAgeDays    int   `json:"max_age_days,omitempty"`
+	MaxTotalBytes int64 `json:"max_total_bytes,omitempty"`
+	KeepFailed    bool  `json:"keep_failed"`
+	KeepFlagged   bool  `json:"keep_flagged"`
+}
+
+type EventsConfig struct {
+	HashChain bool                `json:"hash_chain"`
+	Console   ConsoleSinkConfig   `json:"console"`
+	Files     []FileSinkConfig    `json:"files,omitempty"`
+	Webhooks  []WebhookSinkConfig `json:"webhooks,omitempty"`
+}
+
+type ConsoleSinkConfig struct {
+	Enabled bool   `json:"enabled"`
+	Level   string `json:"level"`
+	Color   string `json:"color"`
+}
+
+type FileSinkConfig struct {
+	Path     string `json:"path"`
+	Level    string `json:"level,omitempty"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+	MaxFiles int    `json:"max_files,omitempty"`
+}
+
+type WebhookSinkConfig struct {
+	URL             string            `json:"url"`
+	Level           string            `json:"level,omitempty"`
+	HeaderEnv       map[string]string `json:"header_env,omitempty"`
+	BatchSize       int               `json:"batch_size,omitempty"`
+	FlushIntervalMS int               `json:"flush_interval_ms,omitempty"`
+	MaxRetries      int               `json:"max_retries,omitempty"`
+	TimeoutSeconds  int               `json:"timeout_seconds,omitempty"`
+	QueueSize       int               `json:"queue_size,omitempty"`
+}
+
+type SpeculativeConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Candidates int      `json:"candidates"`
+	Agents     []string `json:"agents,omitempty"`
+	Severities []string `json:"severities,omitempty"`
+	TaskTypes  []string `json:"task_types,omitempty"`
+}
+
 type CoverageConfig struct {
 	Enabled          bool    `json:"enabled"`
 	MinimumThreshold float64 `json:"minimum_threshold"`
+	MinimumDelta     float64 `json:"minimum_delta"`
 }
 
 func Default() Config {
@@ -33,12 +165,50 @@ func Default() Config {
 		CheckpointMins:  30,
 		AllowedPaths:    []string{"src", "tests"},
 		GitStrategy:     "worktree",
+		Conflicts:       "file",
+		Copy: CopyConfig{
+			Mode:         "auto",
+			UseGitignore: true,
+		},
+		Repair: RepairConfig{
+			Enabled:     false,
+			MaxTurns:    3,
+			MaxMinutes:  20,
+			OutputBytes: 4000,
+		},
 		Pytest: PluginConfig{
 			Enabled: true,
 		},
+		Speculative: SpeculativeConfig{
+			Enabled:    false,
+			Candidates: 3,
+			Severities: []string{"blocker"},
+		},
+		Followups: FollowupConfig{
+			Enabled:    false,
+			MaxPerTask: 3,
+		},
+		Artifacts: ArtifactConfig{
+			Compression: "none",
+		},
+		Export: ExportConfig{
+			BranchPrefix: "atqos-export/",
+		},
+		Retention: RetentionConfig{
+			KeepFailed:  true,
+			KeepFlagged: true,
+		},
+		Events: EventsConfig{
+			HashChain: true,
+			Console: ConsoleSinkConfig{
+				Level: "info",
+				Color: "auto",
+			},
+		},
 		Coverage: CoverageConfig{
 			Enabled:          true,
 			MinimumThreshold: 0.9,
+			MinimumDelta:     0.01,
 		},
 	}
 }
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"atqos/internal/agent"
+)
+
+// EventSchemaVersion is written to every event as "schema". Bump it when a
+// payload changes incompatibly.
+const EventSchemaVersion = 1
+
+const (
+	EventRunStarted            = "run_started"
+	EventRunFinished           = "run_finished"
+	EventRunFailed             = "run_failed"
+	EventDirtyWorktree         = "dirty_worktree"
+	EventCollectStarted        = "collect_started"
+	EventCollectFinished       = "collect_finished"
+	EventNormalizeFinished     = "normalize_finished"
+	EventPlanFinished          = "plan_finished"
+	EventWorktreesPruned       = "worktrees_pruned"
+	EventCheckpointStarted     = "checkpoint_started"
+	EventCheckpointFinished    = "checkpoint_finished"
+	EventRepairTurn            = "repair_turn"
+	EventSpeculationFinished   = "speculation_finished"
+	EventEscalation            = "escalation"
+	EventFollowupsSpawned      = "followups_spawned"
+	EventBudgetExceeded        = "budget_exceeded"
+	EventArtifactsIngested     = "artifacts_ingested"
+	EventArtifactsIngestFailed = "artifacts_ingest_failed"
+	EventSearchIndexFailed     = "search_index_failed"
+
+	// Row events mirror store writes so a run can be rebuilt from its log.
+	EventRunCreated        = "run_created"
+	EventRunStatusChanged  = "run_status_changed"
+	EventFindingsRecorded  = "findings_recorded"
+	EventArtifactRecorded  = "artifact_recorded"
+	EventTaskCreated       = "task_created"
+	EventTaskClaimed       = "task_claimed"
+	EventTaskStatusChanged = "task_status_changed"
+	EventAttemptStarted    = "attempt_started"
+	EventAttemptFinished   = "attempt_finished"
+)
+
+type RunStartedPayload struct {
+	RepoPath       string `json:"repo_path"`
+	BaseCommit     string `json:"base_commit"`
+	SnapshotCommit string `json:"snapshot_commit"`
+}
+
+type RunFinishedPayload struct {
+	Status string `json:"status"`
+}
+
+// ErrorPayload is shared by the *_failed events.
+type ErrorPayload struct {
+	Error string `json:"error"`
+}
+
+type DirtyWorktreePayload struct {
+	Files    []string `json:"files"`
+	Included bool     `json:"included"`
+}
+
+type CollectFinishedPayload struct {
+	ArtifactCount int `json:"artifact_count"`
+}
+
+type NormalizeFinishedPayload struct {
+	FindingCount int `json:"finding_count"`
+}
+
+type PlanFinishedPayload struct {
+	TaskCount int `json:"task_count"`
+}
+
+type WorktreesPrunedPayload struct {
+	Worktrees []string `json:"worktrees"`
+	Branches  []string `json:"branches"`
+}
+
+type RepairTurnPayload struct {
+	Turn       int      `json:"turn"`
+	Violations []string `json:"violations"`
+}
+
+// SpeculationFinishedPayload numbers candidates from 1; Winner is 0 when no
+// candidate succeeded.
+type SpeculationFinishedPayload struct {
+	Candidates int `json:"candidates"`
+	Winner     int `json:"winner"`
+}
+
+type EscalationPayload struct {
+	Reason  string `json:"reason"`
+	Status  string `json:"status"`
+	Summary string `json:"summary"`
+}
+
+type FollowupsSpawnedPayload struct {
+	Count int `json:"count"`
+}
+
+type BudgetExceededPayload struct {
+	Reason string      `json:"reason"`
+	Usage  agent.Usage `json:"usage"`
+}
+
+type ArtifactsIngestedPayload struct {
+	Files       int   `json:"files"`
+	Deduped     int   `json:"deduped"`
+	Skipped     int   `json:"skipped"`
+	Bytes       int64 `json:"bytes"`
+	StoredBytes int64 `json:"stored_bytes"`
+}
+
+type RunCreatedPayload struct {
+	RepoPath       string    `json:"repo_path"`
+	StartedAt      time.Time `json:"started_at"`
+	Status         string    `json:"status"`
+	Config         string    `json:"config"`
+	BaseCommit     string    `json:"base_commit"`
+	DirtyDiff      string    `json:"dirty_diff,omitempty"`
+	SnapshotCommit string    `json:"snapshot_commit,omitempty"`
+}
+
+type RunStatusChangedPayload struct {
+	Status      string `json:"status"`
+	SummaryJSON string `json:"summary_json"`
+}
+
+type FindingsRecordedPayload struct {
+	Findings []FindingPayload `json:"findings"`
+}
+
+type FindingPayload struct {
+	Tool        string    `json:"tool"`
+	Kind        string    `json:"kind"`
+	Severity    string    `json:"severity,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	FilePath    string    `json:"file_path,omitempty"`
+	Line        int       `json:"line,omitempty"`
+	Column      int       `json:"column,omitempty"`
+	Symbol      string    `json:"symbol,omitempty"`
+	TestID      string    `json:"test_id,omitempty"`
+	RawRef      string    `json:"raw_ref,omitempty"`
+	MetaJSON    string    `json:"meta_json,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ArtifactRecordedPayload struct {
+	Kind      string    `json:"kind"`
+	Path      string    `json:"path"`
+	SHA256    string    `json:"sha256,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	MetaJSON  string    `json:"meta_json,omitempty"`
+}
+
+// TaskCreatedPayload carries the task row; the task ID and tool are on the
+// event itself.
+type TaskCreatedPayload struct {
+	TaskType        string    `json:"task_type"`
+	Severity        string    `json:"severity,omitempty"`
+	Priority        int       `json:"priority"`
+	Status          string    `json:"status"`
+	Fingerprint     string    `json:"fingerprint"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	TargetsJSON     string    `json:"targets_json"`
+	ValidationJSON  string    `json:"validation_json"`
+	RetryPolicyJSON string    `json:"retry_policy_json"`
+	DependsOnJSON   string    `json:"depends_on_json"`
+	ExtraJSON       string    `json:"extra_json"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type TaskClaimedPayload struct {
+	WorkerID string `json:"worker_id"`
+}
+
+type TaskStatusChangedPayload struct {
+	Status    string `json:"status"`
+	ExtraJSON string `json:"extra_json"`
+}
+
+type AttemptStartedPayload struct {
+	AttemptNo          int       `json:"attempt_no"`
+	Status             string    `json:"status"`
+	AgentName          string    `json:"agent_name"`
+	AgentExitCode      int       `json:"agent_exit_code"`
+	ValidationExitCode int       `json:"validation_exit_code"`
+	StartedAt          time.Time `json:"started_at"`
+	SummaryJSON        string    `json:"summary_json,omitempty"`
+	DiffStatsJSON      string    `json:"diff_stats_json,omitempty"`
+	ArtifactsJSON      string    `json:"artifacts_json,omitempty"`
+}
+
+type AttemptFinishedPayload struct {
+	Status             string    `json:"status"`
+	AgentExitCode      int       `json:"agent_exit_code"`
+	ValidationExitCode int       `json:"validation_exit_code"`
+	FinishedAt         time.Time `json:"finished_at"`
+	SummaryJSON        string    `json:"summary_json,omitempty"`
+	DiffStatsJSON      string    `json:"diff_stats_json,omitempty"`
+	ArtifactsJSON      string    `json:"artifacts_json,omitempty"`
+}
+
+// eventPayloads maps each event type to a constructor for its payload; nil
+// means the event carries no payload.
+var eventPayloads = map[string]func() interface{}{
+	EventRunStarted:            func() interface{} { return &RunStartedPayload{} },
+	EventRunFinished:           func() interface{} { return &RunFinishedPayload{} },
+	EventRunFailed:             func() interface{} { return &ErrorPayload{} },
+	EventDirtyWorktree:         func() interface{} { return &DirtyWorktreePayload{} },
+	EventCollectStarted:        nil,
+	EventCollectFinished:       func() interface{} { return &CollectFinishedPayload{} },
+	EventNormalizeFinished:     func() interface{} { return &NormalizeFinishedPayload{} },
+	EventPlanFinished:          func() interface{} { return &PlanFinishedPayload{} },
+	EventWorktreesPruned:       func() interface{} { return &WorktreesPrunedPayload{} },
+	EventCheckpointStarted:     nil,
+	EventCheckpointFinished:    nil,
+	EventRepairTurn:            func() interface{} { return &RepairTurnPayload{} },
+	EventSpeculationFinished:   func() interface{} { return &SpeculationFinishedPayload{} },
+	EventEscalation:            func() interface{} { return &EscalationPayload{} },
+	EventFollowupsSpawned:      func() interface{} { return &FollowupsSpawnedPayload{} },
+	EventBudgetExceeded:        func() interface{} { return &BudgetExceededPayload{} },
+	EventArtifactsIngested:     func() interface{} { return &ArtifactsIngestedPayload{} },
+	EventArtifactsIngestFailed: func() interface{} { return &ErrorPayload{} },
+	EventSearchIndexFailed:     func() interface{} { return &ErrorPayload{} },
+	EventRunCreated:            func() interface{} { return &RunCreatedPayload{} },
+	EventRunStatusChanged:      func() interface{} { return &RunStatusChangedPayload{} },
+	EventFindingsRecorded:      func() interface{} { return &FindingsRecordedPayload{} },
+	EventArtifactRecorded:      func() interface{} { return &ArtifactRecordedPayload{} },
+	EventTaskCreated:           func() interface{} { return &TaskCreatedPayload{} },
+	EventTaskClaimed:           func() interface{} { return &TaskClaimedPayload{} },
+	EventTaskStatusChanged:     func() interface{} { return &TaskStatusChangedPayload{} },
+	EventAttemptStarted:        func() interface{} { return &AttemptStartedPayload{} },
+	EventAttemptFinished:       func() interface{} { return &AttemptFinishedPayload{} },
+}
+
+// KnownEventType reports whether eventType is part of the current schema.
+func KnownEventType(eventType string) bool {
+	_, ok := eventPayloads[eventType]
+	return ok
+}
+
+// DecodeEventPayload decodes raw into the payload struct registered for
+// eventType, rejecting unknown fields. Unknown event types decode into a
+// generic value so newer logs stay readable.
+func DecodeEventPayload(eventType string, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	newPayload, ok := eventPayloads[eventType]
+	if !ok {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	if newPayload == nil {
+		return nil, fmt.Errorf("event %s has no payload", eventType)
+	}
+	payload := newPayload()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", eventType, err)
+	}
+	return payload, nil
+}
@@ -15,11 +15,25 @@ const (
 )
 
 type RunRecord struct {
-	RunID     string
-	RepoPath  string
-	StartedAt time.Time
-	Status    string
-	Config    string
+	RunID          string
+	RepoPath       string
+	StartedAt      time.Time
+	Status         string
+	Config         string
+	BaseCommit     string
+	DirtyDiff      string
+	SnapshotCommit string
+	FinishedAt     time.Time
+	SummaryJSON    string
+}
+
+type RunInfo struct {
+	RunID      string
+	RepoPath   string
+	Status     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Flagged    bool
 }
 
 type ArtifactRecord struct {
@@ -55,6 +69,7 @@ type TaskRecord struct {
 	RunID           string
 	Tool            string
 	TaskType        string
+	Severity        string
 	Priority        int
 	Status          string
 	Fingerprint     string
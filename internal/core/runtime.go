@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"atqos/internal/config"
 	"atqos/internal/repo"
@@ -11,6 +12,7 @@ import (
 type RunContext struct {
 	RunID          string
 	RepoPath       string
+	WorkspacePath  string
 	ArtifactRoot   string
 	RunnerRegistry *runner.Registry
 	EventLog       EventLogger
@@ -18,7 +20,20 @@ type RunContext struct {
 	RepoAdapter    *repo.Adapter
 }
 
+func (rc RunContext) WorkDir() string {
+	if rc.WorkspacePath != "" {
+		return rc.WorkspacePath
+	}
+	return rc.RepoPath
+}
+
+// Event is one line of events.jsonl. Schema, Seq, TS and the hashes are
+// stamped by the event log; emitters fill in the rest. Hash must stay the
+// last field: it covers the encoding of every field before it.
 type Event struct {
+	Schema    int         `json:"schema"`
+	Seq       int64       `json:"seq"`
+	TS        time.Time   `json:"ts"`
 	RunID     string      `json:"run_id"`
 	Level     string      `json:"level"`
 	EventType string      `json:"event_type"`
@@ -26,6 +41,8 @@ type Event struct {
 	TaskID    int64       `json:"task_id,omitempty"`
 	AttemptID int64       `json:"attempt_id,omitempty"`
 	Payload   interface{} `json:"payload,omitempty"`
+	PrevHash  string      `json:"prev_hash,omitempty"`
+	Hash      string      `json:"hash,omitempty"`
 }
 
 type EventLogger interface {
@@ -39,3 +56,7 @@ type Plugin interface {
 	Plan(ctx context.Context, rc RunContext, findings []FindingRecord) ([]TaskRecord, error)
 	ValidationSpec(ctx context.Context, rc RunContext, task TaskRecord) (ValidationSpec, error)
 }
+
+type CoverageReporter interface {
+	Coverage(ctx context.Context, rc RunContext, artifacts ArtifactSet, task TaskRecord) (float64, error)
+}
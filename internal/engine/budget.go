@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"atqos/internal/agent"
+	"atqos/internal/config"
+	"atqos/internal/core"
+)
+
+type UsageReport struct {
+	Total          agent.Usage           `json:"total"`
+	Tasks          map[int64]agent.Usage `json:"tasks,omitempty"`
+	BudgetExceeded string                `json:"budget_exceeded,omitempty"`
+}
+
+type usageTracker struct {
+	total    agent.Usage
+	tasks    map[int64]agent.Usage
+	exceeded string
+}
+
+func (e *Executor) addUsage(taskID int64, usage agent.Usage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.usage.tasks == nil {
+		e.usage.tasks = make(map[int64]agent.Usage)
+	}
+	e.usage.total.Add(usage)
+	taskUsage := e.usage.tasks[taskID]
+	taskUsage.Add(usage)
+	e.usage.tasks[taskID] = taskUsage
+}
+
+func (e *Executor) budgetExceeded() bool {
+	e.mu.Lock()
+	if e.usage.exceeded != "" {
+		e.mu.Unlock()
+		return true
+	}
+	reason := budgetReason(e.RunContext.Config.Budget, e.usage.total)
+	e.usage.exceeded = reason
+	total := e.usage.total
+	e.mu.Unlock()
+	if reason == "" {
+		return false
+	}
+
+	_ = e.RunContext.EventLog.Emit(core.Event{
+		RunID:     e.RunContext.RunID,
+		Level:     "warn",
+		EventType: core.EventBudgetExceeded,
+		Payload: core.BudgetExceededPayload{
+			Reason: reason,
+			Usage:  total,
+		},
+	})
+	return true
+}
+
+func (e *Executor) Usage() UsageReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	report := UsageReport{Total: e.usage.total, BudgetExceeded: e.usage.exceeded}
+	if len(e.usage.tasks) > 0 {
+		report.Tasks = make(map[int64]agent.Usage, len(e.usage.tasks))
+		for id, usage := range e.usage.tasks {
+			report.Tasks[id] = usage
+		}
+	}
+	return report
+}
+
+func budgetReason(budget config.BudgetConfig, usage agent.Usage) string {
+	switch {
+	case budget.MaxCostUSD > 0 && usage.CostUSD >= budget.MaxCostUSD:
+		return fmt.Sprintf("cost $%.4f reached budget $%.4f", usage.CostUSD, budget.MaxCostUSD)
+	case budget.MaxTokens > 0 && usage.Tokens() >= budget.MaxTokens:
+		return fmt.Sprintf("%d tokens reached budget %d", usage.Tokens(), budget.MaxTokens)
+	case budget.MaxAgentMinutes > 0 && time.Duration(usage.WallTimeMs)*time.Millisecond >= time.Duration(budget.MaxAgentMinutes*float64(time.Minute)):
+		return fmt.Sprintf("agent time %s reached budget %.1fm", time.Duration(usage.WallTimeMs)*time.Millisecond, budget.MaxAgentMinutes)
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+
+	"atqos/internal/agent"
+	"atqos/internal/config"
+	"atqos/internal/core"
+)
+
+type recordEvents []core.Event
+
+func (r *recordEvents) Emit(event core.Event) error {
+	*r = append(*r, event)
+	return nil
+}
+
+func TestBudgetReason(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		budget   config.BudgetConfig
+		usage    agent.Usage
+		exceeded bool
+	}{
+		{"no budget", config.BudgetConfig{}, agent.Usage{InputTokens: 1 << 20, CostUSD: 100, WallTimeMs: 1 << 30}, false},
+		{"cost under", config.BudgetConfig{MaxCostUSD: 1}, agent.Usage{CostUSD: 0.99}, false},
+		{"cost reached", config.BudgetConfig{MaxCostUSD: 1}, agent.Usage{CostUSD: 1}, true},
+		{"tokens under", config.BudgetConfig{MaxTokens: 100}, agent.Usage{InputTokens: 60, OutputTokens: 39}, false},
+		{"tokens reached", config.BudgetConfig{MaxTokens: 100}, agent.Usage{InputTokens: 60, OutputTokens: 40}, true},
+		{"minutes under", config.BudgetConfig{MaxAgentMinutes: 0.5}, agent.Usage{WallTimeMs: 29999}, false},
+		{"minutes reached", config.BudgetConfig{MaxAgentMinutes: 0.5}, agent.Usage{WallTimeMs: 30000}, true},
+	} {
+		if reason := budgetReason(tc.budget, tc.usage); (reason != "") != tc.exceeded {
+			t.Errorf("%s: budgetReason = %q, want exceeded %v", tc.name, reason, tc.exceeded)
+		}
+	}
+}
+
+func TestUsageAccumulatesAndBudgetStaysExceeded(t *testing.T) {
+	cfg := config.Default()
+	cfg.Budget.MaxTokens = 250
+	var events recordEvents
+	e := &Executor{RunContext: core.RunContext{RunID: "run-1", Config: cfg, EventLog: &events}}
+
+	turn := agent.Usage{InputTokens: 60, OutputTokens: 40, CostUSD: 0.1, WallTimeMs: 5}
+	e.addUsage(1, turn)
+	e.addUsage(1, turn)
+	if e.budgetExceeded() {
+		t.Fatal("budget exceeded at 200 of 250 tokens")
+	}
+	e.addUsage(2, turn)
+	for i := 0; i < 2; i++ {
+		if !e.budgetExceeded() {
+			t.Fatal("budget not exceeded at 300 of 250 tokens")
+		}
+	}
+	if len(events) != 1 || events[0].EventType != core.EventBudgetExceeded {
+		t.Fatalf("events = %+v, want one budget_exceeded", events)
+	}
+
+	report := e.Usage()
+	if report.Total.Tokens() != 300 || report.Tasks[1].Tokens() != 200 || report.Tasks[2].Tokens() != 100 || report.Total.WallTimeMs != 15 {
+		t.Fatalf("usage = %+v", report)
+	}
+	if report.BudgetExceeded == "" {
+		t.Fatal("report does not say why the budget stopped the run")
+	}
+}
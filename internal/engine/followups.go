@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"atqos/internal/core"
+)
+
+const followupTaskType = "followup"
+
+func systematic(summary agentSummary) bool {
+	return summary.Notes != nil && summary.Notes.SystematicIssue
+}
+
+func (e *Executor) escalate(ctx context.Context, task core.TaskRecord, attemptID int64, outcome attemptOutcome) bool {
+	if !systematic(outcome.Agent.Summary) {
+		return false
+	}
+	_ = e.RunContext.EventLog.Emit(core.Event{
+		RunID:     e.RunContext.RunID,
+		Level:     "warn",
+		EventType: core.EventEscalation,
+		Tool:      task.Tool,
+		TaskID:    task.ID,
+		AttemptID: attemptID,
+		Payload: core.EscalationPayload{
+			Reason:  "systematic_issue",
+			Status:  outcome.Status,
+			Summary: outcome.Agent.Summary.Summary,
+		},
+	})
+	return true
+}
+
+func escalationJSON(attemptID int64, outcome attemptOutcome) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"escalation": "systematic_issue",
+		"attempt_id": attemptID,
+		"summary":    outcome.Agent.Summary.Summary,
+	})
+	return string(data)
+}
+
+// spawnFollowups queues the suggested follow-ups of a succeeded attempt.
+// Follow-ups build on the parent's change, so nothing is spawned for failed
+// or blocked parents; that also keeps depends_on satisfied by construction,
+// as the claim does not check it.
+func (e *Executor) spawnFollowups(ctx context.Context, task core.TaskRecord, attemptID int64, outcome attemptOutcome) {
+	cfg := e.RunContext.Config.Followups
+	notes := outcome.Agent.Summary.Notes
+	if !cfg.Enabled || outcome.Status != "succeeded" || notes == nil || len(notes.SuggestedFollowups) == 0 || task.TaskType == followupTaskType {
+		return
+	}
+
+	now := time.Now()
+	dependsOn, _ := json.Marshal([]int64{task.ID})
+	extraJSON, _ := json.Marshal(map[string]int64{"parent_task_id": task.ID, "attempt_id": attemptID})
+	seen := make(map[string]bool)
+	var tasks []core.TaskRecord
+	for _, followup := range notes.SuggestedFollowups {
+		followup = strings.TrimSpace(followup)
+		if followup == "" || seen[followup] {
+			continue
+		}
+		if cfg.MaxPerTask > 0 && len(tasks) >= cfg.MaxPerTask {
+			break
+		}
+		seen[followup] = true
+		sum := sha256.Sum256([]byte(task.Fingerprint + "\x00" + followup))
+		tasks = append(tasks, core.TaskRecord{
+			RunID:           task.RunID,
+			Tool:            task.Tool,
+			TaskType:        followupTaskType,
+			Severity:        "low",
+			Priority:        task.Priority - 1,
+			Status:          "queued",
+			Fingerprint:     hex.EncodeToString(sum[:]),
+			Title:           followupTitle(followup),
+			Description:     fmt.Sprintf("%s\n\nSuggested while working on task %d: %s", followup, task.ID, task.Title),
+			TargetsJSON:     task.TargetsJSON,
+			ValidationJSON:  task.ValidationJSON,
+			RetryPolicyJSON: task.RetryPolicyJSON,
+			DependsOnJSON:   string(dependsOn),
+			ExtraJSON:       string(extraJSON),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	if err := e.Store.InsertTasks(ctx, tasks); err != nil {
+		return
+	}
+	_ = e.RunContext.EventLog.Emit(core.Event{
+		RunID:     e.RunContext.RunID,
+		Level:     "info",
+		EventType: core.EventFollowupsSpawned,
+		Tool:      task.Tool,
+		TaskID:    task.ID,
+		AttemptID: attemptID,
+		Payload: core.FollowupsSpawnedPayload{
+			Count: len(tasks),
+		},
+	})
+}
+
+func followupTitle(followup string) string {
+	title := strings.SplitN(followup, "\n", 2)[0]
+	if len(title) > 80 {
+		title = title[:77] + "..."
+	}
+	return "Follow-up: " + title
+}
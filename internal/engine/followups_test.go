@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"atqos/internal/agent"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/store"
+)
+
+type discardEvents struct{}
+
+func (discardEvents) Emit(core.Event) error { return nil }
+
+func TestSpawnFollowupsOnlyForSucceededParents(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		enabled bool
+		status  string
+		want    int
+	}{
+		{"disabled", false, "succeeded", 0},
+		{"succeeded", true, "succeeded", 2},
+		{"failed", true, "failed", 0},
+		{"blocked", true, "blocked", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := store.NewMemory()
+			cfg := config.Default()
+			cfg.Followups.Enabled = tc.enabled
+			e := &Executor{Store: s, RunContext: core.RunContext{RunID: "run-1", Config: cfg, EventLog: discardEvents{}}}
+
+			parent := []core.TaskRecord{{RunID: "run-1", Tool: "pytest", TaskType: "fix_test", Status: tc.status, Fingerprint: "p"}}
+			if err := s.InsertTasks(ctx, parent); err != nil {
+				t.Fatal(err)
+			}
+			outcome := attemptOutcome{Status: tc.status}
+			outcome.Agent.Summary.Notes = &agent.Notes{SuggestedFollowups: []string{"rename helper", "add docs", "rename helper"}}
+			e.spawnFollowups(ctx, parent[0], 1, outcome)
+
+			tasks, err := s.ListTasks(ctx, "run-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(tasks) - 1; got != tc.want {
+				t.Fatalf("spawned %d follow-ups, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"context"
+
+	"atqos/internal/core"
+	"atqos/internal/store"
+)
+
+// journal wraps a store and emits a debug-level row event after each
+// successful write, so the run's event log alone is enough to rebuild its
+// rows (see atqos replay-events). Events carry the IDs assigned by the
+// store.
+type journal struct {
+	store.Store
+	log   core.EventLogger
+	runID string
+}
+
+// Journal returns s with every run, finding, artifact, task and attempt
+// write mirrored to log.
+func Journal(s store.Store, log core.EventLogger, runID string) store.Store {
+	return &journal{Store: s, log: log, runID: runID}
+}
+
+func (j *journal) emit(eventType string, tool string, taskID int64, attemptID int64, payload interface{}) {
+	_ = j.log.Emit(core.Event{
+		RunID:     j.runID,
+		Level:     "debug",
+		EventType: eventType,
+		Tool:      tool,
+		TaskID:    taskID,
+		AttemptID: attemptID,
+		Payload:   payload,
+	})
+}
+
+func (j *journal) CreateRun(ctx context.Context, run core.RunRecord) error {
+	if err := j.Store.CreateRun(ctx, run); err != nil {
+		return err
+	}
+	j.emit(core.EventRunCreated, "", 0, 0, core.RunCreatedPayload{
+		RepoPath:       run.RepoPath,
+		StartedAt:      run.StartedAt,
+		Status:         run.Status,
+		Config:         run.Config,
+		BaseCommit:     run.BaseCommit,
+		DirtyDiff:      run.DirtyDiff,
+		SnapshotCommit: run.SnapshotCommit,
+	})
+	return nil
+}
+
+func (j *journal) UpdateRunStatus(ctx context.Context, runID string, status string, summaryJSON string) error {
+	if err := j.Store.UpdateRunStatus(ctx, runID, status, summaryJSON); err != nil {
+		return err
+	}
+	j.emit(core.EventRunStatusChanged, "", 0, 0, core.RunStatusChangedPayload{Status: status, SummaryJSON: summaryJSON})
+	return nil
+}
+
+func (j *journal) AddArtifact(ctx context.Context, artifact core.ArtifactRecord) error {
+	if err := j.Store.AddArtifact(ctx, artifact); err != nil {
+		return err
+	}
+	j.emit(core.EventArtifactRecorded, artifact.Tool, 0, 0, core.ArtifactRecordedPayload{
+		Kind:      artifact.Kind,
+		Path:      artifact.Path,
+		SHA256:    artifact.SHA256,
+		SizeBytes: artifact.SizeBytes,
+		CreatedAt: artifact.CreatedAt,
+		MetaJSON:  artifact.MetaJSON,
+	})
+	return nil
+}
+
+func (j *journal) InsertFindings(ctx context.Context, findings []core.FindingRecord) error {
+	if err := j.Store.InsertFindings(ctx, findings); err != nil || len(findings) == 0 {
+		return err
+	}
+	payload := core.FindingsRecordedPayload{Findings: make([]core.FindingPayload, 0, len(findings))}
+	for _, finding := range findings {
+		payload.Findings = append(payload.Findings, core.FindingPayload{
+			Tool:        finding.Tool,
+			Kind:        finding.Kind,
+			Severity:    finding.Severity,
+			Fingerprint: finding.Fingerprint,
+			Message:     finding.Message,
+			FilePath:    finding.FilePath,
+			Line:        finding.Line,
+			Column:      finding.Column,
+			Symbol:      finding.Symbol,
+			TestID:      finding.TestID,
+			RawRef:      finding.RawRef,
+			MetaJSON:    finding.MetaJSON,
+			CreatedAt:   finding.CreatedAt,
+		})
+	}
+	j.emit(core.EventFindingsRecorded, "", 0, 0, payload)
+	return nil
+}
+
+func (j *journal) InsertTasks(ctx context.Context, tasks []core.TaskRecord) error {
+	if err := j.Store.InsertTasks(ctx, tasks); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		j.emit(core.EventTaskCreated, task.Tool, task.ID, 0, core.TaskCreatedPayload{
+			TaskType:        task.TaskType,
+			Severity:        task.Severity,
+			Priority:        task.Priority,
+			Status:          task.Status,
+			Fingerprint:     task.Fingerprint,
+			Title:           task.Title,
+			Description:     task.Description,
+			TargetsJSON:     task.TargetsJSON,
+			ValidationJSON:  task.ValidationJSON,
+			RetryPolicyJSON: task.RetryPolicyJSON,
+			DependsOnJSON:   task.DependsOnJSON,
+			ExtraJSON:       task.ExtraJSON,
+			CreatedAt:       task.CreatedAt,
+		})
+	}
+	return nil
+}
+
+func (j *journal) ClaimNextTask(ctx context.Context, runID string, workerID string) (*core.TaskRecord, error) {
+	task, err := j.Store.ClaimNextTask(ctx, runID, workerID)
+	if err != nil || task == nil {
+		return task, err
+	}
+	j.emit(core.EventTaskClaimed, task.Tool, task.ID, 0, core.TaskClaimedPayload{WorkerID: workerID})
+	return task, nil
+}
+
+func (j *journal) UpdateTaskStatus(ctx context.Context, taskID int64, status string, extraJSON string) error {
+	if err := j.Store.UpdateTaskStatus(ctx, taskID, status, extraJSON); err != nil {
+		return err
+	}
+	j.emit(core.EventTaskStatusChanged, "", taskID, 0, core.TaskStatusChangedPayload{Status: status, ExtraJSON: extraJSON})
+	return nil
+}
+
+func (j *journal) CreateAttempt(ctx context.Context, attempt core.AttemptRecord) (int64, error) {
+	id, err := j.Store.CreateAttempt(ctx, attempt)
+	if err != nil {
+		return id, err
+	}
+	j.emit(core.EventAttemptStarted, "", attempt.TaskID, id, core.AttemptStartedPayload{
+		AttemptNo:          attempt.AttemptNo,
+		Status:             attempt.Status,
+		AgentName:          attempt.AgentName,
+		AgentExitCode:      attempt.AgentExitCode,
+		ValidationExitCode: attempt.ValidationExitCode,
+		StartedAt:          attempt.StartedAt,
+		SummaryJSON:        attempt.SummaryJSON,
+		DiffStatsJSON:      attempt.DiffStatsJSON,
+		ArtifactsJSON:      attempt.ArtifactsJSON,
+	})
+	return id, nil
+}
+
+func (j *journal) FinishAttempt(ctx context.Context, attempt core.AttemptRecord) error {
+	if err := j.Store.FinishAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	j.emit(core.EventAttemptFinished, "", attempt.TaskID, attempt.ID, core.AttemptFinishedPayload{
+		Status:             attempt.Status,
+		AgentExitCode:      attempt.AgentExitCode,
+		ValidationExitCode: attempt.ValidationExitCode,
+		FinishedAt:         attempt.FinishedAt,
+		SummaryJSON:        attempt.SummaryJSON,
+		DiffStatsJSON:      attempt.DiffStatsJSON,
+		ArtifactsJSON:      attempt.ArtifactsJSON,
+	})
+	return nil
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"atqos/internal/agent"
+	"atqos/internal/core"
+	"atqos/internal/git"
+)
+
+type attemptSummary struct {
+	Agent      agentSummary     `json:"agent"`
+	Validation ValidationResult `json:"validation"`
+	Turns      int              `json:"turns"`
+	Usage      agent.Usage      `json:"usage"`
+	History    []attemptTurn    `json:"history,omitempty"`
+	Candidate  *candidateInfo   `json:"candidate,omitempty"`
+}
+
+type attemptTurn struct {
+	Turn       int              `json:"turn"`
+	Agent      agentSummary     `json:"agent"`
+	Validation ValidationResult `json:"validation"`
+	Refs       agentRefs        `json:"artifacts"`
+}
+
+type attemptOutcome struct {
+	Status     string
+	Agent      agentRecord
+	Validation ValidationResult
+	History    []attemptTurn
+	Candidate  *candidateInfo
+	Usage      agent.Usage
+}
+
+func (o attemptOutcome) Record(taskID int64, attemptID int64) core.AttemptRecord {
+	summaryJSON, _ := json.Marshal(attemptSummary{
+		Agent:      o.Agent.Summary,
+		Validation: o.Validation,
+		Turns:      len(o.History) + 1,
+		Usage:      o.Usage,
+		History:    o.History,
+		Candidate:  o.Candidate,
+	})
+	artifactsJSON, _ := json.Marshal(o.Agent.Refs)
+	diffStatsJSON, _ := json.Marshal(o.Agent.Patch)
+	return core.AttemptRecord{
+		ID:                 attemptID,
+		TaskID:             taskID,
+		Status:             o.Status,
+		AgentExitCode:      o.Agent.Summary.ExitCode,
+		ValidationExitCode: o.Validation.ExitCode,
+		SummaryJSON:        string(summaryJSON),
+		DiffStatsJSON:      string(diffStatsJSON),
+		ArtifactsJSON:      string(artifactsJSON),
+	}
+}
+
+func (e *Executor) runAttempt(ctx context.Context, taskAgent agent.Agent, task core.TaskRecord, attemptID int64, req agent.Request, spec core.ValidationSpec, workspace git.Workspace) attemptOutcome {
+	repair := e.RunContext.Config.Repair
+	maxTurns := 1
+	if repair.Enabled && repair.MaxTurns > 1 {
+		maxTurns = repair.MaxTurns
+	}
+	deadline := time.Time{}
+	if repair.Enabled && repair.MaxMinutes > 0 {
+		deadline = time.Now().Add(time.Duration(repair.MaxMinutes) * time.Minute)
+	}
+	if session, ok := taskAgent.(agent.SessionAgent); ok {
+		defer session.EndSession(req)
+	}
+	req.Validation.Run = e.validationTool(spec, workspace.Path, attemptID)
+
+	var outcome attemptOutcome
+	for turn := 1; ; turn++ {
+		started := time.Now()
+		result, agentErr := taskAgent.Invoke(ctx, req)
+		usage := agent.Usage{}
+		if result.Usage != nil {
+			usage = *result.Usage
+		}
+		if usage.WallTimeMs == 0 {
+			usage.WallTimeMs = time.Since(started).Milliseconds()
+		}
+		result.Usage = &usage
+		outcome.Usage.Add(usage)
+		e.addUsage(task.ID, usage)
+
+		record := e.recordAgent(ctx, task, attemptID, e.turnDir(attemptID, "agent", turn), req, result, agentErr, workspace)
+		validation := e.validate(ctx, task, spec, workspace.Path, e.turnDir(attemptID, "validation", turn))
+
+		outcome.Agent = record
+		outcome.Validation = validation
+		outcome.Status = "succeeded"
+		if agentErr != nil || !validation.Passed {
+			outcome.Status = "failed"
+		}
+
+		if outcome.Status == "succeeded" || turn >= maxTurns || ctx.Err() != nil || systematic(record.Summary) {
+			return outcome
+		}
+		if (!deadline.IsZero() && time.Now().After(deadline)) || e.budgetExceeded() {
+			return outcome
+		}
+
+		outcome.History = append(outcome.History, attemptTurn{
+			Turn:       turn,
+			Agent:      record.Summary,
+			Validation: validation,
+			Refs:       record.Refs,
+		})
+		req.PreviousAttempts = append(req.PreviousAttempts, agent.PreviousAttempt{
+			Turn:               turn,
+			Status:             record.Summary.Status,
+			Summary:            record.Summary.Summary,
+			ValidationExitCode: validation.ExitCode,
+			Violations:         validation.Violations,
+			Output:             failureOutput(validation, repair.OutputBytes),
+		})
+		_ = e.RunContext.EventLog.Emit(core.Event{
+			RunID:     e.RunContext.RunID,
+			Level:     "info",
+			EventType: core.EventRepairTurn,
+			Tool:      task.Tool,
+			TaskID:    task.ID,
+			AttemptID: attemptID,
+			Payload: core.RepairTurnPayload{
+				Turn:       turn + 1,
+				Violations: validation.Violations,
+			},
+		})
+	}
+}
+
+func (e *Executor) turnDir(attemptID int64, name string, turn int) string {
+	if turn > 1 {
+		name = fmt.Sprintf("%s-turn-%d", name, turn)
+	}
+	return filepath.Join(e.attemptDir(attemptID), name)
+}
+
+// validationTool runs the validation commands for an agent's
+// run_validation tool, through the same runners, environment and timeouts
+// as attempt validation, and reports them as repair feedback is reported.
+func (e *Executor) validationTool(spec core.ValidationSpec, workspace string, attemptID int64) func(context.Context) (string, error) {
+	var runs int64
+	return func(ctx context.Context) (string, error) {
+		outputDir := filepath.Join(e.attemptDir(attemptID), "tool-validation", fmt.Sprintf("run-%d", atomic.AddInt64(&runs, 1)))
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			return "", err
+		}
+		results, _ := runValidation(ctx, e.RunContext, spec, workspace, outputDir)
+		return commandOutput(results, false, e.RunContext.Config.Repair.OutputBytes), nil
+	}
+}
+
+func failureOutput(validation ValidationResult, limit int) string {
+	return commandOutput(validation.Commands, true, limit)
+}
+
+// commandOutput lists commands with their exit codes and captured output,
+// keeping the last limit bytes.
+func commandOutput(commands []CommandResult, failedOnly bool, limit int) string {
+	if limit <= 0 {
+		limit = 4000
+	}
+	var b strings.Builder
+	for _, command := range commands {
+		if failedOnly && command.ExitCode == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "$ %s (exit %d)\n", joinArgs(command.Args), command.ExitCode)
+		if command.Error != "" {
+			fmt.Fprintf(&b, "%s\n", command.Error)
+		}
+		for _, path := range []string{command.StdoutPath, command.StderrPath} {
+			if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+				b.Write(data)
+				if data[len(data)-1] != '\n' {
+					b.WriteByte('\n')
+				}
+			}
+		}
+	}
+	output := b.String()
+	if len(output) > limit {
+		output = "...[truncated]\n" + output[len(output)-limit:]
+	}
+	return output
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"atqos/internal/agent"
+	"atqos/internal/core"
+	"atqos/internal/git"
+)
+
+type candidateInfo struct {
+	Candidate int  `json:"candidate"`
+	Of        int  `json:"of"`
+	Winner    bool `json:"winner"`
+}
+
+type candidateRun struct {
+	attemptID int64
+	outcome   attemptOutcome
+}
+
+func (e *Executor) candidateAgents(task core.TaskRecord, previous []core.AttemptRecord) []agent.Agent {
+	cfg := e.RunContext.Config.Speculative
+	if !cfg.Enabled || cfg.Candidates < 2 {
+		return nil
+	}
+	if _, ok := e.GitStrategy.(git.CandidateStrategy); !ok {
+		return nil
+	}
+	if len(cfg.Severities) > 0 && !contains(cfg.Severities, task.Severity) {
+		return nil
+	}
+	if len(cfg.TaskTypes) > 0 && !contains(cfg.TaskTypes, task.TaskType) {
+		return nil
+	}
+
+	// Each candidate is an attempt, so never start more than the retry
+	// budget has left; with one left the task runs as a single attempt.
+	count := cfg.Candidates
+	if remaining := maxAttempts(task.RetryPolicyJSON) - len(previous); remaining < count {
+		count = remaining
+	}
+	agents := make([]agent.Agent, 0, count)
+	for i := 0; i < count; i++ {
+		if len(cfg.Agents) == 0 {
+			agents = append(agents, e.Agents.Select(task.TaskType, task.Tool, task.Severity, failedAttempts(previous)))
+			continue
+		}
+		if candidate, ok := e.Agents.Get(cfg.Agents[i%len(cfg.Agents)]); ok {
+			agents = append(agents, candidate)
+		}
+	}
+	return agents
+}
+
+func (e *Executor) runSpeculative(ctx context.Context, task core.TaskRecord, prior int, agents []agent.Agent) {
+	spec, err := validationSpec(task.ValidationJSON)
+	if err != nil {
+		_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", `{"error":"invalid validation spec"}`)
+		return
+	}
+	strategy := e.GitStrategy.(git.CandidateStrategy)
+
+	runs := make([]candidateRun, len(agents))
+	var wg sync.WaitGroup
+	for i, candidateAgent := range agents {
+		wg.Add(1)
+		go func(i int, candidateAgent agent.Agent) {
+			defer wg.Done()
+			runs[i] = e.runCandidate(ctx, strategy, candidateAgent, task, spec, prior+i+1, i+1)
+		}(i, candidateAgent)
+	}
+	wg.Wait()
+
+	winner := pickWinner(runs)
+	for i, run := range runs {
+		if run.attemptID == 0 {
+			continue
+		}
+		run.outcome.Candidate = &candidateInfo{Candidate: i + 1, Of: len(runs), Winner: i == winner}
+		record := run.outcome.Record(task.ID, run.attemptID)
+		if run.outcome.Status == "succeeded" && i != winner {
+			record.Status = "superseded"
+		}
+		_ = e.Store.FinishAttempt(ctx, record)
+	}
+
+	_ = e.RunContext.EventLog.Emit(core.Event{
+		RunID:     e.RunContext.RunID,
+		Level:     "info",
+		EventType: core.EventSpeculationFinished,
+		Tool:      task.Tool,
+		TaskID:    task.ID,
+		Payload: core.SpeculationFinishedPayload{
+			Candidates: len(runs),
+			Winner:     winner + 1,
+		},
+	})
+
+	if winner < 0 {
+		for _, run := range runs {
+			if run.attemptID != 0 && e.escalate(ctx, task, run.attemptID, run.outcome) {
+				_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", escalationJSON(run.attemptID, run.outcome))
+				return
+			}
+		}
+		// Candidates use attempt numbers prior+1..prior+len(agents), so the
+		// retry budget is spent the same way as by sequential attempts.
+		if prior+len(agents) < maxAttempts(task.RetryPolicyJSON) {
+			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "queued", "")
+			return
+		}
+		_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", "")
+		return
+	}
+	extraJSON, _ := json.Marshal(map[string]int64{"winning_attempt_id": runs[winner].attemptID})
+	_ = e.Store.UpdateTaskStatus(ctx, task.ID, "succeeded", string(extraJSON))
+	e.escalate(ctx, task, runs[winner].attemptID, runs[winner].outcome)
+	e.spawnFollowups(ctx, task, runs[winner].attemptID, runs[winner].outcome)
+}
+
+func (e *Executor) runCandidate(ctx context.Context, strategy git.CandidateStrategy, candidateAgent agent.Agent, task core.TaskRecord, spec core.ValidationSpec, attemptNo int, candidate int) candidateRun {
+	attemptID, err := e.Store.CreateAttempt(ctx, core.AttemptRecord{
+		TaskID:    task.ID,
+		AttemptNo: attemptNo,
+		Status:    "running",
+		AgentName: candidateAgent.Name(),
+		StartedAt: time.Now(),
+	})
+	if err != nil {
+		return candidateRun{}
+	}
+
+	workspace, err := strategy.PrepareCandidate(ctx, e.RunContext.RepoPath, task.ID, candidate)
+	if err != nil {
+		return candidateRun{
+			attemptID: attemptID,
+			outcome: attemptOutcome{
+				Status: "failed",
+				Agent:  agentRecord{Summary: agentSummary{Error: "workspace failure: " + err.Error()}},
+			},
+		}
+	}
+	defer strategy.FinalizeWorkspace(ctx, workspace)
+
+	req := e.agentRequest(candidateAgent, task, spec, workspace)
+	return candidateRun{
+		attemptID: attemptID,
+		outcome:   e.runAttempt(ctx, candidateAgent, task, attemptID, req, spec, workspace),
+	}
+}
+
+func pickWinner(runs []candidateRun) int {
+	winner := -1
+	for i, run := range runs {
+		if run.attemptID == 0 || run.outcome.Status != "succeeded" {
+			continue
+		}
+		if winner < 0 || diffSize(run.outcome.Agent.Patch) < diffSize(runs[winner].outcome.Agent.Patch) {
+			winner = i
+		}
+	}
+	return winner
+}
+
+func diffSize(patch git.Patch) int {
+	return patch.Insertions + patch.Deletions
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"atqos/internal/agent"
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/git"
+)
+
+func TestCandidateAgentsStayWithinRetryBudget(t *testing.T) {
+	router, err := agent.NewRouter(map[string]agent.Agent{"local": agent.NewLocal()}, nil, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Speculative = config.SpeculativeConfig{Enabled: true, Candidates: 3}
+	e := &Executor{Agents: router, GitStrategy: git.NewWorktree(t.TempDir()), RunContext: core.RunContext{Config: cfg}}
+
+	for _, tc := range []struct {
+		maxAttempts int
+		previous    int
+		want        int
+	}{
+		{maxAttempts: 1, previous: 0, want: 1},
+		{maxAttempts: 2, previous: 0, want: 2},
+		{maxAttempts: 3, previous: 0, want: 3},
+		{maxAttempts: 5, previous: 0, want: 3},
+		{maxAttempts: 5, previous: 3, want: 2},
+		{maxAttempts: 3, previous: 2, want: 1},
+		{maxAttempts: 3, previous: 3, want: 0},
+	} {
+		task := core.TaskRecord{TaskType: "fix_test", Tool: "pytest", RetryPolicyJSON: fmt.Sprintf(`{"max_attempts":%d}`, tc.maxAttempts)}
+		previous := make([]core.AttemptRecord, tc.previous)
+		if got := len(e.candidateAgents(task, previous)); got != tc.want {
+			t.Errorf("max_attempts %d after %d attempts: %d candidates, want %d", tc.maxAttempts, tc.previous, got, tc.want)
+		}
+	}
+}
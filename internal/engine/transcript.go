@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"atqos/internal/agent"
+	"atqos/internal/core"
+	"atqos/internal/git"
+)
+
+type agentSummary struct {
+	Status         string         `json:"status"`
+	Summary        string         `json:"summary"`
+	FilesChanged   []string       `json:"files_changed"`
+	ExitCode       int            `json:"exit_code"`
+	Usage          *agent.Usage   `json:"usage,omitempty"`
+	Actions        *agent.Actions `json:"actions,omitempty"`
+	Notes          *agent.Notes   `json:"notes,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	ProtocolErrors []string       `json:"protocol_errors,omitempty"`
+}
+
+type agentRefs struct {
+	PromptRef string `json:"prompt_ref,omitempty"`
+	StdoutRef string `json:"stdout_ref,omitempty"`
+	StderrRef string `json:"stderr_ref,omitempty"`
+	ResultRef string `json:"result_ref,omitempty"`
+	PatchRef  string `json:"patch_ref,omitempty"`
+}
+
+type agentRecord struct {
+	Summary agentSummary
+	Refs    agentRefs
+	Patch   git.Patch
+}
+
+func (e *Executor) recordAgent(ctx context.Context, task core.TaskRecord, attemptID int64, dir string, req agent.Request, result agent.Result, agentErr error, workspace git.Workspace) agentRecord {
+	record := agentRecord{
+		Summary: agentSummary{
+			Status:       result.Status,
+			Summary:      result.Summary,
+			FilesChanged: result.FilesChanged,
+			ExitCode:     result.ExitCode,
+			Usage:        result.Usage,
+			Actions:      result.Actions,
+			Notes:        result.Notes,
+		},
+	}
+	if agentErr != nil {
+		record.Summary.Error = agentErr.Error()
+		var protocolErr *agent.ProtocolError
+		if errors.As(agentErr, &protocolErr) {
+			record.Summary.ProtocolErrors = protocolErr.Issues
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return record
+	}
+
+	if data, err := json.MarshalIndent(req, "", "  "); err == nil {
+		record.Refs.PromptRef = e.writeArtifact(ctx, task, attemptID, "prompt", filepath.Join(dir, "request.json"), data)
+	}
+	record.Refs.StdoutRef = e.writeArtifact(ctx, task, attemptID, "stdout", filepath.Join(dir, "stdout.log"), result.Stdout)
+	record.Refs.StderrRef = e.writeArtifact(ctx, task, attemptID, "stderr", filepath.Join(dir, "stderr.log"), result.Stderr)
+	if data, err := json.MarshalIndent(struct {
+		agent.Result
+		ExitCode int    `json:"exit_code"`
+		Error    string `json:"error,omitempty"`
+	}{result, result.ExitCode, record.Summary.Error}, "", "  "); err == nil {
+		record.Refs.ResultRef = e.writeArtifact(ctx, task, attemptID, "json", filepath.Join(dir, "result.json"), data)
+	}
+
+	// Without git (an in-place run on a plain directory) there is nothing to
+	// diff against; keep the agent's file list so diff stats are not empty.
+	if !git.IsRepo(ctx, workspace.Path) {
+		record.Patch = git.Patch{Files: result.FilesChanged}
+	} else if patch, err := git.Diff(ctx, workspace.Path); err == nil {
+		record.Patch = patch
+		record.Refs.PatchRef = e.writeArtifact(ctx, task, attemptID, "diff", filepath.Join(dir, "patch.diff"), patch.Data)
+	}
+
+	return record
+}
+
+func (e *Executor) writeArtifact(ctx context.Context, task core.TaskRecord, attemptID int64, kind string, path string, data []byte) string {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	metaJSON, _ := json.Marshal(map[string]int64{
+		"task_id":    task.ID,
+		"attempt_id": attemptID,
+	})
+	_ = e.Store.AddArtifact(ctx, core.ArtifactRecord{
+		RunID:     e.RunContext.RunID,
+		Tool:      "agent",
+		Kind:      kind,
+		Path:      path,
+		SHA256:    hex.EncodeToString(sum[:]),
+		SizeBytes: int64(len(data)),
+		CreatedAt: time.Now(),
+		MetaJSON:  string(metaJSON),
+	})
+	return path
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"atqos/internal/core"
+	"atqos/internal/runner"
+)
+
+type Baseline struct {
+	Artifacts core.ArtifactSet
+	Findings  []core.FindingRecord
+}
+
+type ValidationResult struct {
+	Passed           bool            `json:"passed"`
+	ExitCode         int             `json:"exit_code"`
+	Commands         []CommandResult `json:"commands"`
+	BaselineFindings int             `json:"baseline_findings"`
+	Findings         int             `json:"findings"`
+	NewFindings      int             `json:"new_findings"`
+	ResolvedFindings int             `json:"resolved_findings"`
+	Coverage         *CoverageResult `json:"coverage,omitempty"`
+	Violations       []string        `json:"violations,omitempty"`
+}
+
+type CommandResult struct {
+	Runner     string   `json:"runner"`
+	Args       []string `json:"args"`
+	ExitCode   int      `json:"exit_code"`
+	DurationMs int64    `json:"duration_ms"`
+	StdoutPath string   `json:"stdout_path,omitempty"`
+	StderrPath string   `json:"stderr_path,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type CoverageResult struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`
+}
+
+func (e *Executor) validate(ctx context.Context, task core.TaskRecord, spec core.ValidationSpec, workspace string, outputDir string) ValidationResult {
+	result := ValidationResult{}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		result.violate("create validation dir: %v", err)
+		return result
+	}
+
+	result.Commands, result.ExitCode = runValidation(ctx, e.RunContext, spec, workspace, outputDir)
+	if spec.SuccessCriteria.RequireExitCode0 && result.ExitCode != 0 {
+		result.violate("validation exited with code %d", result.ExitCode)
+	}
+
+	plugin := e.plugin(task.Tool)
+	if plugin == nil {
+		result.Passed = len(result.Violations) == 0
+		return result
+	}
+
+	wsCtx := e.RunContext
+	wsCtx.WorkspacePath = workspace
+	wsCtx.ArtifactRoot = filepath.Join(outputDir, "collect")
+	if err := os.MkdirAll(wsCtx.ArtifactRoot, 0o755); err != nil {
+		result.violate("create collect dir: %v", err)
+		return result
+	}
+
+	artifacts, err := plugin.Collect(ctx, wsCtx)
+	if err != nil {
+		result.violate("collect %s: %v", plugin.ID(), err)
+		return result
+	}
+	findings, err := plugin.Normalize(ctx, wsCtx, artifacts)
+	if err != nil {
+		result.violate("normalize %s: %v", plugin.ID(), err)
+		return result
+	}
+
+	baseline := e.baseline(plugin.ID())
+	known := findingKeys(baseline.Findings)
+	current := findingKeys(findings)
+	result.BaselineFindings = len(known)
+	result.Findings = len(current)
+	for key := range current {
+		if !known[key] {
+			result.NewFindings++
+		}
+	}
+	for key := range known {
+		if !current[key] {
+			result.ResolvedFindings++
+		}
+	}
+	if result.NewFindings > spec.SuccessCriteria.MaxNewFindings {
+		result.violate("%d new findings exceed limit %d", result.NewFindings, spec.SuccessCriteria.MaxNewFindings)
+	}
+
+	if reporter, ok := plugin.(core.CoverageReporter); ok {
+		coverage, err := coverageDelta(ctx, reporter, e.RunContext, baseline.Artifacts, wsCtx, artifacts, task)
+		if err == nil {
+			result.Coverage = &coverage
+		}
+		minDelta := spec.SuccessCriteria.MinCoverageDelta
+		switch {
+		case minDelta <= 0:
+		case err != nil:
+			result.violate("coverage delta: %v", err)
+		case coverage.Delta < minDelta:
+			result.violate("coverage delta %.4f below minimum %.4f", coverage.Delta, minDelta)
+		}
+	}
+
+	result.Passed = len(result.Violations) == 0
+	return result
+}
+
+func (r *ValidationResult) violate(format string, args ...interface{}) {
+	r.Violations = append(r.Violations, fmt.Sprintf(format, args...))
+}
+
+func coverageDelta(ctx context.Context, reporter core.CoverageReporter, baseCtx core.RunContext, baseArtifacts core.ArtifactSet, wsCtx core.RunContext, artifacts core.ArtifactSet, task core.TaskRecord) (CoverageResult, error) {
+	before, err := reporter.Coverage(ctx, baseCtx, baseArtifacts, task)
+	if err != nil {
+		return CoverageResult{}, fmt.Errorf("baseline: %w", err)
+	}
+	after, err := reporter.Coverage(ctx, wsCtx, artifacts, task)
+	if err != nil {
+		return CoverageResult{}, fmt.Errorf("workspace: %w", err)
+	}
+	return CoverageResult{Before: before, After: after, Delta: after - before}, nil
+}
+
+func runValidation(ctx context.Context, runCtx core.RunContext, spec core.ValidationSpec, workspace string, outputDir string) ([]CommandResult, int) {
+	exitCode := 0
+	results := make([]CommandResult, 0, len(spec.Commands))
+	for i, command := range spec.Commands {
+		cmd := runner.Command{
+			Args:           command.Args,
+			Env:            command.Env,
+			Cwd:            workspace,
+			TimeoutSeconds: command.TimeoutSeconds,
+			AllowNonZero:   true,
+			StdoutPath:     filepath.Join(outputDir, fmt.Sprintf("command-%d.stdout.log", i+1)),
+			StderrPath:     filepath.Join(outputDir, fmt.Sprintf("command-%d.stderr.log", i+1)),
+		}
+		execResult, err := runCtx.RunnerRegistry.Get(command.Runner).Run(ctx, cmd)
+		commandResult := CommandResult{
+			Runner:     command.Runner,
+			Args:       command.Args,
+			ExitCode:   execResult.ExitCode,
+			DurationMs: execResult.DurationMs,
+			StdoutPath: execResult.StdoutPath,
+			StderrPath: execResult.StderrPath,
+		}
+		if err != nil {
+			commandResult.ExitCode = 1
+			commandResult.Error = err.Error()
+		}
+		if commandResult.ExitCode != 0 {
+			exitCode = commandResult.ExitCode
+		}
+		results = append(results, commandResult)
+	}
+	return results, exitCode
+}
+
+// findingKeys identifies findings by what they are about rather than by
+// fingerprint: fingerprints include details such as the coverage
+// percentage or a traceback with workspace paths, which change between the
+// baseline and an attempt without the finding being new.
+func findingKeys(findings []core.FindingRecord) map[string]bool {
+	out := make(map[string]bool, len(findings))
+	for _, finding := range findings {
+		out[findingKey(finding)] = true
+	}
+	return out
+}
+
+func findingKey(finding core.FindingRecord) string {
+	switch {
+	case finding.TestID != "":
+		return finding.Tool + "|" + finding.Kind + "|test|" + finding.TestID
+	case finding.FilePath != "":
+		return fmt.Sprintf("%s|%s|file|%s|%d|%s", finding.Tool, finding.Kind, filepath.ToSlash(finding.FilePath), finding.Line, finding.Symbol)
+	default:
+		return finding.Tool + "|" + finding.Kind + "|fp|" + finding.Fingerprint
+	}
+}
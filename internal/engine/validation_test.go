@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"atqos/internal/config"
+	"atqos/internal/core"
+	"atqos/internal/runner"
+)
+
+func TestFindingKeysIgnoreVolatileFingerprints(t *testing.T) {
+	baseline := []core.FindingRecord{
+		{Tool: "coverage", Kind: "coverage_gap", FilePath: "src/a.py", Fingerprint: "cov-41.0"},
+		{Tool: "pytest", Kind: "test_failure", TestID: "tests/test_a.py::test_x", Fingerprint: "trace-/repo"},
+	}
+	attempt := []core.FindingRecord{
+		{Tool: "coverage", Kind: "coverage_gap", FilePath: "src/a.py", Fingerprint: "cov-55.0"},
+		{Tool: "pytest", Kind: "test_failure", TestID: "tests/test_a.py::test_x", Fingerprint: "trace-/repo/artifacts/run-1/worktrees/w1"},
+		{Tool: "pytest", Kind: "test_failure", TestID: "tests/test_a.py::test_y", Fingerprint: "other"},
+	}
+
+	known := findingKeys(baseline)
+	newFindings := 0
+	for key := range findingKeys(attempt) {
+		if !known[key] {
+			newFindings++
+		}
+	}
+	if newFindings != 1 {
+		t.Fatalf("new findings = %d, want 1 (only test_y)", newFindings)
+	}
+}
+
+func TestValidationToolUsesAttemptRunners(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	root := t.TempDir()
+	cfg := config.Default()
+	cfg.Repair.OutputBytes = 200
+	e := &Executor{RunContext: core.RunContext{Config: cfg, ArtifactRoot: root, RunnerRegistry: runner.NewRegistry(root)}}
+
+	for _, tc := range []struct {
+		name    string
+		command core.CommandSpec
+		want    []string
+		notWant string
+		maxLen  int
+	}{
+		{"environment", core.CommandSpec{Runner: "generic", Args: []string{"sh", "-c", "echo marker=$MARK"}, Env: map[string]string{"MARK": "zebra"}}, []string{"(exit 0)", "marker=zebra"}, "", 0},
+		{"timeout", core.CommandSpec{Args: []string{"sh", "-c", "echo started; sleep 30"}, TimeoutSeconds: 1}, []string{"started"}, "(exit 0)", 0},
+		{"output cap", core.CommandSpec{Args: []string{"sh", "-c", "for i in $(seq 1 200); do echo line $i; done"}}, []string{"...[truncated]", "line 200"}, "line 1\n", 200 + len("...[truncated]\n")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := e.validationTool(core.ValidationSpec{Commands: []core.CommandSpec{tc.command}}, t.TempDir(), 1)
+			started := time.Now()
+			output, err := run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if time.Since(started) > 10*time.Second {
+				t.Fatalf("command ran past its timeout")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+			if tc.notWant != "" && strings.Contains(output, tc.notWant) {
+				t.Errorf("output has %q:\n%s", tc.notWant, output)
+			}
+			if tc.maxLen > 0 && len(output) > tc.maxLen {
+				t.Errorf("output is %d bytes, want at most %d", len(output), tc.maxLen)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(e.attemptDir(1), "tool-validation", "run-1", "command-1.stdout.log")); err != nil {
+		t.Fatalf("tool validation logs not kept under the attempt: %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -11,16 +13,21 @@ import (
 	"atqos/internal/agent"
 	"atqos/internal/core"
 	"atqos/internal/git"
-	"atqos/internal/runner"
 	"atqos/internal/store"
 )
 
+const claimBackoff = 250 * time.Millisecond
+
 type Executor struct {
-	Store       *store.SQLiteStore
+	Store       store.Store
 	RunContext  core.RunContext
-	Agent       agent.Agent
+	Agents      *agent.Router
 	GitStrategy git.Strategy
 	Plugins     []core.Plugin
+	Baselines   map[string]Baseline
+
+	mu    sync.Mutex
+	usage usageTracker
 }
 
 func (e *Executor) Run(ctx context.Context) error {
@@ -46,16 +53,41 @@ func (e *Executor) Run(ctx context.Context) error {
 
 func (e *Executor) runWorker(ctx context.Context, workerID string, checkpoint *checkpointTracker) {
 	for {
+		if e.budgetExceeded() {
+			return
+		}
 		task, err := e.Store.ClaimNextTask(ctx, e.RunContext.RunID, workerID)
+		if errors.Is(err, store.ErrConflict) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(claimBackoff):
+			}
+			continue
+		}
 		if err != nil || task == nil {
 			return
 		}
 
+		previous, err := e.Store.ListAttempts(ctx, task.ID)
+		if err != nil {
+			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", `{"error":"failed to list attempts"}`)
+			return
+		}
+		if candidates := e.candidateAgents(*task, previous); len(candidates) > 1 {
+			e.runSpeculative(ctx, *task, len(previous), candidates)
+			if checkpoint.ShouldRun() {
+				_ = e.runCheckpoint(ctx)
+			}
+			continue
+		}
+		taskAgent := e.Agents.Select(task.TaskType, task.Tool, task.Severity, failedAttempts(previous))
+
 		attempt := core.AttemptRecord{
 			TaskID:    task.ID,
-			AttemptNo: 1,
+			AttemptNo: len(previous) + 1,
 			Status:    "running",
-			AgentName: e.Agent.Name(),
+			AgentName: taskAgent.Name(),
 			StartedAt: time.Now(),
 		}
 		attemptID, err := e.Store.CreateAttempt(ctx, attempt)
@@ -67,45 +99,31 @@ func (e *Executor) runWorker(ctx context.Context, workerID string, checkpoint *c
 		workspace, err := e.GitStrategy.PrepareWorkspace(ctx, e.RunContext.RepoPath, task.ID)
 		if err != nil {
 			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", `{"error":"failed to prepare workspace"}`)
-			_ = e.Store.FinishAttempt(ctx, attemptID, "failed", `{"error":"workspace failure"}`, 1)
+			_ = e.Store.FinishAttempt(ctx, core.AttemptRecord{ID: attemptID, TaskID: task.ID, Status: "failed", SummaryJSON: `{"error":"workspace failure"}`, ValidationExitCode: 1})
 			return
 		}
 
 		validationSpec, err := validationSpec(task.ValidationJSON)
 		if err != nil {
 			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", `{"error":"invalid validation spec"}`)
-			_ = e.Store.FinishAttempt(ctx, attemptID, "failed", `{"error":"validation spec failure"}`, 1)
+			_ = e.Store.FinishAttempt(ctx, core.AttemptRecord{ID: attemptID, TaskID: task.ID, Status: "failed", SummaryJSON: `{"error":"validation spec failure"}`, ValidationExitCode: 1})
 			return
 		}
 
-		agentReq := agent.Request{
-			SchemaVersion: 1,
-			RunID:         e.RunContext.RunID,
-			TaskID:        task.ID,
-			TaskType:      task.TaskType,
-			Tool:          task.Tool,
-			RepoPath:      e.RunContext.RepoPath,
-			WorkspacePath: workspace.Path,
-			AllowedPaths:  e.RunContext.Config.AllowedPaths,
-			ReadOnlyPaths: []string{".git", e.RunContext.ArtifactRoot},
-			Instructions:  task.Description,
-			Validation: agent.Validation{
-				Commands: validationStrings(validationSpec),
-			},
-		}
-
-		_, agentErr := e.Agent.Invoke(ctx, agentReq)
-
-		validationExit := runValidation(ctx, e.RunContext, validationSpec, workspace.Path)
-		status := "succeeded"
-		if agentErr != nil || validationExit != 0 {
-			status = "failed"
-		}
+		agentReq := e.agentRequest(taskAgent, *task, validationSpec, workspace)
 
-		_ = e.Store.FinishAttempt(ctx, attemptID, status, "", validationExit)
-		if status == "succeeded" {
+		outcome := e.runAttempt(ctx, taskAgent, *task, attemptID, agentReq, validationSpec, workspace)
+		_ = e.Store.FinishAttempt(ctx, outcome.Record(task.ID, attemptID))
+		escalated := e.escalate(ctx, *task, attemptID, outcome)
+		switch {
+		case outcome.Status == "succeeded":
 			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "succeeded", "")
-		} else {
+			e.spawnFollowups(ctx, *task, attemptID, outcome)
+		case escalated:
+			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", escalationJSON(attemptID, outcome))
+		case attempt.AttemptNo < maxAttempts(task.RetryPolicyJSON):
+			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "queued", "")
+		default:
 			_ = e.Store.UpdateTaskStatus(ctx, task.ID, "blocked", "")
 		}
 
@@ -121,7 +139,7 @@ func (e *Executor) runCheckpoint(ctx context.Context) error {
 	if err := e.RunContext.EventLog.Emit(core.Event{
 		RunID:     e.RunContext.RunID,
 		Level:     "info",
-		EventType: "checkpoint_started",
+		EventType: core.EventCheckpointStarted,
 	}); err != nil {
 		return err
 	}
@@ -147,6 +165,7 @@ func (e *Executor) runCheckpoint(ctx context.Context) error {
 		if err := e.Store.InsertFindings(ctx, findings); err != nil {
 			return err
 		}
+		e.setBaseline(plugin.ID(), Baseline{Artifacts: artifacts, Findings: findings})
 
 		tasks, err := plugin.Plan(ctx, e.RunContext, findings)
 		if err != nil {
@@ -171,7 +190,7 @@ func (e *Executor) runCheckpoint(ctx context.Context) error {
 	return e.RunContext.EventLog.Emit(core.Event{
 		RunID:     e.RunContext.RunID,
 		Level:     "info",
-		EventType: "checkpoint_finished",
+		EventType: core.EventCheckpointFinished,
 	})
 }
 
@@ -183,6 +202,45 @@ func validationSpec(validationJSON string) (core.ValidationSpec, error) {
 	return spec, nil
 }
 
+func (e *Executor) agentRequest(taskAgent agent.Agent, task core.TaskRecord, spec core.ValidationSpec, workspace git.Workspace) agent.Request {
+	return agent.Request{
+		SchemaVersion: agent.Negotiate(taskAgent),
+		RunID:         e.RunContext.RunID,
+		TaskID:        task.ID,
+		TaskType:      task.TaskType,
+		Tool:          task.Tool,
+		RepoPath:      e.RunContext.RepoPath,
+		WorkspacePath: workspace.Path,
+		AllowedPaths:  e.RunContext.Config.AllowedPaths,
+		ReadOnlyPaths: []string{".git", e.RunContext.ArtifactRoot},
+		Instructions:  task.Description,
+		Validation: agent.Validation{
+			Commands: validationStrings(spec),
+			Argv:     validationArgv(spec),
+		},
+	}
+}
+
+func failedAttempts(attempts []core.AttemptRecord) int {
+	failed := 0
+	for _, attempt := range attempts {
+		if attempt.Status == "failed" {
+			failed++
+		}
+	}
+	return failed
+}
+
+func maxAttempts(retryPolicyJSON string) int {
+	var policy struct {
+		MaxAttempts int `json:"max_attempts"`
+	}
+	if err := json.Unmarshal([]byte(retryPolicyJSON), &policy); err != nil || policy.MaxAttempts < 1 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
 func validationStrings(spec core.ValidationSpec) []string {
 	out := make([]string, 0, len(spec.Commands))
 	for _, command := range spec.Commands {
@@ -191,23 +249,40 @@ func validationStrings(spec core.ValidationSpec) []string {
 	return out
 }
 
-func runValidation(ctx context.Context, runCtx core.RunContext, spec core.ValidationSpec, workspace string) int {
-	exitCode := 0
-	for _, command := range spec.Commands {
-		cmd := runner.Command{
-			Args:         command.Args,
-			Cwd:          workspace,
-			AllowNonZero: true,
-		}
-		result, err := runCtx.RunnerRegistry.Get(command.Runner).Run(ctx, cmd)
-		if err != nil {
-			exitCode = 1
-		}
-		if result.ExitCode != 0 {
-			exitCode = result.ExitCode
+func (e *Executor) attemptDir(attemptID int64) string {
+	return filepath.Join(e.RunContext.ArtifactRoot, "attempts", fmt.Sprintf("attempt-%d", attemptID))
+}
+
+func (e *Executor) plugin(id string) core.Plugin {
+	for _, plugin := range e.Plugins {
+		if plugin.ID() == id {
+			return plugin
 		}
 	}
-	return exitCode
+	return nil
+}
+
+func (e *Executor) baseline(tool string) Baseline {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.Baselines[tool]
+}
+
+func (e *Executor) setBaseline(tool string, baseline Baseline) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Baselines == nil {
+		e.Baselines = make(map[string]Baseline)
+	}
+	e.Baselines[tool] = baseline
+}
+
+func validationArgv(spec core.ValidationSpec) [][]string {
+	out := make([][]string, 0, len(spec.Commands))
+	for _, command := range spec.Commands {
+		out = append(out, command.Args)
+	}
+	return out
 }
 
 func joinArgs(args []string) string {
@@ -0,0 +1,144 @@
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"atqos/internal/core"
+)
+
+// stamper assigns the schema version, sequence number and timestamp to
+// each event and, when chaining, links it to the previous event's hash.
+type stamper struct {
+	chain bool
+	seq   int64
+	last  time.Time
+	prev  string
+}
+
+func (s *stamper) stamp(event core.Event) (core.Event, error) {
+	now := time.Now().UTC()
+	if now.Before(s.last) {
+		now = s.last
+	}
+	event.Schema = core.EventSchemaVersion
+	event.Seq = s.seq + 1
+	event.TS = now
+	event.PrevHash = ""
+	event.Hash = ""
+	if s.chain {
+		event.PrevHash = s.prev
+		hash, err := HashEvent(event)
+		if err != nil {
+			return core.Event{}, err
+		}
+		event.Hash = hash
+	}
+	s.seq = event.Seq
+	s.last = now
+	s.prev = event.Hash
+	return event, nil
+}
+
+// HashEvent returns the hex SHA256 of the event's JSON encoding without its
+// hash field. Because Hash is the last field of core.Event, that encoding is
+// the written line up to the `,"hash":` suffix.
+func HashEvent(event core.Event) (string, error) {
+	event.Hash = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Record is one decoded line of an event log. Payload holds the typed
+// payload struct for known event types; RawPayload and Line keep the bytes
+// as written.
+type Record struct {
+	core.Event
+	RawPayload json.RawMessage
+	Line       []byte
+	LineNo     int
+}
+
+// Scan decodes r line by line, calling fn for each non-empty line. Payloads
+// that do not match their event type's schema are returned as errors.
+func Scan(r io.Reader, fn func(Record) error) error {
+	reader := bufio.NewReader(r)
+	lineNo := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lineNo++
+			line = bytes.TrimRight(line, "\r\n")
+			if len(bytes.TrimSpace(line)) > 0 {
+				record, decodeErr := DecodeRecord(line, lineNo)
+				if decodeErr != nil {
+					return decodeErr
+				}
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ScanFile is Scan over the file at path.
+func ScanFile(path string, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Scan(file, fn)
+}
+
+// DecodeRecord decodes a single event log line; lineNo is only used in
+// errors and recorded on the result.
+func DecodeRecord(line []byte, lineNo int) (Record, error) {
+	var decoded struct {
+		core.Event
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &decoded); err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", lineNo, err)
+	}
+	record := Record{Event: decoded.Event, RawPayload: decoded.Payload, Line: line, LineNo: lineNo}
+	payload, err := core.DecodeEventPayload(decoded.EventType, decoded.Payload)
+	if err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", lineNo, err)
+	}
+	record.Payload = payload
+	return record, nil
+}
+
+// lastEvent returns the final event in the log at path, or a zero event if
+// the log is missing or empty.
+func lastEvent(path string) (core.Event, error) {
+	var last core.Event
+	err := ScanFile(path, func(record Record) error {
+		last = record.Event
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return core.Event{}, nil
+	}
+	return last, err
+}
@@ -0,0 +1,116 @@
+package eventlog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atqos/internal/core"
+)
+
+func writeChainedLog(t *testing.T, n int) [][]byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	log, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fanout := NewFanout(log).WithHashChain(true)
+	for i := 1; i <= n; i++ {
+		event := core.Event{RunID: "run-1", Level: LevelInfo, EventType: core.EventCollectStarted, TaskID: int64(i)}
+		if err := fanout.Emit(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fanout.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(bytes.TrimRight(data, "\n"), []byte("\n"))
+}
+
+func verifyLines(t *testing.T, lines [][]byte) VerifyReport {
+	t.Helper()
+	report, err := Verify(bytes.NewReader(bytes.Join(lines, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestHashEventIgnoresHash(t *testing.T) {
+	event := core.Event{Seq: 1, RunID: "run-1", EventType: core.EventCollectStarted, PrevHash: "abc"}
+	want, err := HashEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Hash = "stale"
+	if got, _ := HashEvent(event); got != want {
+		t.Fatalf("hash depends on Hash field: %s != %s", got, want)
+	}
+	event.PrevHash = "abd"
+	if got, _ := HashEvent(event); got == want {
+		t.Fatal("hash ignores prev_hash")
+	}
+}
+
+func TestVerifyChainedLog(t *testing.T) {
+	report := verifyLines(t, writeChainedLog(t, 4))
+	if !report.OK() {
+		t.Fatalf("problems: %v", report.Problems)
+	}
+	if !report.Chained || report.Events != 4 || report.FirstSeq != 1 || report.LastSeq != 4 || report.RunID != "run-1" {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	cases := map[string]struct {
+		edit func([][]byte) [][]byte
+		want string
+	}{
+		"modified": {
+			edit: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(`"level":"info"`), []byte(`"level":"warn"`), 1)
+				return lines
+			},
+			want: "hash mismatch",
+		},
+		"deleted": {
+			edit: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			want: "prev_hash does not match",
+		},
+		"reordered": {
+			edit: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			want: "prev_hash does not match",
+		},
+		"unchained tail": {
+			edit: func(lines [][]byte) [][]byte {
+				lines[3] = []byte(`{"schema":1,"seq":4,"ts":"2100-01-01T00:00:00Z","run_id":"run-1","level":"info","event_type":"collect_started"}` + "\n")
+				return lines
+			},
+			want: "event has no hash",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report := verifyLines(t, tc.edit(writeChainedLog(t, 4)))
+			for _, problem := range report.Problems {
+				if strings.Contains(problem, tc.want) {
+					return
+				}
+			}
+			t.Fatalf("problems %v do not mention %q", report.Problems, tc.want)
+		})
+	}
+}
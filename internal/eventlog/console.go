@@ -0,0 +1,137 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"atqos/internal/core"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+
+	maxConsoleValue = 120
+)
+
+var levelColors = map[string]string{
+	LevelDebug: "\x1b[90m",
+	LevelInfo:  "\x1b[36m",
+	LevelWarn:  "\x1b[33m",
+	LevelError: "\x1b[31m",
+}
+
+type Console struct {
+	mu    sync.Mutex
+	out   io.Writer
+	color bool
+}
+
+func NewConsole(out io.Writer, color string) *Console {
+	enabled := false
+	switch color {
+	case ColorAlways:
+		enabled = true
+	case ColorNever:
+	default:
+		enabled = isTerminal(out) && os.Getenv("NO_COLOR") == ""
+	}
+	return &Console{out: out, color: enabled}
+}
+
+func (c *Console) Emit(event core.Event) error {
+	var b strings.Builder
+	level := strings.ToLower(event.Level)
+	if level == "" {
+		level = LevelInfo
+	}
+	ts := event.TS
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	b.WriteString(ts.Local().Format("15:04:05.000"))
+	b.WriteByte(' ')
+	if c.color {
+		b.WriteString(levelColors[level])
+	}
+	fmt.Fprintf(&b, "%-5s", strings.ToUpper(level))
+	if c.color {
+		b.WriteString("\x1b[0m")
+	}
+	fmt.Fprintf(&b, " %-22s", event.EventType)
+	if event.Tool != "" {
+		fmt.Fprintf(&b, " tool=%s", event.Tool)
+	}
+	if event.TaskID != 0 {
+		fmt.Fprintf(&b, " task=%d", event.TaskID)
+	}
+	if event.AttemptID != 0 {
+		fmt.Fprintf(&b, " attempt=%d", event.AttemptID)
+	}
+	for _, field := range payloadFields(event.Payload) {
+		b.WriteByte(' ')
+		b.WriteString(field)
+	}
+	b.WriteByte('\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := io.WriteString(c.out, b.String())
+	return err
+}
+
+func (c *Console) Close() error {
+	return nil
+}
+
+func payloadFields(payload interface{}) []string {
+	if payload == nil {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return []string{"payload=" + consoleValue(data)}
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, key+"="+consoleValue(fields[key]))
+	}
+	return out
+}
+
+func consoleValue(raw []byte) string {
+	value := string(raw)
+	var s string
+	if json.Unmarshal(raw, &s) == nil && !strings.ContainsAny(s, " \t\n\"") {
+		value = s
+	}
+	if len(value) > maxConsoleValue {
+		value = value[:maxConsoleValue] + "..."
+	}
+	return value
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
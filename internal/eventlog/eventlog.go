@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 
 	"atqos/internal/core"
 )
@@ -30,23 +29,15 @@ func New(path string) (*EventLog, error) {
 }
 
 func (l *EventLog) Emit(event core.Event) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	payload := struct {
-		TS string `json:"ts"`
-		core.Event
-	}{
-		TS:    time.Now().UTC().Format(time.RFC3339),
-		Event: event,
-	}
-
-	data, err := json.Marshal(payload)
+	data, err := encode(event)
 	if err != nil {
 		return err
 	}
 
-	if _, err := l.file.Write(append(data, '\n')); err != nil {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(data); err != nil {
 		return err
 	}
 
@@ -60,6 +51,14 @@ func (l *EventLog) Close() error {
 	return l.file.Close()
 }
 
+func encode(event core.Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
 func filepathDir(path string) string {
 	dir := filepath.Dir(path)
 	if dir == "." {
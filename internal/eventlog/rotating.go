@@ -0,0 +1,108 @@
+package eventlog
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"atqos/internal/core"
+)
+
+// RotatingFile writes JSON lines like EventLog but starts a new file once
+// the current one would exceed maxBytes, keeping maxFiles rotated copies
+// as path.1 (newest) through path.N.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func NewRotatingFile(path string, maxBytes int64, maxFiles int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepathDir(path), 0o755); err != nil {
+		return nil, err
+	}
+	r := &RotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) Emit(event core.Event) error {
+	data, err := encode(event)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("event file %s is closed", r.path)
+	}
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(data)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return fmt.Errorf("rotate %s: %w", r.path, err)
+		}
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	return err
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	if r.maxFiles <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+	_ = os.Remove(rotatedName(r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedName(r.path, i), rotatedName(r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, rotatedName(r.path, 1)); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func rotatedName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
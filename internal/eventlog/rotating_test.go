@@ -0,0 +1,60 @@
+package eventlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFileShiftsAndCapsFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	// Every event exceeds maxBytes, so each one after the first rotates.
+	log, err := NewRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitN(t, log, 5)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, seq := range map[string]int64{path: 5, path + ".1": 4, path + ".2": 3} {
+		seqs := fileSeqs(t, name)
+		if len(seqs) != 1 || seqs[0] != seq {
+			t.Fatalf("%s holds seqs %v, want [%d]", filepath.Base(name), seqs, seq)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("%s.3 exists beyond maxFiles", path)
+	}
+}
+
+func TestRotatingFileWithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	log, err := NewRotatingFile(path, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitN(t, log, 3)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if seqs := fileSeqs(t, path); len(seqs) != 1 || seqs[0] != 3 {
+		t.Fatalf("seqs = %v, want [3]", seqs)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatal("backup written with maxFiles 0")
+	}
+}
+
+func fileSeqs(t *testing.T, path string) []int64 {
+	t.Helper()
+	var seqs []int64
+	if err := ScanFile(path, func(record Record) error {
+		seqs = append(seqs, record.Seq)
+		return nil
+	}); err != nil {
+		t.Fatalf("scan %s: %v", path, err)
+	}
+	return seqs
+}
@@ -0,0 +1,135 @@
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"atqos/internal/core"
+)
+
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelRanks = map[string]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+type Sink interface {
+	core.EventLogger
+	Close() error
+}
+
+func ParseLevel(level string) (string, error) {
+	if level == "" {
+		return LevelInfo, nil
+	}
+	level = strings.ToLower(level)
+	if _, ok := levelRanks[level]; !ok {
+		return "", fmt.Errorf("unknown event level %q", level)
+	}
+	return level, nil
+}
+
+func levelRank(level string) int {
+	if rank, ok := levelRanks[strings.ToLower(level)]; ok {
+		return rank
+	}
+	return levelRanks[LevelInfo]
+}
+
+// Fanout stamps each event once (see stamper) and delivers the same stamped
+// event to every sink, so all sinks agree on sequence numbers and hashes.
+type Fanout struct {
+	mu      sync.Mutex
+	stamper stamper
+	sinks   []Sink
+}
+
+func NewFanout(sinks ...Sink) *Fanout {
+	return &Fanout{sinks: sinks}
+}
+
+// WithHashChain links each event to the previous one through prev_hash and
+// hash.
+func (f *Fanout) WithHashChain(enabled bool) *Fanout {
+	f.stamper.chain = enabled
+	return f
+}
+
+// Resume continues the sequence (and hash chain) of the event log at path
+// so that reopening a log keeps it verifiable.
+func (f *Fanout) Resume(path string) error {
+	last, err := lastEvent(path)
+	if err != nil {
+		return fmt.Errorf("resume event log: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stamper.seq = last.Seq
+	f.stamper.last = last.TS
+	f.stamper.prev = last.Hash
+	return nil
+}
+
+func (f *Fanout) Add(sink Sink) {
+	f.sinks = append(f.sinks, sink)
+}
+
+func (f *Fanout) Emit(event core.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	event, err := f.stamper.stamp(event)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, sink := range f.sinks {
+		if err := sink.Emit(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Fanout) Close() error {
+	if f == nil {
+		return nil
+	}
+	var errs []error
+	for _, sink := range f.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type filtered struct {
+	Sink
+	min int
+}
+
+// WithLevel drops events below level before they reach sink.
+func WithLevel(sink Sink, level string) Sink {
+	if levelRank(level) == levelRanks[LevelDebug] {
+		return sink
+	}
+	return filtered{Sink: sink, min: levelRank(level)}
+}
+
+func (f filtered) Emit(event core.Event) error {
+	if levelRank(event.Level) < f.min {
+		return nil
+	}
+	return f.Sink.Emit(event)
+}
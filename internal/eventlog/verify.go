@@ -0,0 +1,112 @@
+package eventlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"atqos/internal/core"
+)
+
+type VerifyReport struct {
+	Events   int
+	RunID    string
+	FirstSeq int64
+	LastSeq  int64
+	Chained  bool
+	LastHash string
+	Problems []string
+}
+
+func (r VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks that an event log is an unbroken, append-only sequence:
+// every line uses a known schema version, sequence numbers start at 1 and
+// increase by one, timestamps never go backwards, and all events belong to
+// one run. If the log is hash chained, each event's hash must match its
+// bytes and its prev_hash the hash of the line before, so any edit,
+// insertion, deletion or reordering is detected. A decode error stops the
+// scan and is returned as the error.
+func Verify(r io.Reader) (VerifyReport, error) {
+	var report VerifyReport
+	var lastTS time.Time
+	problem := func(record Record, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, fmt.Sprintf("line %d (seq %d): ", record.LineNo, record.Seq)+fmt.Sprintf(format, args...))
+	}
+
+	err := Scan(r, func(record Record) error {
+		first := report.Events == 0
+		report.Events++
+
+		if record.Schema == 0 {
+			problem(record, "missing schema version (written before sequenced events)")
+		} else if record.Schema > core.EventSchemaVersion {
+			problem(record, "schema %d is newer than supported %d", record.Schema, core.EventSchemaVersion)
+		}
+		if !core.KnownEventType(record.EventType) {
+			problem(record, "unknown event type %q", record.EventType)
+		}
+
+		if first {
+			report.RunID = record.RunID
+			report.FirstSeq = record.Seq
+			report.Chained = record.Hash != ""
+			if record.Seq != 1 {
+				problem(record, "log starts at seq %d, want 1", record.Seq)
+			}
+		} else {
+			if record.Seq != report.LastSeq+1 {
+				problem(record, "seq %d follows %d", record.Seq, report.LastSeq)
+			}
+			if record.RunID != report.RunID {
+				problem(record, "run_id %q differs from %q", record.RunID, report.RunID)
+			}
+		}
+		if record.TS.IsZero() {
+			problem(record, "missing timestamp")
+		} else if record.TS.Before(lastTS) {
+			problem(record, "timestamp %s is before previous %s", record.TS.Format(time.RFC3339Nano), lastTS.Format(time.RFC3339Nano))
+		}
+
+		switch {
+		case report.Chained && record.Hash == "":
+			problem(record, "hash chain is broken: event has no hash")
+		case !report.Chained && record.Hash != "":
+			problem(record, "hash chain starts mid-log")
+		case report.Chained:
+			if record.PrevHash != report.LastHash {
+				problem(record, "prev_hash does not match the previous event's hash")
+			}
+			if sum, ok := lineHash(record.Line, record.Hash); !ok {
+				problem(record, "hash field is not the last field of the line")
+			} else if sum != record.Hash {
+				problem(record, "hash mismatch: event was modified")
+			}
+		}
+
+		report.LastSeq = record.Seq
+		report.LastHash = record.Hash
+		if record.TS.After(lastTS) {
+			lastTS = record.TS
+		}
+		return nil
+	})
+	return report, err
+}
+
+// lineHash hashes line with its trailing `,"hash":"..."` removed, which is
+// exactly what HashEvent hashed when the event was written.
+func lineHash(line []byte, hash string) (string, bool) {
+	suffix := []byte(`,"hash":"` + hash + `"}`)
+	if !bytes.HasSuffix(line, suffix) {
+		return "", false
+	}
+	body := append(append([]byte{}, line[:len(line)-len(suffix)]...), '}')
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), true
+}
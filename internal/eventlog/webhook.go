@@ -0,0 +1,183 @@
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"atqos/internal/core"
+)
+
+type WebhookOptions struct {
+	Headers       http.Header
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int
+	Timeout       time.Duration
+	QueueSize     int
+}
+
+// Webhook POSTs events as JSON arrays from a background goroutine so a slow
+// or unreachable endpoint never blocks the run. Events that cannot be queued
+// or delivered are counted and reported by Close.
+type Webhook struct {
+	url     string
+	opts    WebhookOptions
+	client  *http.Client
+	queue   chan []byte
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+	lastErr error
+}
+
+func NewWebhook(url string, opts WebhookOptions) *Webhook {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	w := &Webhook{
+		url:    url,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan []byte, opts.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *Webhook) Emit(event core.Event) error {
+	data, err := encode(event)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("webhook %s is closed", w.url)
+	}
+	select {
+	case w.queue <- bytes.TrimRight(data, "\n"):
+	default:
+		w.dropped++
+		w.lastErr = fmt.Errorf("queue full")
+	}
+	return nil
+}
+
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dropped > 0 {
+		return fmt.Errorf("webhook %s: %d events not delivered: %v", w.url, w.dropped, w.lastErr)
+	}
+	return nil
+}
+
+func (w *Webhook) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.send(batch); err != nil {
+			w.mu.Lock()
+			w.dropped += len(batch)
+			w.lastErr = err
+			w.mu.Unlock()
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case data, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) >= w.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (w *Webhook) send(batch [][]byte) error {
+	body := append([]byte{'['}, bytes.Join(batch, []byte{','})...)
+	body = append(body, ']')
+
+	backoff := 250 * time.Millisecond
+	var err error
+	for attempt := 0; attempt <= w.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > 5*time.Second {
+				backoff = 5 * time.Second
+			}
+		}
+		var retry bool
+		if retry, err = w.post(body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (w *Webhook) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range w.opts.Headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,121 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atqos/internal/core"
+)
+
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	batches  [][]core.Event
+	requests int
+	statuses []int
+}
+
+// newWebhookServer answers with statuses in order, then 200s.
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if len(s.statuses) > 0 {
+			status := s.statuses[0]
+			s.statuses = s.statuses[1:]
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+		}
+		var batch []core.Event
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("decode batch: %v", err)
+		}
+		s.batches = append(s.batches, batch)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func emitN(t *testing.T, sink Sink, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		if err := sink.Emit(core.Event{Seq: int64(i), EventType: core.EventCollectStarted}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWebhookBatchesAndFlushesOnClose(t *testing.T) {
+	server := newWebhookServer(t)
+	hook := NewWebhook(server.URL, WebhookOptions{BatchSize: 3, FlushInterval: time.Hour})
+	emitN(t, hook, 7)
+	if err := hook.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := server.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Fatalf("batch sizes = %v, want [3 3 1]", got)
+	}
+	if server.batches[2][0].Seq != 7 {
+		t.Fatalf("last batch = %+v", server.batches[2])
+	}
+	if err := hook.Emit(core.Event{}); err == nil {
+		t.Fatal("emit after close succeeded")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []int
+		requests int
+		dropped  bool
+	}{
+		{"429 retried", []int{http.StatusTooManyRequests}, 2, false},
+		{"5xx retried", []int{http.StatusServiceUnavailable}, 2, false},
+		{"4xx not retried", []int{http.StatusBadRequest}, 1, true},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway}, 2, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newWebhookServer(t, tc.statuses...)
+			hook := NewWebhook(server.URL, WebhookOptions{BatchSize: 2, FlushInterval: time.Hour, MaxRetries: 1})
+			emitN(t, hook, 2)
+			err := hook.Close()
+			if server.requests != tc.requests {
+				t.Fatalf("requests = %d, want %d", server.requests, tc.requests)
+			}
+			if !tc.dropped {
+				if err != nil {
+					t.Fatalf("close: %v", err)
+				}
+				if got := server.sizes(); len(got) != 1 || got[0] != 2 {
+					t.Fatalf("batch sizes = %v", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "2 events not delivered") {
+				t.Fatalf("close err = %v, want 2 dropped events", err)
+			}
+		})
+	}
+}
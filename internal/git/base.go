@@ -0,0 +1,84 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type Base struct {
+	Commit    string
+	DirtyDiff Patch
+	Snapshot  string
+}
+
+func (b Base) Ref() string {
+	if b.Snapshot != "" {
+		return b.Snapshot
+	}
+	return b.Commit
+}
+
+func (b Base) Dirty() bool {
+	return len(b.DirtyDiff.Files) > 0
+}
+
+// IsRepo reports whether path is inside a git work tree with at least one
+// commit.
+func IsRepo(ctx context.Context, path string) bool {
+	_, err := headCommit(ctx, path)
+	return err == nil
+}
+
+func ResolveBase(ctx context.Context, repoPath string, includeDirty bool, excludes ...string) (Base, error) {
+	head, err := headCommit(ctx, repoPath)
+	if err != nil {
+		return Base{}, err
+	}
+	base := Base{Commit: head}
+
+	base.DirtyDiff, err = Diff(ctx, repoPath, excludes...)
+	if err != nil {
+		return Base{}, err
+	}
+	if !includeDirty || !base.Dirty() {
+		return base, nil
+	}
+
+	base.Snapshot, err = SnapshotCommit(ctx, repoPath, base.Commit, excludes...)
+	if err != nil {
+		return Base{}, err
+	}
+	return base, nil
+}
+
+func headCommit(ctx context.Context, repoPath string) (string, error) {
+	out, err := gitOutput(ctx, repoPath, "rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolve base commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func identityEnv() []string {
+	return append(os.Environ(),
+		"GIT_AUTHOR_NAME=atqos", "GIT_AUTHOR_EMAIL=atqos@localhost",
+		"GIT_COMMITTER_NAME=atqos", "GIT_COMMITTER_EMAIL=atqos@localhost",
+	)
+}
+
+func SnapshotCommit(ctx context.Context, repoPath string, parent string, excludes ...string) (string, error) {
+	tree, err := SnapshotTree(ctx, repoPath, excludes...)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "commit-tree", tree, "-p", parent, "-m", "atqos snapshot of uncommitted changes")
+	cmd.Env = identityEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("snapshot commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,231 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	CopyModeAuto    = "auto"
+	CopyModeReflink = "reflink"
+	// CopyModeHardlink is accepted for older configs and behaves like
+	// CopyModeAuto: a hardlink shares its inode with the checkout, so an
+	// in-place write in the workspace would change the user's file.
+	CopyModeHardlink = "hardlink"
+	CopyModeCopy     = "copy"
+	CopyModeOverlay  = "overlay"
+)
+
+type CopyStrategy struct {
+	Root     string
+	Mode     string
+	Excludes *Excludes
+
+	mu     sync.Mutex
+	mounts map[string]string
+}
+
+func NewCopy(root string, mode string, excludes *Excludes) *CopyStrategy {
+	if mode == "" || mode == CopyModeHardlink {
+		mode = CopyModeAuto
+	}
+	return &CopyStrategy{Root: root, Mode: mode, Excludes: excludes, mounts: make(map[string]string)}
+}
+
+func (s *CopyStrategy) PrepareWorkspace(ctx context.Context, repoPath string, taskID int64) (Workspace, error) {
+	return s.prepare(ctx, repoPath, fmt.Sprintf("task-%d", taskID))
+}
+
+func (s *CopyStrategy) PrepareCandidate(ctx context.Context, repoPath string, taskID int64, candidate int) (Workspace, error) {
+	return s.prepare(ctx, repoPath, fmt.Sprintf("task-%d-c%d", taskID, candidate))
+}
+
+func (s *CopyStrategy) prepare(ctx context.Context, repoPath string, name string) (Workspace, error) {
+	path := filepath.Join(s.Root, name)
+	if err := os.RemoveAll(path); err != nil {
+		return Workspace{}, fmt.Errorf("clear workspace: %w", err)
+	}
+
+	if s.Mode == CopyModeOverlay {
+		if err := s.mount(repoPath, path); err != nil {
+			return Workspace{}, err
+		}
+	} else if err := s.copyTree(repoPath, path); err != nil {
+		_ = os.RemoveAll(path)
+		return Workspace{}, err
+	}
+
+	if err := commitBaseline(ctx, path); err != nil {
+		_ = s.FinalizeWorkspace(ctx, Workspace{Path: path})
+		return Workspace{}, err
+	}
+	return Workspace{Path: path}, nil
+}
+
+func (s *CopyStrategy) FinalizeWorkspace(ctx context.Context, ws Workspace) error {
+	s.mu.Lock()
+	layers, mounted := s.mounts[ws.Path]
+	delete(s.mounts, ws.Path)
+	s.mu.Unlock()
+
+	if mounted {
+		if err := unmountOverlay(ws.Path); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(layers); err != nil {
+			return fmt.Errorf("remove overlay layers: %w", err)
+		}
+	}
+	if err := os.RemoveAll(ws.Path); err != nil {
+		return fmt.Errorf("remove workspace: %w", err)
+	}
+	return nil
+}
+
+// mount overlays the repository at path. Whatever a copy would skip is
+// hidden by a whiteout in the upper layer, so the workspace sees the same
+// tree as a copy and gets its own git repository.
+func (s *CopyStrategy) mount(repoPath string, path string) error {
+	layers := path + ".layers"
+	upper := filepath.Join(layers, "upper")
+	work := filepath.Join(layers, "work")
+	if err := os.RemoveAll(layers); err != nil {
+		return err
+	}
+	for _, dir := range []string{upper, work, path} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	err := s.walk(repoPath, func(rel string, entry fs.DirEntry, skipped bool) error {
+		if !skipped {
+			return nil
+		}
+		target := filepath.Join(upper, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return whiteout(target)
+	})
+	if err != nil {
+		_ = os.RemoveAll(layers)
+		return fmt.Errorf("hide excluded paths: %w", err)
+	}
+	if err := mountOverlay(repoPath, upper, work, path); err != nil {
+		_ = os.RemoveAll(layers)
+		return err
+	}
+	s.mu.Lock()
+	s.mounts[path] = layers
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *CopyStrategy) copyTree(src string, dst string) error {
+	return s.walk(src, func(rel string, entry fs.DirEntry, skipped bool) error {
+		if skipped {
+			return nil
+		}
+		path := filepath.Join(src, rel)
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return s.copyFile(path, target, rel, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// walk calls fn for every entry under src. Entries a workspace leaves out
+// (.git, the workspace root itself and excludes) are reported once with
+// skipped set and not descended into.
+func (s *CopyStrategy) walk(src string, fn func(rel string, entry fs.DirEntry, skipped bool) error) error {
+	root := filepath.Clean(s.Root)
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && (entry.Name() == ".git" || withinDir(path, root) || s.Excludes.Match(rel, entry.IsDir())) {
+			if err := fn(rel, entry, true); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(rel, entry, false)
+	})
+}
+
+func (s *CopyStrategy) copyFile(src string, dst string, rel string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if s.Mode == CopyModeAuto || s.Mode == CopyModeReflink {
+		err := reflink(in, out)
+		if err == nil {
+			return nil
+		}
+		if s.Mode == CopyModeReflink {
+			return fmt.Errorf("reflink %s: %w", rel, err)
+		}
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("copy %s: %w", rel, err)
+	}
+	return out.Close()
+}
+
+func withinDir(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func commitBaseline(ctx context.Context, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := runGit(ctx, dir, "init", "--quiet"); err != nil {
+			return fmt.Errorf("init workspace: %w", err)
+		}
+	}
+	if err := runGit(ctx, dir, "add", "-A"); err != nil {
+		return fmt.Errorf("stage workspace baseline: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "-c", "core.hooksPath=/dev/null", "commit", "--quiet", "--allow-empty", "--no-verify", "-m", "atqos workspace baseline")
+	cmd.Env = identityEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("commit workspace baseline: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
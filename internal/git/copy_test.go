@@ -0,0 +1,90 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyStrategyWorkspaces(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		"src/a.py":      "x = 1\n",
+		"README":        "readme\n",
+		"build/out.bin": "binary\n",
+		"notes.log":     "log\n",
+		"keep.log":      "kept\n",
+	} {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := commitBaseline(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	repoHead, err := headCommit(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excludes := NewExcludes([]string{"build/", "*.log", "!keep.log"})
+
+	for _, mode := range []string{CopyModeCopy, CopyModeAuto, CopyModeHardlink, CopyModeOverlay} {
+		t.Run(mode, func(t *testing.T) {
+			s := NewCopy(t.TempDir(), mode, excludes)
+			ws, err := s.PrepareWorkspace(ctx, repo, 1)
+			if mode == CopyModeOverlay && err != nil {
+				t.Skipf("overlay unavailable: %v", err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range map[string]bool{"src/a.py": true, "README": true, "keep.log": true, "build": false, "notes.log": false} {
+				if _, err := os.Lstat(filepath.Join(ws.Path, name)); (err == nil) != want {
+					t.Errorf("%s present = %v, want %v", name, err == nil, want)
+				}
+			}
+			head, err := headCommit(ctx, ws.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := gitOutput(ctx, ws.Path, "rev-list", "--count", "HEAD")
+			if err != nil || head == repoHead || strings.TrimSpace(string(count)) != "1" {
+				t.Fatalf("workspace history = %s commits at %s, want its own single baseline", count, head)
+			}
+
+			// In-place writes stay in the workspace.
+			for _, name := range []string{"src/a.py", "README"} {
+				file, err := os.OpenFile(filepath.Join(ws.Path, name), os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				file.WriteString("changed\n")
+				file.Close()
+			}
+			for name, want := range map[string]string{"src/a.py": "x = 1\n", "README": "readme\n"} {
+				if data, _ := os.ReadFile(filepath.Join(repo, name)); string(data) != want {
+					t.Errorf("checkout %s = %q after a workspace write", name, data)
+				}
+			}
+
+			if err := s.FinalizeWorkspace(ctx, ws); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(ws.Path); !os.IsNotExist(err) {
+				t.Fatalf("workspace left behind: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type Patch struct {
+	Data       []byte   `json:"-"`
+	Files      []string `json:"files"`
+	Insertions int      `json:"insertions"`
+	Deletions  int      `json:"deletions"`
+}
+
+func Diff(ctx context.Context, dir string, excludes ...string) (Patch, error) {
+	tracked, err := gitOutput(ctx, dir, append([]string{"diff", "--binary", "HEAD"}, pathspec(excludes)...)...)
+	if err != nil {
+		return Patch{}, fmt.Errorf("diff workspace: %w", err)
+	}
+
+	untracked, err := gitOutput(ctx, dir, append([]string{"ls-files", "--others", "--exclude-standard", "-z"}, pathspec(excludes)...)...)
+	if err != nil {
+		return Patch{}, fmt.Errorf("list untracked files: %w", err)
+	}
+
+	var data bytes.Buffer
+	data.Write(tracked)
+	for _, file := range strings.Split(string(untracked), "\x00") {
+		if file == "" {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, "git", "diff", "--binary", "--no-index", "--", "/dev/null", file)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+				return Patch{}, fmt.Errorf("diff untracked %s: %w", file, err)
+			}
+		}
+		data.Write(out)
+	}
+
+	return ParsePatch(data.Bytes()), nil
+}
+
+func ParsePatch(data []byte) Patch {
+	patch := Patch{Data: data}
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			fields := strings.Fields(line)
+			patch.Files = append(patch.Files, strings.TrimPrefix(fields[len(fields)-1], "b/"))
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			patch.Insertions++
+		case strings.HasPrefix(line, "-"):
+			patch.Deletions++
+		}
+	}
+	return patch
+}
+
+func pathspec(excludes []string) []string {
+	if len(excludes) == 0 {
+		return nil
+	}
+	spec := []string{"--", "."}
+	for _, exclude := range excludes {
+		spec = append(spec, ":(exclude)"+exclude)
+	}
+	return spec
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	return cmd.Output()
+}
+
+func SnapshotTree(ctx context.Context, dir string, excludes ...string) (string, error) {
+	tmp, err := os.MkdirTemp("", "atqos-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	indexPath := filepath.Join(tmp, "index")
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+indexPath)
+	for _, args := range [][]string{{"read-tree", "HEAD"}, append([]string{"add", "-A"}, pathspec(excludes)...)} {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("snapshot %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "write-tree")
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("snapshot write-tree: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func DiffTrees(ctx context.Context, dir string, from string, to string) (Patch, error) {
+	out, err := gitOutput(ctx, dir, "diff", "--binary", from, to)
+	if err != nil {
+		return Patch{}, fmt.Errorf("diff trees: %w", err)
+	}
+	return ParsePatch(out), nil
+}
+
+func Apply(ctx context.Context, dir string, data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "apply", "--binary", "--whitespace=nowarn", "-")
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apply patch: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type excludeRule struct {
+	pattern  string
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+type Excludes struct {
+	rules []excludeRule
+}
+
+func NewExcludes(patterns []string) *Excludes {
+	e := &Excludes{}
+	for _, pattern := range patterns {
+		e.Add(pattern)
+	}
+	return e
+}
+
+func (e *Excludes) LoadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func (e *Excludes) Add(pattern string) {
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+	rule := excludeRule{}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		rule.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	if strings.Contains(pattern, "/") {
+		rule.anchored = true
+	}
+	pattern = strings.TrimPrefix(pattern, "**/")
+	if pattern == "" {
+		return
+	}
+	rule.pattern = pattern
+	e.rules = append(e.rules, rule)
+}
+
+func (e *Excludes) Match(rel string, isDir bool) bool {
+	if e == nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	excluded := false
+	for _, rule := range e.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+func (r excludeRule) matches(rel string) bool {
+	if r.anchored {
+		ok, _ := path.Match(r.pattern, rel)
+		return ok
+	}
+	ok, _ := path.Match(r.pattern, path.Base(rel))
+	return ok
+}
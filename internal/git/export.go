@@ -0,0 +1,110 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type SeriesPatch struct {
+	Subject string
+	Body    string
+	Data    []byte
+}
+
+type Series struct {
+	Base      string
+	Head      string
+	Branch    string
+	Applied   []int
+	Conflicts []int
+}
+
+func BuildSeries(ctx context.Context, repoPath string, base string, branch string, patches []SeriesPatch) (Series, error) {
+	series := Series{Base: base, Head: base, Branch: branch}
+
+	tmp, err := os.MkdirTemp("", "atqos-export-")
+	if err != nil {
+		return series, err
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "series")
+	if err := addWorktree(ctx, repoPath, path, base, ""); err != nil {
+		return series, fmt.Errorf("create export worktree: %w", err)
+	}
+	defer runGit(context.Background(), repoPath, "worktree", "remove", "--force", "--force", path)
+
+	for i, patch := range patches {
+		if len(bytes.TrimSpace(patch.Data)) == 0 {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, "git", "-C", path, "apply", "--index", "--3way", "--binary", "--whitespace=nowarn", "-")
+		cmd.Stdin = bytes.NewReader(patch.Data)
+		if err := cmd.Run(); err != nil {
+			if err := runGit(ctx, path, "reset", "--quiet", "--hard", "HEAD"); err != nil {
+				return series, fmt.Errorf("reset export worktree: %w", err)
+			}
+			series.Conflicts = append(series.Conflicts, i)
+			continue
+		}
+
+		message := patch.Subject
+		if patch.Body != "" {
+			message += "\n\n" + patch.Body
+		}
+		commit := exec.CommandContext(ctx, "git", "-C", path, "-c", "core.hooksPath=/dev/null", "commit", "--quiet", "--no-verify", "-F", "-")
+		commit.Env = identityEnv()
+		commit.Stdin = strings.NewReader(message)
+		if out, err := commit.CombinedOutput(); err != nil {
+			return series, fmt.Errorf("commit patch %d: %w: %s", i+1, err, strings.TrimSpace(string(out)))
+		}
+		series.Applied = append(series.Applied, i)
+	}
+
+	head, err := gitOutput(ctx, path, "rev-parse", "HEAD")
+	if err != nil {
+		return series, fmt.Errorf("resolve export head: %w", err)
+	}
+	series.Head = strings.TrimSpace(string(head))
+	if branch != "" && len(series.Applied) > 0 {
+		if err := runGit(ctx, repoPath, "branch", "--force", branch, series.Head); err != nil {
+			return series, fmt.Errorf("create export branch: %w", err)
+		}
+	}
+	return series, nil
+}
+
+func FormatPatches(ctx context.Context, repoPath string, series Series, outDir string) ([]string, error) {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+	out, err := gitOutput(ctx, repoPath, "format-patch", "-o", outDir, series.Base+".."+series.Head)
+	if err != nil {
+		return nil, fmt.Errorf("format patches: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func CreateBundle(ctx context.Context, repoPath string, series Series, path string) error {
+	if series.Branch == "" {
+		return fmt.Errorf("bundle requires a branch")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := runGit(ctx, repoPath, "bundle", "create", path, series.Base+".."+series.Branch); err != nil {
+		return fmt.Errorf("create bundle: %w", err)
+	}
+	return nil
+}
+
+func Push(ctx context.Context, repoPath string, remote string, branch string) error {
+	if err := runGit(ctx, repoPath, "push", "--force", remote, branch+":"+branch); err != nil {
+		return fmt.Errorf("push %s to %s: %w", branch, remote, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// patchFor returns the diff that writes content to name in repo, leaving
+// the repo unchanged.
+func patchFor(t *testing.T, repo string, name string, content string) []byte {
+	t.Helper()
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, repo, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	patch, err := gitOutput(ctx, repo, "diff", "--cached", "--binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, repo, "reset", "--quiet", "--hard", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	return patch
+}
+
+func TestBuildSeries(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitBaseline(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	base, err := headCommit(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patches := []SeriesPatch{
+		{Subject: "fix a", Data: patchFor(t, repo, "a.txt", "two\n")},
+		// Written against the base, so it conflicts once "fix a" is applied.
+		{Subject: "fix a differently", Data: patchFor(t, repo, "a.txt", "three\n")},
+		{Subject: "no changes"},
+		{Subject: "add b", Body: "adds a test", Data: patchFor(t, repo, "b.txt", "new\n")},
+	}
+	series, err := BuildSeries(ctx, repo, base, "atqos-export/run-1", patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(series.Applied, []int{0, 3}) || !reflect.DeepEqual(series.Conflicts, []int{1}) {
+		t.Fatalf("applied %v, conflicts %v; want [0 3] and [1]", series.Applied, series.Conflicts)
+	}
+	head, err := gitOutput(ctx, repo, "rev-parse", "atqos-export/run-1")
+	if err != nil || strings.TrimSpace(string(head)) != series.Head {
+		t.Fatalf("export branch at %s, %v; want %s", head, err, series.Head)
+	}
+	body, err := gitOutput(ctx, repo, "log", "-1", "--format=%B", series.Head)
+	if err != nil || strings.TrimSpace(string(body)) != "add b\n\nadds a test" {
+		t.Fatalf("head message = %q, %v", body, err)
+	}
+
+	files, err := FormatPatches(ctx, repo, series, filepath.Join(t.TempDir(), "patches"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("format patches = %v, %v; want 2 files", files, err)
+	}
+	bundle := filepath.Join(t.TempDir(), "run.bundle")
+	if err := CreateBundle(ctx, repo, series, bundle); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, repo, "bundle", "verify", bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// The export worktree is gone and the checkout untouched.
+	if list, _ := gitOutput(ctx, repo, "worktree", "list", "--porcelain"); strings.Count(string(list), "worktree ") != 1 {
+		t.Fatalf("export worktree left behind:\n%s", list)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repo, "a.txt")); string(data) != "one\n" {
+		t.Fatalf("checkout changed: a.txt = %q", data)
+	}
+}
+
+func TestExportWorktreeIsNotOrphaned(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	if err := commitBaseline(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "series")
+	if err := addWorktree(ctx, repo, path, "HEAD", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer runGit(ctx, repo, "worktree", "remove", "--force", "--force", path)
+
+	report, err := FindOrphans(ctx, repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Worktrees) != 0 {
+		t.Fatalf("export worktree reported as orphaned: %v", report.Worktrees)
+	}
+}
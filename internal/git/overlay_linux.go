@@ -0,0 +1,27 @@
+package git
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func mountOverlay(lower string, upper string, work string, merged string) error {
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lower, upper, work)
+	if err := unix.Mount("overlay", merged, "overlay", 0, options); err != nil {
+		return fmt.Errorf("mount overlay: %w", err)
+	}
+	return nil
+}
+
+func unmountOverlay(merged string) error {
+	if err := unix.Unmount(merged, 0); err != nil {
+		return fmt.Errorf("unmount overlay: %w", err)
+	}
+	return nil
+}
+
+// whiteout hides path's counterpart in the lower layer of an overlay.
+func whiteout(path string) error {
+	return unix.Mknod(path, unix.S_IFCHR, 0)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package git
+
+import "errors"
+
+func mountOverlay(lower string, upper string, work string, merged string) error {
+	return errors.New("overlay workspaces require linux")
+}
+
+func unmountOverlay(merged string) error {
+	return nil
+}
+
+func whiteout(path string) error {
+	return errors.New("overlay workspaces require linux")
+}
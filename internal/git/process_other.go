@@ -0,0 +1,9 @@
+//go:build !unix
+
+package git
+
+// processAlive cannot probe other processes here, so owners are assumed
+// alive and only runs known to be finished are pruned.
+func processAlive(pid int) bool {
+	return true
+}
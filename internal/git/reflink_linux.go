@@ -0,0 +1,11 @@
+package git
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func reflink(src *os.File, dst *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
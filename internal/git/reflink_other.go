@@ -0,0 +1,12 @@
+//go:build !linux
+
+package git
+
+import (
+	"errors"
+	"os"
+)
+
+func reflink(src *os.File, dst *os.File) error {
+	return errors.New("reflink not supported on this platform")
+}
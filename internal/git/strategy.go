@@ -7,6 +7,11 @@ type Strategy interface {
 	FinalizeWorkspace(ctx context.Context, ws Workspace) error
 }
 
+type CandidateStrategy interface {
+	Strategy
+	PrepareCandidate(ctx context.Context, repoPath string, taskID int64, candidate int) (Workspace, error)
+}
+
 type Workspace struct {
 	Path     string
 	Branch   string
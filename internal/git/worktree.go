@@ -1,42 +1,317 @@
 package git
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	branchPrefix = "atqos/"
+	lockReason   = "atqos"
 )
 
 type WorktreeStrategy struct {
-	Root string
+	Root     string
+	PoolSize int
+	Base     Base
+	// RunID is recorded as the owner of every worktree and branch the pool
+	// creates so concurrent runs and gc can tell them apart.
+	RunID string
+
+	mu       sync.Mutex
+	repoPath string
+	idle     []string
+	next     int
+}
+
+type PruneReport struct {
+	Worktrees []string `json:"worktrees"`
+	Branches  []string `json:"branches"`
 }
 
 func NewWorktree(root string) *WorktreeStrategy {
-	return &WorktreeStrategy{Root: root}
+	return &WorktreeStrategy{Root: root, PoolSize: 4}
 }
 
 func (s *WorktreeStrategy) PrepareWorkspace(ctx context.Context, repoPath string, taskID int64) (Workspace, error) {
-	if err := os.MkdirAll(s.Root, 0o755); err != nil {
+	return s.prepare(ctx, repoPath, fmt.Sprintf("task-%d", taskID))
+}
+
+func (s *WorktreeStrategy) PrepareCandidate(ctx context.Context, repoPath string, taskID int64, candidate int) (Workspace, error) {
+	return s.prepare(ctx, repoPath, fmt.Sprintf("task-%d-c%d", taskID, candidate))
+}
+
+func (s *WorktreeStrategy) prepare(ctx context.Context, repoPath string, name string) (Workspace, error) {
+	base := s.Base.Ref()
+	if base == "" {
+		head, err := headCommit(ctx, repoPath)
+		if err != nil {
+			return Workspace{}, err
+		}
+		base = head
+	}
+	path, err := s.acquire(ctx, repoPath, base)
+	if err != nil {
 		return Workspace{}, err
 	}
-	branch := fmt.Sprintf("atqos/task-%d", taskID)
-	path := filepath.Join(s.Root, fmt.Sprintf("task-%d", taskID))
 
-	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "worktree", "add", "-b", branch, path)
-	if err := cmd.Run(); err != nil {
-		return Workspace{}, fmt.Errorf("create worktree: %w", err)
+	branch := branchPrefix + name
+	if s.RunID != "" {
+		branch = branchPrefix + s.RunID + "/" + name
+	}
+	if err := runGit(ctx, path, "checkout", "--quiet", "--force", "-B", branch, base); err != nil {
+		_ = s.remove(ctx, repoPath, path)
+		return Workspace{}, fmt.Errorf("reset worktree: %w", err)
+	}
+	if err := runGit(ctx, path, "clean", "-ffdxq"); err != nil {
+		_ = s.remove(ctx, repoPath, path)
+		return Workspace{}, fmt.Errorf("clean worktree: %w", err)
 	}
 	return Workspace{Path: path, Branch: branch, Worktree: true}, nil
 }
 
+func (s *WorktreeStrategy) acquire(ctx context.Context, repoPath string, base string) (string, error) {
+	s.mu.Lock()
+	s.repoPath = repoPath
+	if n := len(s.idle); n > 0 {
+		path := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return path, nil
+	}
+	s.next++
+	path := filepath.Join(s.Root, fmt.Sprintf("pool-%d", s.next))
+	s.mu.Unlock()
+
+	if err := os.MkdirAll(s.Root, 0o755); err != nil {
+		return "", err
+	}
+	if err := addWorktree(ctx, repoPath, path, base, s.RunID); err != nil {
+		return "", fmt.Errorf("create worktree: %w", err)
+	}
+	return path, nil
+}
+
+// addWorktree creates a detached worktree locked in the name of runID and
+// this process, so gc leaves it alone while the process lives.
+func addWorktree(ctx context.Context, repoPath string, path string, base string, runID string) error {
+	return runGit(ctx, repoPath, "worktree", "add", "--quiet", "--detach", "--lock", "--reason", Owner{RunID: runID, PID: os.Getpid()}.reason(), path, base)
+}
+
 func (s *WorktreeStrategy) FinalizeWorkspace(ctx context.Context, ws Workspace) error {
 	if !ws.Worktree {
 		return nil
 	}
-	cmd := exec.CommandContext(ctx, "git", "-C", ws.Path, "worktree", "remove", "--force", ws.Path)
-	if err := cmd.Run(); err != nil {
+	s.mu.Lock()
+	repoPath := s.repoPath
+	s.mu.Unlock()
+
+	err := runGit(ctx, ws.Path, "checkout", "--quiet", "--force", "--detach")
+	if err == nil {
+		err = runGit(ctx, ws.Path, "clean", "-ffdxq")
+	}
+	if ws.Branch != "" {
+		_ = runGit(ctx, ws.Path, "branch", "-D", ws.Branch)
+	}
+	if err != nil {
+		return s.remove(ctx, repoPath, ws.Path)
+	}
+
+	s.mu.Lock()
+	if len(s.idle) < s.PoolSize {
+		s.idle = append(s.idle, ws.Path)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+	return s.remove(ctx, repoPath, ws.Path)
+}
+
+func (s *WorktreeStrategy) Close(ctx context.Context) error {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	repoPath := s.repoPath
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, path := range idle {
+		if err := s.remove(ctx, repoPath, path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *WorktreeStrategy) remove(ctx context.Context, repoPath string, path string) error {
+	if err := runGit(ctx, repoPath, "worktree", "remove", "--force", "--force", path); err != nil {
 		return fmt.Errorf("remove worktree: %w", err)
 	}
 	return nil
 }
+
+// Owner is the run and process holding a worktree, recorded in its lock
+// reason as "atqos run=<id> pid=<pid>"; export worktrees belong to no run
+// and record only the pid. Worktrees locked by older versions carry no
+// owner.
+type Owner struct {
+	RunID string
+	PID   int
+}
+
+func (o Owner) reason() string {
+	if o.RunID == "" {
+		return fmt.Sprintf("%s pid=%d", lockReason, o.PID)
+	}
+	return fmt.Sprintf("%s run=%s pid=%d", lockReason, o.RunID, o.PID)
+}
+
+func parseOwner(reason string) Owner {
+	var owner Owner
+	for _, field := range strings.Fields(reason) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "run":
+			owner.RunID = value
+		case "pid":
+			owner.PID, _ = strconv.Atoi(value)
+		}
+	}
+	return owner
+}
+
+// Alive reports whether the owning process is still running. Worktrees
+// without an owner are never alive.
+func (o Owner) Alive() bool {
+	return o.PID > 0 && processAlive(o.PID)
+}
+
+// FindOrphans lists managed worktrees and branches whose owner is not
+// active. active may be nil, in which case an owner is active while its
+// process is alive.
+func FindOrphans(ctx context.Context, repoPath string, active func(Owner) bool) (PruneReport, error) {
+	if active == nil {
+		active = Owner.Alive
+	}
+	var report PruneReport
+	out, err := gitOutput(ctx, repoPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return report, fmt.Errorf("list worktrees: %w", err)
+	}
+	liveRuns := make(map[string]bool)
+	liveBranches := make(map[string]bool)
+	for _, wt := range parseWorktrees(out) {
+		if !wt.managed() {
+			continue
+		}
+		owner := parseOwner(wt.locked)
+		if active(owner) {
+			if owner.RunID != "" {
+				liveRuns[owner.RunID] = true
+			}
+			liveBranches[wt.branch] = true
+			continue
+		}
+		report.Worktrees = append(report.Worktrees, wt.path)
+	}
+
+	out, err = gitOutput(ctx, repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads/"+branchPrefix)
+	if err != nil {
+		return report, fmt.Errorf("list branches: %w", err)
+	}
+	for _, branch := range strings.Fields(string(out)) {
+		if liveBranches[branch] || liveRuns[branchRun(branch)] {
+			continue
+		}
+		report.Branches = append(report.Branches, branch)
+	}
+	return report, nil
+}
+
+// PruneOrphans removes what FindOrphans reports.
+func PruneOrphans(ctx context.Context, repoPath string, active func(Owner) bool) (PruneReport, error) {
+	var report PruneReport
+	if err := runGit(ctx, repoPath, "worktree", "prune"); err != nil {
+		return report, fmt.Errorf("prune worktrees: %w", err)
+	}
+
+	found, err := FindOrphans(ctx, repoPath, active)
+	if err != nil {
+		return report, err
+	}
+	for _, path := range found.Worktrees {
+		if err := runGit(ctx, repoPath, "worktree", "remove", "--force", "--force", path); err != nil {
+			return report, fmt.Errorf("remove worktree %s: %w", path, err)
+		}
+		report.Worktrees = append(report.Worktrees, path)
+	}
+	for _, branch := range found.Branches {
+		if err := runGit(ctx, repoPath, "branch", "-D", branch); err != nil {
+			return report, fmt.Errorf("delete branch %s: %w", branch, err)
+		}
+		report.Branches = append(report.Branches, branch)
+	}
+	return report, nil
+}
+
+// branchRun returns the run ID in an "atqos/<run>/<name>" branch, or "" for
+// branches created before branches were namespaced by run.
+func branchRun(branch string) string {
+	run, _, ok := strings.Cut(strings.TrimPrefix(branch, branchPrefix), "/")
+	if !ok {
+		return ""
+	}
+	return run
+}
+
+type worktreeEntry struct {
+	path   string
+	branch string
+	locked string
+}
+
+func (w worktreeEntry) managed() bool {
+	return w.locked == lockReason || strings.HasPrefix(w.locked, lockReason+" ") || strings.HasPrefix(w.branch, branchPrefix)
+}
+
+func parseWorktrees(data []byte) []worktreeEntry {
+	var (
+		entries []worktreeEntry
+		current *worktreeEntry
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			entries = append(entries, worktreeEntry{path: value})
+			current = &entries[len(entries)-1]
+		case "branch":
+			if current != nil {
+				current.branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		case "locked":
+			if current != nil {
+				current.locked = value
+			}
+		}
+	}
+	return entries
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
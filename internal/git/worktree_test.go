@@ -0,0 +1,80 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindOrphansKeepsLiveOwners(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitBaseline(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+
+	live := NewWorktree(filepath.Join(repo, ".wt-live"))
+	live.RunID = "run-live"
+	liveWS, err := live.PrepareWorkspace(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead := filepath.Join(repo, ".wt-dead")
+	if err := runGit(ctx, repo, "worktree", "add", "--quiet", "--detach", "--lock", "--reason", Owner{RunID: "run-dead", PID: 0}.reason(), dead, "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, dead, "checkout", "--quiet", "-b", "atqos/run-dead/task-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, repo, "branch", "atqos/task-9"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := PruneOrphans(ctx, repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Worktrees) != 1 || report.Worktrees[0] != dead {
+		t.Fatalf("pruned worktrees = %v, want only %s", report.Worktrees, dead)
+	}
+	want := map[string]bool{"atqos/run-dead/task-1": true, "atqos/task-9": true}
+	if len(report.Branches) != len(want) {
+		t.Fatalf("pruned branches = %v", report.Branches)
+	}
+	for _, branch := range report.Branches {
+		if !want[branch] {
+			t.Fatalf("pruned branch %s belongs to a live run", branch)
+		}
+	}
+	if _, err := os.Stat(liveWS.Path); err != nil {
+		t.Fatalf("live worktree removed: %v", err)
+	}
+	if err := live.FinalizeWorkspace(ctx, liveWS); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	owner := parseOwner(Owner{RunID: "run-1", PID: 42}.reason())
+	if owner.RunID != "run-1" || owner.PID != 42 {
+		t.Fatalf("owner = %+v", owner)
+	}
+	if owner := parseOwner(lockReason); owner.Alive() {
+		t.Fatal("legacy lock without owner reported alive")
+	}
+	if branchRun("atqos/run-1/task-2") != "run-1" || branchRun("atqos/task-2") != "" {
+		t.Fatal("branchRun did not split run namespace")
+	}
+}
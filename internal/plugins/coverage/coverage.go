@@ -53,7 +53,7 @@ func (p *Plugin) Collect(ctx context.Context, rc core.RunContext) (core.Artifact
 
 	cmd := runner.Command{
 		Args:         args,
-		Cwd:          rc.RepoPath,
+		Cwd:          rc.WorkDir(),
 		AllowNonZero: true,
 		StdoutPath:   stdoutPath,
 		StderrPath:   stderrPath,
@@ -151,6 +151,7 @@ func (p *Plugin) Plan(ctx context.Context, rc core.RunContext, findings []core.F
 			RunID:           rc.RunID,
 			Tool:            p.ID(),
 			TaskType:        "add_test",
+			Severity:        "medium",
 			Priority:        50,
 			Status:          "queued",
 			Fingerprint:     hashTask("coverage", file),
@@ -167,10 +168,8 @@ func (p *Plugin) Plan(ctx context.Context, rc core.RunContext, findings []core.F
 }
 
 func (p *Plugin) ValidationSpec(ctx context.Context, rc core.RunContext, task core.TaskRecord) (core.ValidationSpec, error) {
-	var targets struct {
-		Files []string `json:"files"`
-	}
-	if err := json.Unmarshal([]byte(task.TargetsJSON), &targets); err != nil {
+	targets, err := taskTargets(task)
+	if err != nil {
 		return core.ValidationSpec{}, err
 	}
 
@@ -197,10 +196,67 @@ func (p *Plugin) ValidationSpec(ctx context.Context, rc core.RunContext, task co
 		Commands: []core.CommandSpec{command},
 		SuccessCriteria: core.SuccessCriteria{
 			RequireExitCode0: true,
+			MinCoverageDelta: rc.Config.Coverage.MinimumDelta,
 		},
 	}, nil
 }
 
+func (p *Plugin) Coverage(ctx context.Context, rc core.RunContext, artifacts core.ArtifactSet, task core.TaskRecord) (float64, error) {
+	reportPath := ""
+	for _, artifact := range artifacts.Items {
+		if artifact.Kind == "report" {
+			reportPath = artifact.Path
+			break
+		}
+	}
+	if reportPath == "" {
+		return 0, fmt.Errorf("coverage report artifact missing")
+	}
+
+	targets, err := taskTargets(task)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var report coverageReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return 0, err
+	}
+
+	covered := 0
+	statements := 0
+	for _, file := range targets.Files {
+		details, ok := report.Files[file]
+		if !ok {
+			continue
+		}
+		covered += details.Summary.CoveredLines
+		statements += details.Summary.NumStatements
+	}
+	if statements == 0 {
+		return 0, fmt.Errorf("coverage report has no statements for %v", targets.Files)
+	}
+
+	return float64(covered) / float64(statements), nil
+}
+
+type coverageTargets struct {
+	Files []string `json:"files"`
+}
+
+func taskTargets(task core.TaskRecord) (coverageTargets, error) {
+	var out coverageTargets
+	if err := json.Unmarshal([]byte(task.TargetsJSON), &out); err != nil {
+		return coverageTargets{}, err
+	}
+	return out, nil
+}
+
 func newArtifact(runID string, tool string, kind string, path string) core.ArtifactRecord {
 	info, _ := os.Stat(path)
 	size := int64(0)
@@ -249,4 +305,6 @@ type coverageFile struct {
 
 type coverageSummary struct {
 	PercentCovered float64 `json:"percent_covered"`
+	CoveredLines   int     `json:"covered_lines"`
+	NumStatements  int     `json:"num_statements"`
 }
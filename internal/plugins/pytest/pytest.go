@@ -57,7 +57,7 @@ func (p *Plugin) Collect(ctx context.Context, rc core.RunContext) (core.Artifact
 
 	cmd := runner.Command{
 		Args:         args,
-		Cwd:          rc.RepoPath,
+		Cwd:          rc.WorkDir(),
 		AllowNonZero: true,
 		StdoutPath:   stdoutPath,
 		StderrPath:   stderrPath,
@@ -159,10 +159,14 @@ func (p *Plugin) Plan(ctx context.Context, rc core.RunContext, findings []core.F
 	for _, file := range files {
 		fileFindings := byFile[file]
 		testIDs := make([]string, 0, len(fileFindings))
+		severity := "high"
 		for _, finding := range fileFindings {
 			if finding.TestID != "" {
 				testIDs = append(testIDs, finding.TestID)
 			}
+			if finding.Severity == "blocker" {
+				severity = "blocker"
+			}
 		}
 		targets := map[string]interface{}{
 			"files":    []string{file},
@@ -178,6 +182,7 @@ func (p *Plugin) Plan(ctx context.Context, rc core.RunContext, findings []core.F
 			RunID:           rc.RunID,
 			Tool:            p.ID(),
 			TaskType:        "fix",
+			Severity:        severity,
 			Priority:        100,
 			Status:          "queued",
 			Fingerprint:     hashTask("pytest", file),
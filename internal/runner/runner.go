@@ -49,7 +49,8 @@ func (r *GenericRunner) Run(ctx context.Context, cmd Command) (ExecResult, error
 	}
 
 	start := time.Now()
-	ctx = applyTimeout(ctx, cmd.TimeoutSeconds)
+	ctx, cancel := applyTimeout(ctx, cmd.TimeoutSeconds)
+	defer cancel()
 
 	execCmd := exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
 	if cmd.Cwd != "" {
@@ -137,13 +138,12 @@ func (r *Registry) Get(name string) Runner {
 	return r.runners["generic"]
 }
 
-func applyTimeout(ctx context.Context, seconds int) context.Context {
+func applyTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
 	if seconds <= 0 {
-		return ctx
+		return ctx, func() {}
 	}
 	timeout := time.Duration(seconds) * time.Second
-	ctx, _ = context.WithTimeout(ctx, timeout)
-	return ctx
+	return context.WithTimeout(ctx, timeout)
 }
 
 func envSlice(env map[string]string) []string {
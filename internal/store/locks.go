@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	ConflictNone      = "none"
+	ConflictFile      = "file"
+	ConflictDirectory = "directory"
+	ConflictModule    = "module"
+)
+
+var ErrConflict = errors.New("queued tasks conflict with running tasks")
+
+// heldLocks returns the lock keys held in a run. query selects lock_key for
+// the run ID in the backend's placeholder syntax.
+func heldLocks(ctx context.Context, tx *sql.Tx, query string, runID string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, query, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		held[key] = true
+	}
+	return held, rows.Err()
+}
+
+func conflicts(held map[string]bool, keys []string) bool {
+	for _, key := range keys {
+		if held[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func lockKeys(targetsJSON string, granularity string) []string {
+	if granularity == ConflictNone || targetsJSON == "" {
+		return nil
+	}
+	var targets struct {
+		Files   []string `json:"files"`
+		TestIDs []string `json:"test_ids"`
+	}
+	if err := json.Unmarshal([]byte(targetsJSON), &targets); err != nil {
+		return nil
+	}
+
+	files := targets.Files
+	for _, testID := range targets.TestIDs {
+		files = append(files, strings.SplitN(testID, "::", 2)[0])
+	}
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, file := range files {
+		file = path.Clean(strings.TrimPrefix(strings.ReplaceAll(file, "\\", "/"), "./"))
+		if file == "." || file == "" {
+			continue
+		}
+		for _, key := range []string{lockKey(file, granularity), sourceKey(file)} {
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sourceKey ties a test file to the module it exercises, so a task fixing
+// tests/test_parser.py and one covering src/pkg/parser.py lock each other
+// out even though their paths differ. It matches by module name only, which
+// errs towards serialising unrelated modules that share a name.
+func sourceKey(file string) string {
+	name := path.Base(file)
+	if !strings.HasSuffix(name, ".py") {
+		return ""
+	}
+	name = strings.TrimSuffix(name, ".py")
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "test_"), "_test")
+	if name == "" || name == "__init__" || name == "conftest" {
+		return ""
+	}
+	return "module:" + name
+}
+
+func lockKey(file string, granularity string) string {
+	switch granularity {
+	case ConflictDirectory:
+		return path.Dir(file)
+	case ConflictModule:
+		parts := strings.Split(file, "/")
+		if len(parts) > 1 && parts[0] == "src" {
+			return strings.Join(parts[:2], "/")
+		}
+		return parts[0]
+	default:
+		return file
+	}
+}
@@ -0,0 +1,25 @@
+package store
+
+import "testing"
+
+func TestLockKeysTieTestsToSources(t *testing.T) {
+	pytestTask := `{"files":["tests/test_parser.py"],"test_ids":["tests/test_parser.py::test_empty"]}`
+	coverageTask := `{"files":["src/pkg/parser.py"]}`
+	otherTask := `{"files":["src/pkg/lexer.py"]}`
+
+	for _, granularity := range []string{ConflictFile, ConflictDirectory, ConflictModule} {
+		held := make(map[string]bool)
+		for _, key := range lockKeys(pytestTask, granularity) {
+			held[key] = true
+		}
+		if !conflicts(held, lockKeys(coverageTask, granularity)) {
+			t.Errorf("%s: coverage task on the tested source does not conflict with the pytest task", granularity)
+		}
+		if granularity == ConflictFile && conflicts(held, lockKeys(otherTask, granularity)) {
+			t.Errorf("%s: unrelated source conflicts with the pytest task", granularity)
+		}
+	}
+	if keys := lockKeys(pytestTask, ConflictNone); len(keys) != 0 {
+		t.Errorf("none granularity took locks %v", keys)
+	}
+}
@@ -0,0 +1,478 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"atqos/internal/core"
+)
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	conflicts string
+
+	runs      map[string]*memoryRun
+	artifacts []core.ArtifactRecord
+	findings  []core.FindingRecord
+	tasks     []*core.TaskRecord
+	attempts  []*core.AttemptRecord
+	locks     map[string]map[string]int64
+	index     []SearchDocument
+}
+
+type memoryRun struct {
+	record      core.RunRecord
+	summaryJSON string
+	finishedAt  time.Time
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		conflicts: ConflictFile,
+		runs:      make(map[string]*memoryRun),
+		locks:     make(map[string]map[string]int64),
+	}
+}
+
+func (s *MemoryStore) WithConflictGranularity(granularity string) *MemoryStore {
+	s.conflicts = granularity
+	return s
+}
+
+func (s *MemoryStore) Init(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) CreateRun(ctx context.Context, run core.RunRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.runs[run.RunID]; ok {
+		return fmt.Errorf("run %s already exists", run.RunID)
+	}
+	run.StartedAt = storedTime(run.StartedAt)
+	s.runs[run.RunID] = &memoryRun{record: run, summaryJSON: run.SummaryJSON, finishedAt: storedTime(run.FinishedAt)}
+	return nil
+}
+
+func (s *MemoryStore) UpdateRunStatus(ctx context.Context, runID string, status string, summaryJSON string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[runID]
+	if !ok {
+		return nil
+	}
+	run.record.Status = status
+	run.summaryJSON = summaryJSON
+	run.finishedAt = storedTime(time.Now())
+	return nil
+}
+
+func (s *MemoryStore) GetRun(ctx context.Context, runID string) (core.RunRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[runID]
+	if !ok {
+		return core.RunRecord{}, fmt.Errorf("run %s: %w", runID, ErrNotFound)
+	}
+	record := run.record
+	record.FinishedAt = run.finishedAt
+	record.SummaryJSON = run.summaryJSON
+	return record, nil
+}
+
+func (s *MemoryStore) GetRunSummary(ctx context.Context, runID string) (core.RunSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[runID]
+	if !ok {
+		return core.RunSummary{}, fmt.Errorf("run %s: %w", runID, ErrNotFound)
+	}
+	summary := core.RunSummary{
+		RunID:    runID,
+		Status:   run.record.Status,
+		Started:  run.record.StartedAt,
+		Finished: run.finishedAt,
+	}
+	for _, finding := range s.findings {
+		if finding.RunID == runID {
+			summary.Findings++
+		}
+	}
+	for _, task := range s.tasks {
+		if task.RunID == runID {
+			summary.Tasks++
+		}
+	}
+	return summary, nil
+}
+
+func (s *MemoryStore) ListRuns(ctx context.Context) ([]core.RunInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]core.RunInfo, 0, len(s.runs))
+	for _, run := range s.runs {
+		info := core.RunInfo{
+			RunID:      run.record.RunID,
+			RepoPath:   run.record.RepoPath,
+			Status:     run.record.Status,
+			StartedAt:  run.record.StartedAt,
+			FinishedAt: run.finishedAt,
+		}
+		for _, task := range s.tasks {
+			if task.RunID == info.RunID && strings.Contains(task.ExtraJSON, `"escalation"`) {
+				info.Flagged = true
+				break
+			}
+		}
+		runs = append(runs, info)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].RunID > runs[j].RunID
+	})
+	return runs, nil
+}
+
+func (s *MemoryStore) DeleteRun(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.runs, runID)
+	delete(s.locks, runID)
+	s.artifacts = filterRun(s.artifacts, runID, func(a core.ArtifactRecord) string { return a.RunID })
+	s.findings = filterRun(s.findings, runID, func(f core.FindingRecord) string { return f.RunID })
+	s.index = filterRun(s.index, runID, func(d SearchDocument) string { return d.RunID })
+
+	// Task and attempt IDs index into their slices, so rows are tombstoned
+	// rather than removed.
+	for i, task := range s.tasks {
+		if task.RunID != runID {
+			continue
+		}
+		for j, attempt := range s.attempts {
+			if attempt.TaskID == task.ID {
+				s.attempts[j] = &core.AttemptRecord{ID: attempt.ID}
+			}
+		}
+		s.tasks[i] = &core.TaskRecord{ID: task.ID, Status: "deleted"}
+	}
+	return nil
+}
+
+func filterRun[T any](items []T, runID string, key func(T) string) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if key(item) != runID {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (s *MemoryStore) AddArtifact(ctx context.Context, artifact core.ArtifactRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifact.CreatedAt = storedTime(artifact.CreatedAt)
+	s.artifacts = append(s.artifacts, artifact)
+	return nil
+}
+
+func (s *MemoryStore) ListArtifacts(ctx context.Context, runID string) ([]core.ArtifactRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts := make([]core.ArtifactRecord, 0)
+	for _, artifact := range s.artifacts {
+		if artifact.RunID == runID {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts, nil
+}
+
+func (s *MemoryStore) InsertFindings(ctx context.Context, findings []core.FindingRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, finding := range findings {
+		finding.CreatedAt = storedTime(finding.CreatedAt)
+		s.findings = append(s.findings, finding)
+		s.index = append(s.index, findingDocument(finding, int64(len(s.findings))))
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListFindings(ctx context.Context, runID string) ([]core.FindingRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	findings := make([]core.FindingRecord, 0)
+	for _, finding := range s.findings {
+		if finding.RunID == runID {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+func (s *MemoryStore) InsertTasks(ctx context.Context, tasks []core.TaskRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, task := range tasks {
+		task.ID = int64(len(s.tasks) + 1)
+		tasks[i].ID = task.ID
+		task.ClaimedBy = ""
+		task.ClaimedAt = time.Time{}
+		task.CreatedAt = storedTime(task.CreatedAt)
+		task.UpdatedAt = storedTime(task.UpdatedAt)
+		s.tasks = append(s.tasks, &task)
+		s.index = append(s.index, taskDocument(task, task.ID))
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListTasks(ctx context.Context, runID string) ([]core.TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]core.TaskRecord, 0)
+	for _, task := range s.tasks {
+		if task.RunID == runID {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) ClaimNextTask(ctx context.Context, runID string, workerID string) (*core.TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queued []*core.TaskRecord
+	for _, task := range s.tasks {
+		if task.RunID == runID && task.Status == "queued" {
+			queued = append(queued, task)
+		}
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		a, b := queued[i], queued[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	held := make(map[string]bool)
+	for key := range s.locks[runID] {
+		held[key] = true
+	}
+	var (
+		task *core.TaskRecord
+		keys []string
+	)
+	for _, candidate := range queued {
+		candidateKeys := lockKeys(candidate.TargetsJSON, s.conflicts)
+		if !conflicts(held, candidateKeys) {
+			task = candidate
+			keys = candidateKeys
+			break
+		}
+	}
+	if task == nil {
+		return nil, ErrConflict
+	}
+
+	now := storedTime(time.Now())
+	task.Status = "running"
+	task.ClaimedBy = workerID
+	task.ClaimedAt = now
+	task.UpdatedAt = now
+	if len(keys) > 0 && s.locks[runID] == nil {
+		s.locks[runID] = make(map[string]int64)
+	}
+	for _, key := range keys {
+		s.locks[runID][key] = task.ID
+	}
+
+	claimed := *task
+	return &claimed, nil
+}
+
+func (s *MemoryStore) UpdateTaskStatus(ctx context.Context, taskID int64, status string, extraJSON string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task := s.task(taskID)
+	if task == nil {
+		return nil
+	}
+	task.Status = status
+	task.ExtraJSON = extraJSON
+	task.UpdatedAt = storedTime(time.Now())
+	if status != "running" {
+		for key, holder := range s.locks[task.RunID] {
+			if holder == taskID {
+				delete(s.locks[task.RunID], key)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateAttempt(ctx context.Context, attempt core.AttemptRecord) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.task(attempt.TaskID) == nil {
+		return 0, fmt.Errorf("task %d: %w", attempt.TaskID, ErrNotFound)
+	}
+	attempt.ID = int64(len(s.attempts) + 1)
+	attempt.StartedAt = storedTime(attempt.StartedAt)
+	attempt.FinishedAt = time.Time{}
+	s.attempts = append(s.attempts, &attempt)
+	return attempt.ID, nil
+}
+
+func (s *MemoryStore) FinishAttempt(ctx context.Context, attempt core.AttemptRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt.ID < 1 || attempt.ID > int64(len(s.attempts)) {
+		return nil
+	}
+	stored := s.attempts[attempt.ID-1]
+	stored.Status = attempt.Status
+	stored.AgentExitCode = attempt.AgentExitCode
+	stored.ValidationExitCode = attempt.ValidationExitCode
+	stored.SummaryJSON = attempt.SummaryJSON
+	stored.DiffStatsJSON = attempt.DiffStatsJSON
+	stored.ArtifactsJSON = attempt.ArtifactsJSON
+	stored.FinishedAt = storedTime(time.Now())
+	if !attempt.FinishedAt.IsZero() {
+		stored.FinishedAt = storedTime(attempt.FinishedAt)
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListAttempts(ctx context.Context, taskID int64) ([]core.AttemptRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := make([]core.AttemptRecord, 0)
+	for _, attempt := range s.attempts {
+		if attempt.TaskID == taskID {
+			attempts = append(attempts, *attempt)
+		}
+	}
+	sort.SliceStable(attempts, func(i, j int) bool {
+		if attempts[i].AttemptNo != attempts[j].AttemptNo {
+			return attempts[i].AttemptNo < attempts[j].AttemptNo
+		}
+		return attempts[i].ID < attempts[j].ID
+	})
+	return attempts, nil
+}
+
+func (s *MemoryStore) IndexDocuments(ctx context.Context, docs []SearchDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.index = append(s.index, docs...)
+	return nil
+}
+
+// Search scores documents by how often the query terms occur; every term
+// must occur at least once.
+func (s *MemoryStore) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	terms := searchTerms(strings.ToLower(query.Text))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	hits := make([]SearchHit, 0)
+	for _, doc := range s.index {
+		if (query.RunID != "" && doc.RunID != query.RunID) || (query.Tool != "" && doc.Tool != query.Tool) {
+			continue
+		}
+		title, body := strings.ToLower(doc.Title), strings.ToLower(doc.Body)
+		score := 0.0
+		for _, term := range terms {
+			n := 2*strings.Count(title, term) + strings.Count(body, term)
+			if n == 0 {
+				score = 0
+				break
+			}
+			score += float64(n)
+		}
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, SearchHit{
+			RunID:   doc.RunID,
+			Kind:    doc.Kind,
+			RefID:   doc.RefID,
+			Tool:    doc.Tool,
+			Path:    doc.Path,
+			Title:   doc.Title,
+			Snippet: memorySnippet(doc.Body, body, terms[0]),
+			Score:   score,
+		})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit := searchLimit(query.Limit); len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func memorySnippet(body string, lower string, term string) string {
+	i := strings.Index(lower, term)
+	if i < 0 || len(lower) != len(body) {
+		return ""
+	}
+	start, end := i-60, i+len(term)+60
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(body) {
+		end, suffix = len(body), ""
+	}
+	return prefix + body[start:i] + "[" + body[i:i+len(term)] + "]" + body[i+len(term):end] + suffix
+}
+
+func (s *MemoryStore) task(taskID int64) *core.TaskRecord {
+	if taskID < 1 || taskID > int64(len(s.tasks)) {
+		return nil
+	}
+	return s.tasks[taskID-1]
+}
+
+func storedTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+	addColumnPattern = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(\w+)\s+ADD\s+COLUMN\s+(\w+)\b`)
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+type SchemaVersion struct {
+	Current int
+	Latest  int
+	Pending []Migration
+}
+
+type Migrator interface {
+	SchemaVersion(ctx context.Context) (SchemaVersion, error)
+	Migrate(ctx context.Context) ([]Migration, error)
+}
+
+type dialect struct {
+	name       string
+	createSQL  string
+	tableSQL   string
+	appliedSQL string
+	insertSQL  string
+	lockSQL    string
+	hasColumn  func(ctx context.Context, tx *sql.Tx, table string, column string) (bool, error)
+}
+
+var sqliteDialect = dialect{
+	name: "sqlite",
+	createSQL: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`,
+	tableSQL:   `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	appliedSQL: `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`,
+	insertSQL: `
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)`,
+	hasColumn: sqliteHasColumn,
+}
+
+func Migrations(dialectName string) ([]Migration, error) {
+	dir := path.Join("migrations", dialectName)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unknown migration dialect %q: %w", dialectName, err)
+	}
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, label, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		data, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: label, SQL: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+func (s *SQLiteStore) SchemaVersion(ctx context.Context) (SchemaVersion, error) {
+	return schemaVersion(ctx, s.db, sqliteDialect)
+}
+
+func (s *SQLiteStore) Migrate(ctx context.Context) ([]Migration, error) {
+	return migrate(ctx, s.db, sqliteDialect)
+}
+
+// schemaVersion reads the applied migrations without changing the
+// database; one that has never been migrated has none applied.
+func schemaVersion(ctx context.Context, db *sql.DB, d dialect) (SchemaVersion, error) {
+	migrations, err := Migrations(d.name)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+	applied, err := appliedVersions(ctx, db, d)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+
+	version := SchemaVersion{}
+	if len(migrations) > 0 {
+		version.Latest = migrations[len(migrations)-1].Version
+	}
+	for v := range applied {
+		if v > version.Current {
+			version.Current = v
+		}
+	}
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			version.Pending = append(version.Pending, migration)
+		}
+	}
+	return version, nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB, d dialect) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	var tables int
+	if err := db.QueryRowContext(ctx, d.tableSQL).Scan(&tables); err != nil || tables == 0 {
+		return applied, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func migrate(ctx context.Context, db *sql.DB, d dialect) ([]Migration, error) {
+	if _, err := db.ExecContext(ctx, d.createSQL); err != nil {
+		return nil, err
+	}
+	version, err := schemaVersion(ctx, db, d)
+	if err != nil {
+		return nil, err
+	}
+	if version.Current > version.Latest {
+		return nil, fmt.Errorf("%w (database at version %d, latest known %d)", ErrSchemaTooNew, version.Current, version.Latest)
+	}
+
+	var applied []Migration
+	for _, migration := range version.Pending {
+		ok, err := apply(ctx, db, d, migration)
+		if err != nil {
+			return applied, fmt.Errorf("apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+func apply(ctx context.Context, db *sql.DB, d dialect, migration Migration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if d.lockSQL != "" {
+		if _, err := tx.ExecContext(ctx, d.lockSQL); err != nil {
+			return false, err
+		}
+	}
+	// Another process may have applied it while we waited for the lock.
+	var count int
+	if err := tx.QueryRowContext(ctx, d.appliedSQL, migration.Version).Scan(&count); err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	for _, stmt := range splitStatements(migration.SQL) {
+		// Databases created before versioned migrations may already have
+		// columns added by later migrations.
+		if match := addColumnPattern.FindStringSubmatch(stmt); match != nil {
+			exists, err := d.hasColumn(ctx, tx, match[1], match[2])
+			if err != nil {
+				return false, err
+			}
+			if exists {
+				continue
+			}
+		}
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, d.insertSQL, migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func sqliteHasColumn(ctx context.Context, tx *sql.Tx, table string, column string) (bool, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newSQLiteFile(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "atqos.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func sqliteTables(t *testing.T, s *SQLiteStore) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSchemaVersionLeavesDatabaseUnchanged(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteFile(t)
+	migrations, err := Migrations("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Current != 0 || len(version.Pending) != len(migrations) {
+		t.Fatalf("empty database version = %+v", version)
+	}
+	if n := sqliteTables(t, s); n != 0 {
+		t.Fatalf("reading the version created %d tables", n)
+	}
+}
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := Migrations("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	for _, tc := range []struct {
+		name string
+		// extra is run after the baseline schema, before versioning began.
+		extra []string
+	}{
+		{name: "baseline schema"},
+		{name: "baseline with a later column", extra: []string{`ALTER TABLE tasks ADD COLUMN severity TEXT`}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSQLiteFile(t)
+			for _, stmt := range append(splitStatements(migrations[0].SQL), tc.extra...) {
+				if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := s.db.ExecContext(ctx, `INSERT INTO runs (run_id, repo_path, started_at, status, config_json) VALUES ('run-old', '/repo', '2024-01-01T00:00:00Z', 'succeeded', '{}')`); err != nil {
+				t.Fatal(err)
+			}
+
+			version, err := s.SchemaVersion(ctx)
+			if err != nil || version.Current != 0 || len(version.Pending) != len(migrations) {
+				t.Fatalf("version before migrating = %+v, %v", version, err)
+			}
+			applied, err := s.Migrate(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != len(migrations) {
+				t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+			}
+			if version, err := s.SchemaVersion(ctx); err != nil || version.Current != latest || len(version.Pending) != 0 {
+				t.Fatalf("version after migrating = %+v, %v", version, err)
+			}
+			if run, err := s.GetRun(ctx, "run-old"); err != nil || run.RepoPath != "/repo" {
+				t.Fatalf("existing run after migrating = %+v, %v", run, err)
+			}
+			if err := s.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+			checkRuns(t, ctx, s, "run-new")
+		})
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteFile(t)
+	if _, err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.ExecContext(ctx, sqliteDialect.insertSQL, version.Latest+1, "from_the_future", "2030-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Migrate(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Migrate error = %v, want ErrSchemaTooNew", err)
+	}
+	if err := s.Init(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Init error = %v, want ErrSchemaTooNew", err)
+	}
+	if version, err := s.SchemaVersion(ctx); err != nil || version.Current != version.Latest+1 {
+		t.Fatalf("version = %+v, %v", version, err)
+	}
+}
@@ -0,0 +1,106 @@
+CREATE TABLE IF NOT EXISTS runs (
+	id BIGSERIAL PRIMARY KEY,
+	run_id TEXT NOT NULL UNIQUE,
+	repo_path TEXT NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	status TEXT NOT NULL,
+	config_json TEXT NOT NULL,
+	summary_json TEXT,
+	base_commit TEXT,
+	dirty_diff TEXT,
+	snapshot_commit TEXT
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+	id BIGSERIAL PRIMARY KEY,
+	run_id TEXT NOT NULL REFERENCES runs(run_id),
+	tool TEXT,
+	kind TEXT NOT NULL,
+	path TEXT NOT NULL,
+	sha256 TEXT,
+	size_bytes BIGINT,
+	created_at TIMESTAMPTZ NOT NULL,
+	meta_json TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_run_id ON artifacts(run_id);
+
+CREATE TABLE IF NOT EXISTS findings (
+	id BIGSERIAL PRIMARY KEY,
+	run_id TEXT NOT NULL REFERENCES runs(run_id),
+	tool TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	message TEXT NOT NULL,
+	file_path TEXT,
+	line INTEGER,
+	col INTEGER,
+	symbol TEXT,
+	test_id TEXT,
+	raw_ref TEXT,
+	meta_json TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_findings_run_tool ON findings(run_id, tool);
+CREATE INDEX IF NOT EXISTS idx_findings_fingerprint ON findings(fingerprint);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id BIGSERIAL PRIMARY KEY,
+	run_id TEXT NOT NULL REFERENCES runs(run_id),
+	tool TEXT NOT NULL,
+	task_type TEXT NOT NULL,
+	severity TEXT,
+	priority INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	targets_json TEXT NOT NULL,
+	validation_json TEXT NOT NULL,
+	retry_policy_json TEXT NOT NULL,
+	depends_on_json TEXT,
+	extra_json TEXT,
+	claimed_by TEXT,
+	claimed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_run_status ON tasks(run_id, status);
+CREATE INDEX IF NOT EXISTS idx_tasks_fingerprint ON tasks(fingerprint);
+
+CREATE TABLE IF NOT EXISTS attempts (
+	id BIGSERIAL PRIMARY KEY,
+	task_id BIGINT NOT NULL REFERENCES tasks(id),
+	attempt_no INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	agent_name TEXT,
+	agent_exit_code INTEGER,
+	validation_exit_code INTEGER,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	summary_json TEXT,
+	diff_stats_json TEXT,
+	artifacts_json TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_attempts_task_id ON attempts(task_id);
+
+CREATE TABLE IF NOT EXISTS task_locks (
+	run_id TEXT NOT NULL,
+	lock_key TEXT NOT NULL,
+	task_id BIGINT NOT NULL REFERENCES tasks(id),
+	acquired_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(run_id, lock_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_locks_task_id ON task_locks(task_id);
+
+CREATE TABLE IF NOT EXISTS task_findings (
+	task_id BIGINT NOT NULL REFERENCES tasks(id),
+	finding_id BIGINT NOT NULL REFERENCES findings(id),
+	PRIMARY KEY(task_id, finding_id)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS search_index (
+	id BIGSERIAL PRIMARY KEY,
+	run_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	ref_id BIGINT,
+	tool TEXT NOT NULL DEFAULT '',
+	path TEXT NOT NULL DEFAULT '',
+	title TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	document TSVECTOR GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B')
+	) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_index_document ON search_index USING GIN (document);
+CREATE INDEX IF NOT EXISTS idx_search_index_run_id ON search_index(run_id);
+
+INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+SELECT run_id, 'finding', id, tool, COALESCE(file_path, ''), COALESCE(NULLIF(test_id, ''), file_path, ''), message
+FROM findings;
+
+INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+SELECT run_id, 'task', id, tool, '', title, COALESCE(description, '')
+FROM tasks;
@@ -0,0 +1,98 @@
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id TEXT NOT NULL UNIQUE,
+	repo_path TEXT NOT NULL,
+	started_at TEXT NOT NULL,
+	finished_at TEXT,
+	status TEXT NOT NULL,
+	config_json TEXT NOT NULL,
+	summary_json TEXT
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id TEXT NOT NULL,
+	tool TEXT,
+	kind TEXT NOT NULL,
+	path TEXT NOT NULL,
+	sha256 TEXT,
+	size_bytes INTEGER,
+	created_at TEXT NOT NULL,
+	meta_json TEXT,
+	FOREIGN KEY(run_id) REFERENCES runs(run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_run_id ON artifacts(run_id);
+
+CREATE TABLE IF NOT EXISTS findings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id TEXT NOT NULL,
+	tool TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	severity TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	message TEXT NOT NULL,
+	file_path TEXT,
+	line INTEGER,
+	col INTEGER,
+	symbol TEXT,
+	test_id TEXT,
+	raw_ref TEXT,
+	meta_json TEXT,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY(run_id) REFERENCES runs(run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_findings_run_tool ON findings(run_id, tool);
+CREATE INDEX IF NOT EXISTS idx_findings_fingerprint ON findings(fingerprint);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id TEXT NOT NULL,
+	tool TEXT NOT NULL,
+	task_type TEXT NOT NULL,
+	priority INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	targets_json TEXT NOT NULL,
+	validation_json TEXT NOT NULL,
+	retry_policy_json TEXT NOT NULL,
+	depends_on_json TEXT,
+	extra_json TEXT,
+	claimed_by TEXT,
+	claimed_at TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	FOREIGN KEY(run_id) REFERENCES runs(run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_run_status ON tasks(run_id, status);
+CREATE INDEX IF NOT EXISTS idx_tasks_fingerprint ON tasks(fingerprint);
+
+CREATE TABLE IF NOT EXISTS attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	attempt_no INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	agent_name TEXT,
+	agent_exit_code INTEGER,
+	validation_exit_code INTEGER,
+	started_at TEXT NOT NULL,
+	finished_at TEXT,
+	summary_json TEXT,
+	diff_stats_json TEXT,
+	artifacts_json TEXT,
+	FOREIGN KEY(task_id) REFERENCES tasks(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attempts_task_id ON attempts(task_id);
+
+CREATE TABLE IF NOT EXISTS task_findings (
+	task_id INTEGER NOT NULL,
+	finding_id INTEGER NOT NULL,
+	PRIMARY KEY(task_id, finding_id),
+	FOREIGN KEY(task_id) REFERENCES tasks(id),
+	FOREIGN KEY(finding_id) REFERENCES findings(id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE runs ADD COLUMN base_commit TEXT;
+ALTER TABLE runs ADD COLUMN dirty_diff TEXT;
+ALTER TABLE runs ADD COLUMN snapshot_commit TEXT;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_locks (
+	run_id TEXT NOT NULL,
+	lock_key TEXT NOT NULL,
+	task_id INTEGER NOT NULL,
+	acquired_at TEXT NOT NULL,
+	PRIMARY KEY(run_id, lock_key),
+	FOREIGN KEY(task_id) REFERENCES tasks(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_locks_task_id ON task_locks(task_id);
@@ -0,0 +1,18 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+	run_id UNINDEXED,
+	kind UNINDEXED,
+	ref_id UNINDEXED,
+	tool UNINDEXED,
+	path UNINDEXED,
+	title,
+	body,
+	tokenize = 'porter unicode61'
+);
+
+INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+SELECT run_id, 'finding', id, tool, COALESCE(file_path, ''), COALESCE(NULLIF(test_id, ''), file_path, ''), message
+FROM findings;
+
+INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+SELECT run_id, 'task', id, tool, '', title, COALESCE(description, '')
+FROM tasks;
@@ -0,0 +1,724 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"atqos/internal/core"
+)
+
+var postgresDialect = dialect{
+	name: "postgres",
+	createSQL: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`,
+	tableSQL:   `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`,
+	appliedSQL: `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`,
+	insertSQL: `
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES ($1, $2, $3)`,
+	lockSQL:   `SELECT pg_advisory_xact_lock(hashtext('atqos_schema_migrations'))`,
+	hasColumn: postgresHasColumn,
+}
+
+type PostgresStore struct {
+	db        *sql.DB
+	conflicts string
+}
+
+func IsPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+func NewPostgres(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	return &PostgresStore{db: db, conflicts: ConflictFile}, nil
+}
+
+func (s *PostgresStore) WithConflictGranularity(granularity string) *PostgresStore {
+	s.conflicts = granularity
+	return s
+}
+
+func (s *PostgresStore) Init(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	_, err := s.Migrate(ctx)
+	return err
+}
+
+func (s *PostgresStore) SchemaVersion(ctx context.Context) (SchemaVersion, error) {
+	return schemaVersion(ctx, s.db, postgresDialect)
+}
+
+func (s *PostgresStore) Migrate(ctx context.Context) ([]Migration, error) {
+	return migrate(ctx, s.db, postgresDialect)
+}
+
+func (s *PostgresStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *PostgresStore) CreateRun(ctx context.Context, run core.RunRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO runs (run_id, repo_path, started_at, status, config_json, base_commit, dirty_diff, snapshot_commit, finished_at, summary_json)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		run.RunID,
+		run.RepoPath,
+		run.StartedAt.UTC(),
+		run.Status,
+		run.Config,
+		run.BaseCommit,
+		run.DirtyDiff,
+		run.SnapshotCommit,
+		sql.NullTime{Time: run.FinishedAt.UTC(), Valid: !run.FinishedAt.IsZero()},
+		run.SummaryJSON,
+	)
+	return err
+}
+
+func (s *PostgresStore) UpdateRunStatus(ctx context.Context, runID string, status string, summaryJSON string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE runs
+		SET status = $1, summary_json = $2, finished_at = $3
+		WHERE run_id = $4`,
+		status,
+		summaryJSON,
+		time.Now().UTC(),
+		runID,
+	)
+	return err
+}
+
+func (s *PostgresStore) GetRun(ctx context.Context, runID string) (core.RunRecord, error) {
+	var (
+		run        core.RunRecord
+		finishedAt sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT run_id, repo_path, started_at, status, config_json,
+		       COALESCE(base_commit, ''), COALESCE(dirty_diff, ''), COALESCE(snapshot_commit, ''),
+		       finished_at, COALESCE(summary_json, '')
+		FROM runs
+		WHERE run_id = $1`,
+		runID,
+	).Scan(&run.RunID, &run.RepoPath, &run.StartedAt, &run.Status, &run.Config, &run.BaseCommit, &run.DirtyDiff, &run.SnapshotCommit, &finishedAt, &run.SummaryJSON)
+	if err != nil {
+		return core.RunRecord{}, notFound(err, "run "+runID)
+	}
+	run.FinishedAt = finishedAt.Time
+	return run, nil
+}
+
+func (s *PostgresStore) GetRunSummary(ctx context.Context, runID string) (core.RunSummary, error) {
+	var (
+		summary  = core.RunSummary{RunID: runID}
+		finished sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT status, started_at, finished_at,
+		       (SELECT COUNT(*) FROM findings WHERE run_id = $1),
+		       (SELECT COUNT(*) FROM tasks WHERE run_id = $1)
+		FROM runs
+		WHERE run_id = $1`,
+		runID,
+	).Scan(&summary.Status, &summary.Started, &finished, &summary.Findings, &summary.Tasks)
+	if err != nil {
+		return core.RunSummary{}, notFound(err, "run "+runID)
+	}
+	if finished.Valid {
+		summary.Finished = finished.Time
+	}
+	return summary, nil
+}
+
+func (s *PostgresStore) ListRuns(ctx context.Context) ([]core.RunInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.run_id, r.repo_path, r.status, r.started_at, r.finished_at,
+		       EXISTS (SELECT 1 FROM tasks t WHERE t.run_id = r.run_id AND t.extra_json LIKE '%"escalation"%')
+		FROM runs r
+		ORDER BY r.started_at DESC, r.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]core.RunInfo, 0)
+	for rows.Next() {
+		var (
+			run        core.RunInfo
+			finishedAt sql.NullTime
+		)
+		if err := rows.Scan(&run.RunID, &run.RepoPath, &run.Status, &run.StartedAt, &finishedAt, &run.Flagged); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *PostgresStore) DeleteRun(ctx context.Context, runID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range deleteRunStatements {
+		if _, err := tx.ExecContext(ctx, strings.Replace(stmt, "?", "$1", 1), runID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) AddArtifact(ctx context.Context, artifact core.ArtifactRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO artifacts (run_id, tool, kind, path, sha256, size_bytes, created_at, meta_json)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		artifact.RunID,
+		artifact.Tool,
+		artifact.Kind,
+		artifact.Path,
+		artifact.SHA256,
+		artifact.SizeBytes,
+		artifact.CreatedAt.UTC(),
+		artifact.MetaJSON,
+	)
+	return err
+}
+
+func (s *PostgresStore) ListArtifacts(ctx context.Context, runID string) ([]core.ArtifactRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(tool, ''), kind, path, COALESCE(sha256, ''), COALESCE(size_bytes, 0), created_at, COALESCE(meta_json, '')
+		FROM artifacts
+		WHERE run_id = $1
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := make([]core.ArtifactRecord, 0)
+	for rows.Next() {
+		artifact := core.ArtifactRecord{RunID: runID}
+		if err := rows.Scan(&artifact.Tool, &artifact.Kind, &artifact.Path, &artifact.SHA256, &artifact.SizeBytes, &artifact.CreatedAt, &artifact.MetaJSON); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, rows.Err()
+}
+
+func (s *PostgresStore) InsertFindings(ctx context.Context, findings []core.FindingRecord) error {
+	if len(findings) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO findings (run_id, tool, kind, severity, fingerprint, message, file_path, line, col, symbol, test_id, raw_ref, meta_json, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	docs := make([]SearchDocument, 0, len(findings))
+	for _, finding := range findings {
+		var id int64
+		if err := stmt.QueryRowContext(ctx,
+			finding.RunID,
+			finding.Tool,
+			finding.Kind,
+			finding.Severity,
+			finding.Fingerprint,
+			finding.Message,
+			finding.FilePath,
+			finding.Line,
+			finding.Column,
+			finding.Symbol,
+			finding.TestID,
+			finding.RawRef,
+			finding.MetaJSON,
+			finding.CreatedAt.UTC(),
+		).Scan(&id); err != nil {
+			return err
+		}
+		docs = append(docs, findingDocument(finding, id))
+	}
+	if err := postgresIndex(ctx, tx, docs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) ListFindings(ctx context.Context, runID string) ([]core.FindingRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tool, kind, severity, fingerprint, message, COALESCE(file_path, ''), COALESCE(line, 0), COALESCE(col, 0),
+		       COALESCE(symbol, ''), COALESCE(test_id, ''), COALESCE(raw_ref, ''), COALESCE(meta_json, ''), created_at
+		FROM findings
+		WHERE run_id = $1
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	findings := make([]core.FindingRecord, 0)
+	for rows.Next() {
+		finding := core.FindingRecord{RunID: runID}
+		if err := rows.Scan(&finding.Tool, &finding.Kind, &finding.Severity, &finding.Fingerprint, &finding.Message, &finding.FilePath, &finding.Line, &finding.Column,
+			&finding.Symbol, &finding.TestID, &finding.RawRef, &finding.MetaJSON, &finding.CreatedAt); err != nil {
+			return nil, err
+		}
+		findings = append(findings, finding)
+	}
+	return findings, rows.Err()
+}
+
+func (s *PostgresStore) InsertTasks(ctx context.Context, tasks []core.TaskRecord) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO tasks (run_id, tool, task_type, severity, priority, status, fingerprint, title, description, targets_json, validation_json, retry_policy_json, depends_on_json, extra_json, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	docs := make([]SearchDocument, 0, len(tasks))
+	for i, task := range tasks {
+		var id int64
+		if err := stmt.QueryRowContext(ctx,
+			task.RunID,
+			task.Tool,
+			task.TaskType,
+			task.Severity,
+			task.Priority,
+			task.Status,
+			task.Fingerprint,
+			task.Title,
+			task.Description,
+			task.TargetsJSON,
+			task.ValidationJSON,
+			task.RetryPolicyJSON,
+			task.DependsOnJSON,
+			task.ExtraJSON,
+			task.CreatedAt.UTC(),
+			task.UpdatedAt.UTC(),
+		).Scan(&id); err != nil {
+			return err
+		}
+		tasks[i].ID = id
+		docs = append(docs, taskDocument(task, id))
+	}
+	if err := postgresIndex(ctx, tx, docs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const postgresTaskColumns = `id, run_id, tool, task_type, COALESCE(severity, ''), priority, status, fingerprint, title, COALESCE(description, ''),
+	targets_json, validation_json, retry_policy_json, COALESCE(depends_on_json, ''), COALESCE(extra_json, ''),
+	COALESCE(claimed_by, ''), claimed_at, created_at, updated_at`
+
+func scanPostgresTask(row interface{ Scan(...any) error }) (core.TaskRecord, error) {
+	var (
+		task      core.TaskRecord
+		claimedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&task.ID,
+		&task.RunID,
+		&task.Tool,
+		&task.TaskType,
+		&task.Severity,
+		&task.Priority,
+		&task.Status,
+		&task.Fingerprint,
+		&task.Title,
+		&task.Description,
+		&task.TargetsJSON,
+		&task.ValidationJSON,
+		&task.RetryPolicyJSON,
+		&task.DependsOnJSON,
+		&task.ExtraJSON,
+		&task.ClaimedBy,
+		&claimedAt,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	); err != nil {
+		return core.TaskRecord{}, err
+	}
+	if claimedAt.Valid {
+		task.ClaimedAt = claimedAt.Time
+	}
+	return task, nil
+}
+
+func (s *PostgresStore) ListTasks(ctx context.Context, runID string) ([]core.TaskRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+postgresTaskColumns+`
+		FROM tasks
+		WHERE run_id = $1
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]core.TaskRecord, 0)
+	for rows.Next() {
+		task, err := scanPostgresTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *PostgresStore) ClaimNextTask(ctx context.Context, runID string, workerID string) (*core.TaskRecord, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, targets_json
+		FROM tasks
+		WHERE run_id = $1 AND status = 'queued'
+		ORDER BY priority DESC, created_at ASC, id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		id      int64
+		targets string
+	}
+	var queued []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.targets); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		queued = append(queued, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	held, err := heldLocks(ctx, tx, `SELECT lock_key FROM task_locks WHERE run_id = $1`, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows another host is claiming right now are skipped rather than waited
+	// on; if every candidate is skipped the caller backs off and retries.
+	for _, c := range queued {
+		keys := lockKeys(c.targets, s.conflicts)
+		if conflicts(held, keys) {
+			continue
+		}
+		task, err := scanPostgresTask(tx.QueryRowContext(ctx, `
+			SELECT `+postgresTaskColumns+`
+			FROM tasks
+			WHERE id = $1 AND status = 'queued'
+			FOR UPDATE SKIP LOCKED`,
+			c.id,
+		))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		claimedAt := time.Now().UTC()
+		for _, key := range keys {
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO task_locks (run_id, lock_key, task_id, acquired_at)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT DO NOTHING`,
+				runID,
+				key,
+				task.ID,
+				claimedAt,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				return nil, ErrConflict
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE tasks
+			SET status = 'running', claimed_by = $1, claimed_at = $2, updated_at = $2
+			WHERE id = $3`,
+			workerID,
+			claimedAt,
+			task.ID,
+		); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		task.Status = "running"
+		task.ClaimedBy = workerID
+		task.ClaimedAt = claimedAt
+		task.UpdatedAt = claimedAt
+		return &task, nil
+	}
+	return nil, ErrConflict
+}
+
+func (s *PostgresStore) UpdateTaskStatus(ctx context.Context, taskID int64, status string, extraJSON string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = $1, extra_json = $2, updated_at = $3
+		WHERE id = $4`,
+		status,
+		extraJSON,
+		time.Now().UTC(),
+		taskID,
+	); err != nil {
+		return err
+	}
+	if status != "running" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_locks WHERE task_id = $1`, taskID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) CreateAttempt(ctx context.Context, attempt core.AttemptRecord) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO attempts (task_id, attempt_no, status, agent_name, agent_exit_code, validation_exit_code, started_at, summary_json, diff_stats_json, artifacts_json)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`,
+		attempt.TaskID,
+		attempt.AttemptNo,
+		attempt.Status,
+		attempt.AgentName,
+		attempt.AgentExitCode,
+		attempt.ValidationExitCode,
+		attempt.StartedAt.UTC(),
+		attempt.SummaryJSON,
+		attempt.DiffStatsJSON,
+		attempt.ArtifactsJSON,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) FinishAttempt(ctx context.Context, attempt core.AttemptRecord) error {
+	finishedAt := attempt.FinishedAt
+	if finishedAt.IsZero() {
+		finishedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE attempts
+		SET status = $1, agent_exit_code = $2, validation_exit_code = $3, summary_json = $4, diff_stats_json = $5, artifacts_json = $6, finished_at = $7
+		WHERE id = $8`,
+		attempt.Status,
+		attempt.AgentExitCode,
+		attempt.ValidationExitCode,
+		attempt.SummaryJSON,
+		attempt.DiffStatsJSON,
+		attempt.ArtifactsJSON,
+		finishedAt.UTC(),
+		attempt.ID,
+	)
+	return err
+}
+
+func (s *PostgresStore) ListAttempts(ctx context.Context, taskID int64) ([]core.AttemptRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, attempt_no, status, COALESCE(agent_name, ''), COALESCE(agent_exit_code, 0), COALESCE(validation_exit_code, 0),
+		       started_at, finished_at, COALESCE(summary_json, ''), COALESCE(diff_stats_json, ''), COALESCE(artifacts_json, '')
+		FROM attempts
+		WHERE task_id = $1
+		ORDER BY attempt_no ASC, id ASC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]core.AttemptRecord, 0)
+	for rows.Next() {
+		var (
+			attempt    core.AttemptRecord
+			finishedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.AttemptNo,
+			&attempt.Status,
+			&attempt.AgentName,
+			&attempt.AgentExitCode,
+			&attempt.ValidationExitCode,
+			&attempt.StartedAt,
+			&finishedAt,
+			&attempt.SummaryJSON,
+			&attempt.DiffStatsJSON,
+			&attempt.ArtifactsJSON,
+		); err != nil {
+			return nil, err
+		}
+		attempt.TaskID = taskID
+		if finishedAt.Valid {
+			attempt.FinishedAt = finishedAt.Time
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func postgresHasColumn(ctx context.Context, tx *sql.Tx, table string, column string) (bool, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2`,
+		table,
+		column,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (s *PostgresStore) IndexDocuments(ctx context.Context, docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := postgresIndex(ctx, tx, docs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func postgresIndex(ctx context.Context, tx *sql.Tx, docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, doc := range docs {
+		// Postgres text cannot hold NUL bytes, which captured output may contain.
+		body := strings.ReplaceAll(doc.Body, "\x00", "")
+		if _, err := stmt.ExecContext(ctx, doc.RunID, doc.Kind, doc.RefID, doc.Tool, doc.Path, doc.Title, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	terms := tsQuery(query.Text)
+	if terms == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT run_id, kind, COALESCE(ref_id, 0), tool, path, title,
+		       ts_headline('english', body, q, 'StartSel=[, StopSel=], MaxFragments=1, MaxWords=24, MinWords=8'),
+		       ts_rank(document, q)
+		FROM search_index, to_tsquery('english', $1) q
+		WHERE document @@ q AND ($2 = '' OR run_id = $2) AND ($3 = '' OR tool = $3)
+		ORDER BY ts_rank(document, q) DESC
+		LIMIT $4`,
+		terms,
+		query.RunID,
+		query.Tool,
+		searchLimit(query.Limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]SearchHit, 0)
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.RunID, &hit.Kind, &hit.RefID, &hit.Tool, &hit.Path, &hit.Title, &hit.Snippet, &hit.Score); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+
+	"atqos/internal/core"
+)
+
+const (
+	SearchFinding = "finding"
+	SearchTask    = "task"
+	SearchOutput  = "output"
+
+	defaultSearchLimit = 20
+)
+
+type SearchDocument struct {
+	RunID string
+	Kind  string
+	RefID int64
+	Tool  string
+	Path  string
+	Title string
+	Body  string
+}
+
+type SearchQuery struct {
+	Text  string
+	RunID string
+	Tool  string
+	Limit int
+}
+
+// SearchHit scores are backend specific; higher is always better.
+type SearchHit struct {
+	RunID   string
+	Kind    string
+	RefID   int64
+	Tool    string
+	Path    string
+	Title   string
+	Snippet string
+	Score   float64
+}
+
+func findingDocument(finding core.FindingRecord, id int64) SearchDocument {
+	title := finding.TestID
+	if title == "" {
+		title = finding.FilePath
+	}
+	return SearchDocument{
+		RunID: finding.RunID,
+		Kind:  SearchFinding,
+		RefID: id,
+		Tool:  finding.Tool,
+		Path:  finding.FilePath,
+		Title: title,
+		Body:  finding.Message,
+	}
+}
+
+func taskDocument(task core.TaskRecord, id int64) SearchDocument {
+	return SearchDocument{
+		RunID: task.RunID,
+		Kind:  SearchTask,
+		RefID: id,
+		Tool:  task.Tool,
+		Title: task.Title,
+		Body:  task.Description,
+	}
+}
+
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// ftsQuery quotes each term so user input never hits FTS5 query syntax.
+// Terms match as prefixes and are implicitly ANDed.
+func ftsQuery(text string) string {
+	terms := searchTerms(text)
+	for i, term := range terms {
+		terms[i] = `"` + term + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+func tsQuery(text string) string {
+	terms := searchTerms(text)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSearchLimit
+	}
+	return limit
+}
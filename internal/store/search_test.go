@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFTSQueryQuotesTerms(t *testing.T) {
+	cases := map[string]string{
+		"":                         "",
+		"  ":                       "",
+		"parser":                   `"parser"*`,
+		"parse_config empty":       `"parse_config"* "empty"*`,
+		`foo" OR bar`:              `"foo"* "OR"* "bar"*`,
+		"NEAR(a b) -c col:val ^x*": `"NEAR"* "a"* "b"* "c"* "col"* "val"* "x"*`,
+		"tests/test_parser.py::t1": `"tests"* "test_parser"* "py"* "t1"*`,
+		"café 42":                  `"café"* "42"*`,
+	}
+	for text, want := range cases {
+		if got := ftsQuery(text); got != want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestSQLiteSearchAcceptsQuerySyntax(t *testing.T) {
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "atqos.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	if err := s.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.IndexDocuments(ctx, []SearchDocument{{
+		RunID: "run-1",
+		Kind:  "finding",
+		RefID: 1,
+		Tool:  "pytest",
+		Path:  "tests/test_parser.py",
+		Title: "parse_config fails on empty input",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for text, hits := range map[string]int{
+		"parse_conf":         1,
+		"EMPTY parse_config": 1,
+		`empty" OR "missing`: 0,
+		"NEAR(empty input)":  0,
+		"title:empty":        0,
+		"-empty *":           1,
+		"():\"":              0,
+	} {
+		got, err := s.Search(ctx, SearchQuery{Text: text})
+		if err != nil {
+			t.Errorf("Search(%q): %v", text, err)
+			continue
+		}
+		if len(got) != hits {
+			t.Errorf("Search(%q) = %d hits, want %d", text, len(got), hits)
+		}
+	}
+}
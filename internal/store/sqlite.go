@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,119 +13,37 @@ import (
 )
 
 type SQLiteStore struct {
-	db *sql.DB
+	db        *sql.DB
+	conflicts string
 }
 
 func NewSQLite(path string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite", path)
+	// Transactions read before they write; taking the write lock up front
+	// lets concurrent claims wait on busy_timeout instead of failing when
+	// the read lock cannot be upgraded.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
-	return &SQLiteStore{db: db}, nil
+	return &SQLiteStore{db: db, conflicts: ConflictFile}, nil
+}
+
+func (s *SQLiteStore) WithConflictGranularity(granularity string) *SQLiteStore {
+	s.conflicts = granularity
+	return s
 }
 
 func (s *SQLiteStore) Init(ctx context.Context) error {
-	ddl := []string{
+	for _, pragma := range []string{
 		`PRAGMA journal_mode=WAL;`,
 		`PRAGMA foreign_keys=ON;`,
-		`CREATE TABLE IF NOT EXISTS runs (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			run_id TEXT NOT NULL UNIQUE,
-			repo_path TEXT NOT NULL,
-			started_at TEXT NOT NULL,
-			finished_at TEXT,
-			status TEXT NOT NULL,
-			config_json TEXT NOT NULL,
-			summary_json TEXT
-		);`,
-		`CREATE TABLE IF NOT EXISTS artifacts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			run_id TEXT NOT NULL,
-			tool TEXT,
-			kind TEXT NOT NULL,
-			path TEXT NOT NULL,
-			sha256 TEXT,
-			size_bytes INTEGER,
-			created_at TEXT NOT NULL,
-			meta_json TEXT,
-			FOREIGN KEY(run_id) REFERENCES runs(run_id)
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_artifacts_run_id ON artifacts(run_id);`,
-		`CREATE TABLE IF NOT EXISTS findings (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			run_id TEXT NOT NULL,
-			tool TEXT NOT NULL,
-			kind TEXT NOT NULL,
-			severity TEXT NOT NULL,
-			fingerprint TEXT NOT NULL,
-			message TEXT NOT NULL,
-			file_path TEXT,
-			line INTEGER,
-			col INTEGER,
-			symbol TEXT,
-			test_id TEXT,
-			raw_ref TEXT,
-			meta_json TEXT,
-			created_at TEXT NOT NULL,
-			FOREIGN KEY(run_id) REFERENCES runs(run_id)
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_findings_run_tool ON findings(run_id, tool);`,
-		`CREATE INDEX IF NOT EXISTS idx_findings_fingerprint ON findings(fingerprint);`,
-		`CREATE TABLE IF NOT EXISTS tasks (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			run_id TEXT NOT NULL,
-			tool TEXT NOT NULL,
-			task_type TEXT NOT NULL,
-			priority INTEGER NOT NULL,
-			status TEXT NOT NULL,
-			fingerprint TEXT NOT NULL,
-			title TEXT NOT NULL,
-			description TEXT,
-			targets_json TEXT NOT NULL,
-			validation_json TEXT NOT NULL,
-			retry_policy_json TEXT NOT NULL,
-			depends_on_json TEXT,
-			extra_json TEXT,
-			claimed_by TEXT,
-			claimed_at TEXT,
-			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL,
-			FOREIGN KEY(run_id) REFERENCES runs(run_id)
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_run_status ON tasks(run_id, status);`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_fingerprint ON tasks(fingerprint);`,
-		`CREATE TABLE IF NOT EXISTS attempts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			task_id INTEGER NOT NULL,
-			attempt_no INTEGER NOT NULL,
-			status TEXT NOT NULL,
-			agent_name TEXT,
-			agent_exit_code INTEGER,
-			validation_exit_code INTEGER,
-			started_at TEXT NOT NULL,
-			finished_at TEXT,
-			summary_json TEXT,
-			diff_stats_json TEXT,
-			artifacts_json TEXT,
-			FOREIGN KEY(task_id) REFERENCES tasks(id)
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_attempts_task_id ON attempts(task_id);`,
-		`CREATE TABLE IF NOT EXISTS task_findings (
-			task_id INTEGER NOT NULL,
-			finding_id INTEGER NOT NULL,
-			PRIMARY KEY(task_id, finding_id),
-			FOREIGN KEY(task_id) REFERENCES tasks(id),
-			FOREIGN KEY(finding_id) REFERENCES findings(id)
-		);`,
-	}
-
-	for _, stmt := range ddl {
-		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+	} {
+		if _, err := s.db.ExecContext(ctx, pragma); err != nil {
 			return err
 		}
 	}
-
-	return nil
+	_, err := s.Migrate(ctx)
+	return err
 }
 
 func (s *SQLiteStore) Close() error {
@@ -135,14 +54,23 @@ func (s *SQLiteStore) Close() error {
 }
 
 func (s *SQLiteStore) CreateRun(ctx context.Context, run core.RunRecord) error {
+	var finishedAt sql.NullString
+	if !run.FinishedAt.IsZero() {
+		finishedAt = sql.NullString{String: run.FinishedAt.UTC().Format(time.RFC3339), Valid: true}
+	}
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO runs (run_id, repo_path, started_at, status, config_json)
-		VALUES (?, ?, ?, ?, ?)`,
+		INSERT INTO runs (run_id, repo_path, started_at, status, config_json, base_commit, dirty_diff, snapshot_commit, finished_at, summary_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.RunID,
 		run.RepoPath,
 		run.StartedAt.UTC().Format(time.RFC3339),
 		run.Status,
 		run.Config,
+		run.BaseCommit,
+		run.DirtyDiff,
+		run.SnapshotCommit,
+		finishedAt,
+		run.SummaryJSON,
 	)
 	return err
 }
@@ -176,6 +104,34 @@ func (s *SQLiteStore) AddArtifact(ctx context.Context, artifact core.ArtifactRec
 	return err
 }
 
+func (s *SQLiteStore) ListArtifacts(ctx context.Context, runID string) ([]core.ArtifactRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(tool, ''), kind, path, COALESCE(sha256, ''), COALESCE(size_bytes, 0), created_at, COALESCE(meta_json, '')
+		FROM artifacts
+		WHERE run_id = ?
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := make([]core.ArtifactRecord, 0)
+	for rows.Next() {
+		var (
+			artifact  = core.ArtifactRecord{RunID: runID}
+			createdAt string
+		)
+		if err := rows.Scan(&artifact.Tool, &artifact.Kind, &artifact.Path, &artifact.SHA256, &artifact.SizeBytes, &createdAt, &artifact.MetaJSON); err != nil {
+			return nil, err
+		}
+		artifact.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, rows.Err()
+}
+
 func (s *SQLiteStore) InsertFindings(ctx context.Context, findings []core.FindingRecord) error {
 	if len(findings) == 0 {
 		return nil
@@ -195,8 +151,9 @@ func (s *SQLiteStore) InsertFindings(ctx context.Context, findings []core.Findin
 	}
 	defer stmt.Close()
 
+	docs := make([]SearchDocument, 0, len(findings))
 	for _, finding := range findings {
-		if _, err := stmt.ExecContext(ctx,
+		result, err := stmt.ExecContext(ctx,
 			finding.RunID,
 			finding.Tool,
 			finding.Kind,
@@ -211,14 +168,53 @@ func (s *SQLiteStore) InsertFindings(ctx context.Context, findings []core.Findin
 			finding.RawRef,
 			finding.MetaJSON,
 			finding.CreatedAt.UTC().Format(time.RFC3339),
-		); err != nil {
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
 			return err
 		}
+		docs = append(docs, findingDocument(finding, id))
+	}
+	if err := sqliteIndex(ctx, tx, docs); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
+func (s *SQLiteStore) ListFindings(ctx context.Context, runID string) ([]core.FindingRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tool, kind, severity, fingerprint, message, COALESCE(file_path, ''), COALESCE(line, 0), COALESCE(col, 0),
+		       COALESCE(symbol, ''), COALESCE(test_id, ''), COALESCE(raw_ref, ''), COALESCE(meta_json, ''), created_at
+		FROM findings
+		WHERE run_id = ?
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	findings := make([]core.FindingRecord, 0)
+	for rows.Next() {
+		var (
+			finding   = core.FindingRecord{RunID: runID}
+			createdAt string
+		)
+		if err := rows.Scan(&finding.Tool, &finding.Kind, &finding.Severity, &finding.Fingerprint, &finding.Message, &finding.FilePath, &finding.Line, &finding.Column,
+			&finding.Symbol, &finding.TestID, &finding.RawRef, &finding.MetaJSON, &createdAt); err != nil {
+			return nil, err
+		}
+		finding.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		findings = append(findings, finding)
+	}
+	return findings, rows.Err()
+}
+
 func (s *SQLiteStore) InsertTasks(ctx context.Context, tasks []core.TaskRecord) error {
 	if len(tasks) == 0 {
 		return nil
@@ -231,18 +227,20 @@ func (s *SQLiteStore) InsertTasks(ctx context.Context, tasks []core.TaskRecord)
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO tasks (run_id, tool, task_type, priority, status, fingerprint, title, description, targets_json, validation_json, retry_policy_json, depends_on_json, extra_json, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		INSERT INTO tasks (run_id, tool, task_type, severity, priority, status, fingerprint, title, description, targets_json, validation_json, retry_policy_json, depends_on_json, extra_json, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	for _, task := range tasks {
-		if _, err := stmt.ExecContext(ctx,
+	docs := make([]SearchDocument, 0, len(tasks))
+	for i, task := range tasks {
+		result, err := stmt.ExecContext(ctx,
 			task.RunID,
 			task.Tool,
 			task.TaskType,
+			task.Severity,
 			task.Priority,
 			task.Status,
 			task.Fingerprint,
@@ -255,9 +253,19 @@ func (s *SQLiteStore) InsertTasks(ctx context.Context, tasks []core.TaskRecord)
 			task.ExtraJSON,
 			task.CreatedAt.UTC().Format(time.RFC3339),
 			task.UpdatedAt.UTC().Format(time.RFC3339),
-		); err != nil {
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
 			return err
 		}
+		tasks[i].ID = id
+		docs = append(docs, taskDocument(task, id))
+	}
+	if err := sqliteIndex(ctx, tx, docs); err != nil {
+		return err
 	}
 
 	return tx.Commit()
@@ -270,17 +278,94 @@ func (s *SQLiteStore) ClaimNextTask(ctx context.Context, runID string, workerID
 	}
 	defer tx.Rollback()
 
-	row := tx.QueryRowContext(ctx, `
-		SELECT id, tool, task_type, priority, status, fingerprint, title, description,
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tool, task_type, COALESCE(severity, ''), priority, status, fingerprint, title, description,
 		       targets_json, validation_json, retry_policy_json, depends_on_json, extra_json,
 		       claimed_by, claimed_at, created_at, updated_at
 		FROM tasks
 		WHERE run_id = ? AND status = 'queued'
-		ORDER BY priority DESC, created_at ASC
-		LIMIT 1`,
+		ORDER BY priority DESC, created_at ASC, id ASC`,
 		runID,
 	)
+	if err != nil {
+		return nil, err
+	}
+	var queued []core.TaskRecord
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		task.RunID = runID
+		queued = append(queued, task)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	held, err := heldLocks(ctx, tx, `SELECT lock_key FROM task_locks WHERE run_id = ?`, runID)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		task *core.TaskRecord
+		keys []string
+	)
+	for i := range queued {
+		candidate := lockKeys(queued[i].TargetsJSON, s.conflicts)
+		if !conflicts(held, candidate) {
+			task = &queued[i]
+			keys = candidate
+			break
+		}
+	}
+	if task == nil {
+		return nil, ErrConflict
+	}
+
+	claimedAtText := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = 'running', claimed_by = ?, claimed_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'queued'`,
+		workerID,
+		claimedAtText,
+		claimedAtText,
+		task.ID,
+	); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO task_locks (run_id, lock_key, task_id, acquired_at)
+			VALUES (?, ?, ?, ?)`,
+			runID,
+			key,
+			task.ID,
+			claimedAtText,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	task.Status = "running"
+	task.ClaimedBy = workerID
+	task.ClaimedAt, _ = time.Parse(time.RFC3339, claimedAtText)
+	task.UpdatedAt = task.ClaimedAt
+	return task, nil
+}
 
+func scanTask(rows *sql.Rows) (core.TaskRecord, error) {
 	var (
 		task      core.TaskRecord
 		claimedBy sql.NullString
@@ -288,11 +373,11 @@ func (s *SQLiteStore) ClaimNextTask(ctx context.Context, runID string, workerID
 		createdAt string
 		updatedAt string
 	)
-
-	err = row.Scan(
+	if err := rows.Scan(
 		&task.ID,
 		&task.Tool,
 		&task.TaskType,
+		&task.Severity,
 		&task.Priority,
 		&task.Status,
 		&task.Fingerprint,
@@ -307,15 +392,9 @@ func (s *SQLiteStore) ClaimNextTask(ctx context.Context, runID string, workerID
 		&claimedAt,
 		&createdAt,
 		&updatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
+	); err != nil {
+		return core.TaskRecord{}, err
 	}
-
-	task.RunID = runID
 	if claimedBy.Valid {
 		task.ClaimedBy = claimedBy.String
 	}
@@ -324,33 +403,17 @@ func (s *SQLiteStore) ClaimNextTask(ctx context.Context, runID string, workerID
 	}
 	task.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	task.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-	claimedAtText := time.Now().UTC().Format(time.RFC3339)
-	if _, err := tx.ExecContext(ctx, `
-		UPDATE tasks
-		SET status = 'running', claimed_by = ?, claimed_at = ?, updated_at = ?
-		WHERE id = ? AND status = 'queued'`,
-		workerID,
-		claimedAtText,
-		claimedAtText,
-		task.ID,
-	); err != nil {
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, err
-	}
-
-	task.Status = "running"
-	task.ClaimedBy = workerID
-	task.ClaimedAt, _ = time.Parse(time.RFC3339, claimedAtText)
-	task.UpdatedAt = task.ClaimedAt
-	return &task, nil
+	return task, nil
 }
 
 func (s *SQLiteStore) UpdateTaskStatus(ctx context.Context, taskID int64, status string, extraJSON string) error {
-	_, err := s.db.ExecContext(ctx, `
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE tasks
 		SET status = ?, extra_json = ?, updated_at = ?
 		WHERE id = ?`,
@@ -358,8 +421,15 @@ func (s *SQLiteStore) UpdateTaskStatus(ctx context.Context, taskID int64, status
 		extraJSON,
 		time.Now().UTC().Format(time.RFC3339),
 		taskID,
-	)
-	return err
+	); err != nil {
+		return err
+	}
+	if status != "running" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_locks WHERE task_id = ?`, taskID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *SQLiteStore) CreateAttempt(ctx context.Context, attempt core.AttemptRecord) (int64, error) {
@@ -383,20 +453,125 @@ func (s *SQLiteStore) CreateAttempt(ctx context.Context, attempt core.AttemptRec
 	return result.LastInsertId()
 }
 
-func (s *SQLiteStore) FinishAttempt(ctx context.Context, attemptID int64, status string, summaryJSON string, validationExitCode int) error {
+func (s *SQLiteStore) FinishAttempt(ctx context.Context, attempt core.AttemptRecord) error {
+	finishedAt := attempt.FinishedAt
+	if finishedAt.IsZero() {
+		finishedAt = time.Now()
+	}
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE attempts
-		SET status = ?, summary_json = ?, validation_exit_code = ?, finished_at = ?
+		SET status = ?, agent_exit_code = ?, validation_exit_code = ?, summary_json = ?, diff_stats_json = ?, artifacts_json = ?, finished_at = ?
 		WHERE id = ?`,
-		status,
-		summaryJSON,
-		validationExitCode,
-		time.Now().UTC().Format(time.RFC3339),
-		attemptID,
+		attempt.Status,
+		attempt.AgentExitCode,
+		attempt.ValidationExitCode,
+		attempt.SummaryJSON,
+		attempt.DiffStatsJSON,
+		attempt.ArtifactsJSON,
+		finishedAt.UTC().Format(time.RFC3339),
+		attempt.ID,
 	)
 	return err
 }
 
+func (s *SQLiteStore) ListAttempts(ctx context.Context, taskID int64) ([]core.AttemptRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, attempt_no, status, COALESCE(agent_name, ''), COALESCE(agent_exit_code, 0), COALESCE(validation_exit_code, 0),
+		       started_at, finished_at, COALESCE(summary_json, ''), COALESCE(diff_stats_json, ''), COALESCE(artifacts_json, '')
+		FROM attempts
+		WHERE task_id = ?
+		ORDER BY attempt_no ASC, id ASC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]core.AttemptRecord, 0)
+	for rows.Next() {
+		var (
+			attempt    core.AttemptRecord
+			startedAt  string
+			finishedAt sql.NullString
+		)
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.AttemptNo,
+			&attempt.Status,
+			&attempt.AgentName,
+			&attempt.AgentExitCode,
+			&attempt.ValidationExitCode,
+			&startedAt,
+			&finishedAt,
+			&attempt.SummaryJSON,
+			&attempt.DiffStatsJSON,
+			&attempt.ArtifactsJSON,
+		); err != nil {
+			return nil, err
+		}
+		attempt.TaskID = taskID
+		attempt.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		if finishedAt.Valid {
+			attempt.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt.String)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func (s *SQLiteStore) GetRun(ctx context.Context, runID string) (core.RunRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT run_id, repo_path, started_at, status, config_json,
+		       COALESCE(base_commit, ''), COALESCE(dirty_diff, ''), COALESCE(snapshot_commit, ''),
+		       finished_at, COALESCE(summary_json, '')
+		FROM runs
+		WHERE run_id = ?`,
+		runID,
+	)
+
+	var (
+		run        core.RunRecord
+		startedAt  string
+		finishedAt sql.NullString
+	)
+	if err := row.Scan(&run.RunID, &run.RepoPath, &startedAt, &run.Status, &run.Config, &run.BaseCommit, &run.DirtyDiff, &run.SnapshotCommit, &finishedAt, &run.SummaryJSON); err != nil {
+		return core.RunRecord{}, notFound(err, "run "+runID)
+	}
+	run.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+	if finishedAt.Valid {
+		run.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt.String)
+	}
+	return run, nil
+}
+
+func (s *SQLiteStore) ListTasks(ctx context.Context, runID string) ([]core.TaskRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tool, task_type, COALESCE(severity, ''), priority, status, fingerprint, title, description,
+		       targets_json, validation_json, retry_policy_json, depends_on_json, extra_json,
+		       claimed_by, claimed_at, created_at, updated_at
+		FROM tasks
+		WHERE run_id = ?
+		ORDER BY id ASC`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]core.TaskRecord, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		task.RunID = runID
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
 func (s *SQLiteStore) GetRunSummary(ctx context.Context, runID string) (core.RunSummary, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT status, started_at, finished_at
@@ -411,7 +586,7 @@ func (s *SQLiteStore) GetRunSummary(ctx context.Context, runID string) (core.Run
 		finishedAt sql.NullString
 	)
 	if err := row.Scan(&status, &startedAt, &finishedAt); err != nil {
-		return core.RunSummary{}, err
+		return core.RunSummary{}, notFound(err, "run "+runID)
 	}
 
 	var findingCount int
@@ -439,3 +614,140 @@ func (s *SQLiteStore) GetRunSummary(ctx context.Context, runID string) (core.Run
 		Finished: finished,
 	}, nil
 }
+
+func (s *SQLiteStore) ListRuns(ctx context.Context) ([]core.RunInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.run_id, r.repo_path, r.status, r.started_at, r.finished_at,
+		       EXISTS (SELECT 1 FROM tasks t WHERE t.run_id = r.run_id AND t.extra_json LIKE '%"escalation"%')
+		FROM runs r
+		ORDER BY r.started_at DESC, r.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]core.RunInfo, 0)
+	for rows.Next() {
+		var (
+			run        core.RunInfo
+			startedAt  string
+			finishedAt sql.NullString
+		)
+		if err := rows.Scan(&run.RunID, &run.RepoPath, &run.Status, &startedAt, &finishedAt, &run.Flagged); err != nil {
+			return nil, err
+		}
+		run.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		if finishedAt.Valid {
+			run.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt.String)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteRun(ctx context.Context, runID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range deleteRunStatements {
+		if _, err := tx.ExecContext(ctx, stmt, runID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+var deleteRunStatements = []string{
+	`DELETE FROM task_findings WHERE task_id IN (SELECT id FROM tasks WHERE run_id = ?)`,
+	`DELETE FROM task_locks WHERE run_id = ?`,
+	`DELETE FROM attempts WHERE task_id IN (SELECT id FROM tasks WHERE run_id = ?)`,
+	`DELETE FROM tasks WHERE run_id = ?`,
+	`DELETE FROM findings WHERE run_id = ?`,
+	`DELETE FROM artifacts WHERE run_id = ?`,
+	`DELETE FROM search_index WHERE run_id = ?`,
+	`DELETE FROM runs WHERE run_id = ?`,
+}
+
+func (s *SQLiteStore) IndexDocuments(ctx context.Context, docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := sqliteIndex(ctx, tx, docs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sqliteIndex(ctx context.Context, tx *sql.Tx, docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO search_index (run_id, kind, ref_id, tool, path, title, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, doc := range docs {
+		if _, err := stmt.ExecContext(ctx, doc.RunID, doc.Kind, doc.RefID, doc.Tool, doc.Path, doc.Title, doc.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	match := ftsQuery(query.Text)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT run_id, kind, COALESCE(ref_id, 0), tool, path, title,
+		       snippet(search_index, 6, '[', ']', '...', 16), bm25(search_index, 0, 0, 0, 0, 0, 2.0, 1.0)
+		FROM search_index
+		WHERE search_index MATCH ? AND (? = '' OR run_id = ?) AND (? = '' OR tool = ?)
+		ORDER BY bm25(search_index, 0, 0, 0, 0, 0, 2.0, 1.0)
+		LIMIT ?`,
+		match,
+		query.RunID, query.RunID,
+		query.Tool, query.Tool,
+		searchLimit(query.Limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]SearchHit, 0)
+	for rows.Next() {
+		var (
+			hit  SearchHit
+			bm25 float64
+		)
+		if err := rows.Scan(&hit.RunID, &hit.Kind, &hit.RefID, &hit.Tool, &hit.Path, &hit.Title, &hit.Snippet, &bm25); err != nil {
+			return nil, err
+		}
+		hit.Score = -bm25
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func notFound(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	return err
+}
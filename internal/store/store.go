@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"atqos/internal/core"
+)
+
+const MemoryDSN = ":memory:"
+
+var ErrNotFound = errors.New("not found")
+
+var (
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*PostgresStore)(nil)
+
+	_ Migrator = (*SQLiteStore)(nil)
+	_ Migrator = (*PostgresStore)(nil)
+)
+
+type Store interface {
+	Init(ctx context.Context) error
+	Close() error
+
+	CreateRun(ctx context.Context, run core.RunRecord) error
+	UpdateRunStatus(ctx context.Context, runID string, status string, summaryJSON string) error
+	GetRun(ctx context.Context, runID string) (core.RunRecord, error)
+	GetRunSummary(ctx context.Context, runID string) (core.RunSummary, error)
+	ListRuns(ctx context.Context) ([]core.RunInfo, error)
+	DeleteRun(ctx context.Context, runID string) error
+
+	AddArtifact(ctx context.Context, artifact core.ArtifactRecord) error
+	ListArtifacts(ctx context.Context, runID string) ([]core.ArtifactRecord, error)
+	InsertFindings(ctx context.Context, findings []core.FindingRecord) error
+	ListFindings(ctx context.Context, runID string) ([]core.FindingRecord, error)
+	IndexDocuments(ctx context.Context, docs []SearchDocument) error
+	Search(ctx context.Context, query SearchQuery) ([]SearchHit, error)
+
+	// InsertTasks sets the ID of each task in tasks to its stored ID.
+	InsertTasks(ctx context.Context, tasks []core.TaskRecord) error
+	ListTasks(ctx context.Context, runID string) ([]core.TaskRecord, error)
+	ClaimNextTask(ctx context.Context, runID string, workerID string) (*core.TaskRecord, error)
+	UpdateTaskStatus(ctx context.Context, taskID int64, status string, extraJSON string) error
+
+	CreateAttempt(ctx context.Context, attempt core.AttemptRecord) (int64, error)
+	FinishAttempt(ctx context.Context, attempt core.AttemptRecord) error
+	ListAttempts(ctx context.Context, taskID int64) ([]core.AttemptRecord, error)
+}
+
+func IsPath(dsn string) bool {
+	return dsn != MemoryDSN && !IsPostgresDSN(dsn)
+}
+
+func Open(dsn string, conflicts string) (Store, error) {
+	if dsn == MemoryDSN {
+		return NewMemory().WithConflictGranularity(conflicts), nil
+	}
+	if IsPostgresDSN(dsn) {
+		s, err := NewPostgres(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return s.WithConflictGranularity(conflicts), nil
+	}
+	s, err := NewSQLite(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return s.WithConflictGranularity(conflicts), nil
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"atqos/internal/core"
+)
+
+// postgresDSNEnv names the environment variable holding a DSN for a
+// scratch Postgres database; the Postgres conformance run is skipped
+// without it unless postgresRequiredEnv is set, as it is in CI.
+const (
+	postgresDSNEnv      = "ATQOS_TEST_POSTGRES_DSN"
+	postgresRequiredEnv = "ATQOS_TEST_REQUIRE_POSTGRES"
+)
+
+func TestStoreConformance(t *testing.T) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) Store
+	}{
+		{"sqlite", func(t *testing.T) Store {
+			s, err := NewSQLite(filepath.Join(t.TempDir(), "atqos.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}},
+		{"memory", func(t *testing.T) Store {
+			return NewMemory()
+		}},
+		{"postgres", func(t *testing.T) Store {
+			dsn := os.Getenv(postgresDSNEnv)
+			if dsn == "" && os.Getenv(postgresRequiredEnv) != "" {
+				t.Fatalf("%s is set but %s is not", postgresRequiredEnv, postgresDSNEnv)
+			}
+			if dsn == "" {
+				t.Skipf("%s not set", postgresDSNEnv)
+			}
+			s, err := NewPostgres(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			defer s.Close()
+			ctx := context.Background()
+			if err := s.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+			runID := fmt.Sprintf("run-conformance-%d", time.Now().UnixNano())
+			defer s.DeleteRun(ctx, runID)
+
+			checkRuns(t, ctx, s, runID)
+			checkFindings(t, ctx, s, runID)
+			checkClaims(t, ctx, s, runID)
+			checkConcurrentClaims(t, ctx, s, runID+"-concurrent")
+			checkDeleteRun(t, ctx, s, runID)
+		})
+	}
+}
+
+func checkRuns(t *testing.T, ctx context.Context, s Store, runID string) {
+	t.Helper()
+	run := core.RunRecord{
+		RunID:      runID,
+		RepoPath:   "/repo",
+		StartedAt:  time.Now(),
+		Status:     core.RunStatusRunning,
+		Config:     `{}`,
+		BaseCommit: "abc123",
+	}
+	if err := s.CreateRun(ctx, run); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if err := s.UpdateRunStatus(ctx, runID, core.RunStatusSucceeded, `{"Tasks":2}`); err != nil {
+		t.Fatalf("update run: %v", err)
+	}
+	got, err := s.GetRun(ctx, runID)
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if got.RepoPath != "/repo" || got.BaseCommit != "abc123" || got.Status != core.RunStatusSucceeded || got.SummaryJSON != `{"Tasks":2}` {
+		t.Fatalf("get run = %+v", got)
+	}
+	infos, err := s.ListRuns(ctx)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	found := false
+	for _, info := range infos {
+		found = found || info.RunID == runID
+	}
+	if !found {
+		t.Fatalf("list runs does not include %s", runID)
+	}
+}
+
+func checkFindings(t *testing.T, ctx context.Context, s Store, runID string) {
+	t.Helper()
+	findings := []core.FindingRecord{
+		{RunID: runID, Tool: "pytest", Kind: "test_failure", Fingerprint: "f1", Message: "boom", FilePath: "tests/test_a.py", TestID: "tests/test_a.py::test_x", CreatedAt: time.Now()},
+		{RunID: runID, Tool: "coverage", Kind: "coverage_gap", Fingerprint: "f2", Message: "low", FilePath: "src/a.py", Line: 3, CreatedAt: time.Now()},
+	}
+	if err := s.InsertFindings(ctx, findings); err != nil {
+		t.Fatalf("insert findings: %v", err)
+	}
+	got, err := s.ListFindings(ctx, runID)
+	if err != nil {
+		t.Fatalf("list findings: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("list findings = %d rows, want 2", len(got))
+	}
+	byFingerprint := make(map[string]core.FindingRecord)
+	for _, finding := range got {
+		byFingerprint[finding.Fingerprint] = finding
+	}
+	if byFingerprint["f1"].TestID != "tests/test_a.py::test_x" || byFingerprint["f2"].Line != 3 {
+		t.Fatalf("list findings = %+v", got)
+	}
+}
+
+// checkConcurrentClaims races workers over tasks where two pairs share a
+// file: every task is claimed at most once and no two running tasks hold
+// the same lock.
+func checkConcurrentClaims(t *testing.T, ctx context.Context, s Store, runID string) {
+	t.Helper()
+	if err := s.CreateRun(ctx, core.RunRecord{RunID: runID, RepoPath: "/repo", StartedAt: time.Now(), Status: core.RunStatusRunning, Config: `{}`}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	defer s.DeleteRun(ctx, runID)
+	var tasks []core.TaskRecord
+	for i, file := range []string{"a", "b", "c", "d", "a", "b"} {
+		tasks = append(tasks, core.TaskRecord{
+			RunID:       runID,
+			Tool:        "pytest",
+			TaskType:    "fix_test",
+			Status:      "queued",
+			Fingerprint: fmt.Sprintf("c-%d", i),
+			TargetsJSON: fmt.Sprintf(`{"files":["src/%s.py"]}`, file),
+			CreatedAt:   time.Now(),
+		})
+	}
+	if err := s.InsertTasks(ctx, tasks); err != nil {
+		t.Fatalf("insert tasks: %v", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed []*core.TaskRecord
+		wg      sync.WaitGroup
+	)
+	claim := func(worker string) bool {
+		task, err := s.ClaimNextTask(ctx, runID, worker)
+		if err != nil && !errors.Is(err, ErrConflict) {
+			t.Errorf("%s: claim: %v", worker, err)
+		}
+		if task == nil {
+			return false
+		}
+		mu.Lock()
+		claimed = append(claimed, task)
+		mu.Unlock()
+		return true
+	}
+	for i := 0; i < len(tasks); i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			for claim(worker) {
+			}
+		}(fmt.Sprintf("w%d", i))
+	}
+	wg.Wait()
+	// A claim can lose a race and report a conflict while other tasks are
+	// still free; drain those sequentially.
+	for claim("drain") {
+	}
+
+	seen := make(map[int64]bool)
+	files := make(map[string]bool)
+	for _, task := range claimed {
+		if seen[task.ID] {
+			t.Fatalf("task %d claimed twice", task.ID)
+		}
+		seen[task.ID] = true
+		if files[task.TargetsJSON] {
+			t.Fatalf("two running tasks lock %s", task.TargetsJSON)
+		}
+		files[task.TargetsJSON] = true
+	}
+	if len(claimed) != 4 {
+		t.Fatalf("claimed %d tasks, want one per file (4)", len(claimed))
+	}
+}
+
+func checkClaims(t *testing.T, ctx context.Context, s Store, runID string) {
+	t.Helper()
+	now := time.Now()
+	tasks := []core.TaskRecord{
+		{RunID: runID, Tool: "pytest", TaskType: "fix_test", Priority: 1, Status: "queued", Fingerprint: "t-low", Title: "low", TargetsJSON: `{"files":["src/b.py"]}`, CreatedAt: now},
+		{RunID: runID, Tool: "pytest", TaskType: "fix_test", Priority: 5, Status: "queued", Fingerprint: "t-high", Title: "high", TargetsJSON: `{"files":["src/a.py"]}`, CreatedAt: now},
+		{RunID: runID, Tool: "coverage", TaskType: "add_tests", Priority: 3, Status: "queued", Fingerprint: "t-same", Title: "same file", TargetsJSON: `{"files":["./src/a.py"]}`, CreatedAt: now},
+	}
+	if err := s.InsertTasks(ctx, tasks); err != nil {
+		t.Fatalf("insert tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.ID == 0 {
+			t.Fatalf("insert tasks did not set the ID of %q", task.Title)
+		}
+	}
+
+	first, err := s.ClaimNextTask(ctx, runID, "w1")
+	if err != nil || first == nil {
+		t.Fatalf("claim 1 = %v, %v", first, err)
+	}
+	if first.ID != tasks[1].ID || first.Status != "running" || first.ClaimedBy != "w1" {
+		t.Fatalf("claim 1 = %+v, want the highest priority task", first)
+	}
+
+	// The priority 3 task locks the same file as the running task, so the
+	// claim skips it.
+	second, err := s.ClaimNextTask(ctx, runID, "w2")
+	if err != nil || second == nil || second.ID != tasks[0].ID {
+		t.Fatalf("claim 2 = %+v, %v, want the non-conflicting task", second, err)
+	}
+	if _, err := s.ClaimNextTask(ctx, runID, "w3"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("claim 3 err = %v, want ErrConflict", err)
+	}
+
+	attemptID, err := s.CreateAttempt(ctx, core.AttemptRecord{TaskID: first.ID, AttemptNo: 1, Status: "running", AgentName: "local", StartedAt: now})
+	if err != nil || attemptID == 0 {
+		t.Fatalf("create attempt = %d, %v", attemptID, err)
+	}
+	if err := s.FinishAttempt(ctx, core.AttemptRecord{ID: attemptID, TaskID: first.ID, AttemptNo: 1, Status: "success", AgentName: "local", FinishedAt: time.Now()}); err != nil {
+		t.Fatalf("finish attempt: %v", err)
+	}
+	attempts, err := s.ListAttempts(ctx, first.ID)
+	if err != nil || len(attempts) != 1 || attempts[0].Status != "success" {
+		t.Fatalf("list attempts = %+v, %v", attempts, err)
+	}
+
+	if err := s.UpdateTaskStatus(ctx, first.ID, "succeeded", `{"note":"done"}`); err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	third, err := s.ClaimNextTask(ctx, runID, "w3")
+	if err != nil || third == nil || third.ID != tasks[2].ID {
+		t.Fatalf("claim after release = %+v, %v", third, err)
+	}
+	for _, id := range []int64{second.ID, third.ID} {
+		if err := s.UpdateTaskStatus(ctx, id, "succeeded", ""); err != nil {
+			t.Fatalf("update task: %v", err)
+		}
+	}
+	if task, err := s.ClaimNextTask(ctx, runID, "w1"); err != nil || task != nil {
+		t.Fatalf("claim on empty queue = %+v, %v", task, err)
+	}
+
+	listed, err := s.ListTasks(ctx, runID)
+	if err != nil || len(listed) != 3 {
+		t.Fatalf("list tasks = %d, %v", len(listed), err)
+	}
+	for _, task := range listed {
+		if task.Status != "succeeded" {
+			t.Fatalf("task %d status = %s", task.ID, task.Status)
+		}
+		if task.ID == first.ID && task.ExtraJSON != `{"note":"done"}` {
+			t.Fatalf("task %d extra = %q", task.ID, task.ExtraJSON)
+		}
+	}
+}
+
+func checkDeleteRun(t *testing.T, ctx context.Context, s Store, runID string) {
+	t.Helper()
+	if err := s.DeleteRun(ctx, runID); err != nil {
+		t.Fatalf("delete run: %v", err)
+	}
+	if _, err := s.GetRun(ctx, runID); err == nil {
+		t.Fatal("get run after delete succeeded")
+	}
+	findings, err := s.ListFindings(ctx, runID)
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("findings after delete = %d, %v", len(findings), err)
+	}
+	tasks, err := s.ListTasks(ctx, runID)
+	if err != nil || len(tasks) != 0 {
+		t.Fatalf("tasks after delete = %d, %v", len(tasks), err)
+	}
+}